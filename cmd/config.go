@@ -2,15 +2,20 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/richardamare/ai-digest/internal/config"
+	"github.com/richardamare/ai-digest/internal/utils"
 	"github.com/spf13/cobra"
 )
 
 var (
-	configFile string
-	configCmd  = &cobra.Command{
+	configFile       string
+	withIgnore       bool
+	noDefaultIgnores bool
+	configCmd        = &cobra.Command{
 		Use:   "config",
 		Short: "Manage AI Digest configuration",
 		Long: `View and modify AI Digest configuration settings.
@@ -23,22 +28,36 @@ By default, configuration is stored in ai-digest.json in the current directory.`
 		RunE:  showConfig,
 	}
 
+	configValidateCmd = &cobra.Command{
+		Use:   "validate",
+		Short: "Check the configuration file for errors",
+		Long: `Validate the configuration file, reporting unknown keys, invalid values and
+ignore patterns that can't be compiled, and print the effective ignore set.
+Exits with an error if any problems are found.`,
+		RunE: validateConfig,
+	}
+
 	configInitCmd = &cobra.Command{
 		Use:   "init",
 		Short: "Initialize configuration file",
 		Long: `Initialize a new configuration file in the current directory.
-If no config file exists, creates ai-digest.json with default settings.`,
+If no config file exists, creates ai-digest.json with default settings.
+With --with-ignore, also creates a commented .aidigestignore starter file.`,
 		RunE: initConfig,
 	}
 )
 
 func init() {
 	rootCmd.AddCommand(configCmd)
-	configCmd.AddCommand(configShowCmd, configInitCmd)
+	configCmd.AddCommand(configShowCmd, configInitCmd, configValidateCmd)
 
 	// Make the config flag optional, default to CWD
 	configCmd.PersistentFlags().StringVar(&configFile, "config", "",
 		"config file path (defaults to ./ai-digest.json)")
+	configInitCmd.Flags().BoolVar(&withIgnore, "with-ignore", false,
+		"also scaffold an ignore file with common patterns")
+	configValidateCmd.Flags().BoolVar(&noDefaultIgnores, "no-default-ignores", false,
+		"leave the built-in default ignores out of the effective set, as when digesting without them")
 }
 
 func showConfig(cmd *cobra.Command, args []string) error {
@@ -64,6 +83,13 @@ func initConfig(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("config file already exists at %s", manager.GetConfigPath())
 	}
 
+	ignoreFile := config.GetDefaultConfig().IgnoreFile
+	if withIgnore {
+		if _, err := os.Stat(manager.GetIgnorePath(ignoreFile)); err == nil {
+			return fmt.Errorf("ignore file already exists at %s", manager.GetIgnorePath(ignoreFile))
+		}
+	}
+
 	if err := manager.Init(); err != nil {
 		return fmt.Errorf("failed to initialize config: %w", err)
 	}
@@ -74,6 +100,100 @@ func initConfig(cmd *cobra.Command, args []string) error {
 	}
 
 	fmt.Printf("Created config file: %s\n", absPath)
+
+	if withIgnore {
+		if err := manager.InitIgnoreFile(ignoreFile); err != nil {
+			return fmt.Errorf("failed to initialize ignore file: %w", err)
+		}
+		fmt.Printf("Created ignore file: %s\n", manager.GetIgnorePath(ignoreFile))
+	}
+
 	fmt.Println("You can now modify this file or use 'ai-digest config show' to view it")
 	return nil
 }
+
+func validateConfig(cmd *cobra.Command, args []string) error {
+	manager := config.NewManager(configFile)
+
+	if !manager.Exists() {
+		return fmt.Errorf("no config file found at %s", manager.GetConfigPath())
+	}
+
+	cfg, diag, err := manager.Validate()
+	if err != nil {
+		return err
+	}
+
+	// Built-in defaults apply unless disabled, whatever the config holds
+	var effective ignoreSet
+	if !noDefaultIgnores {
+		effective.add(utils.DefaultIgnores...)
+	}
+
+	if cfg != nil {
+		for _, pattern := range cfg.DefaultIgnores {
+			if err := utils.ValidatePattern(pattern); err != nil {
+				diag.Errors = append(diag.Errors, fmt.Sprintf("defaultIgnores: %v", err))
+				continue
+			}
+			effective.add(pattern)
+		}
+
+		if cfg.IgnoreFile != "" {
+			ignorePath := manager.GetIgnorePath(cfg.IgnoreFile)
+			filePatterns, err := utils.ReadIgnoreFile(ignorePath)
+			if err != nil {
+				diag.Errors = append(diag.Errors, fmt.Sprintf("failed to read ignore file: %v", err))
+			}
+			for _, pattern := range filePatterns {
+				if err := utils.ValidatePattern(pattern); err != nil {
+					diag.Errors = append(diag.Errors, fmt.Sprintf("%s: %v", cfg.IgnoreFile, err))
+					continue
+				}
+				effective.add(pattern)
+			}
+		}
+	}
+
+	out := cmd.OutOrStdout()
+	for _, warning := range diag.Warnings {
+		fmt.Fprintf(out, "warning: %s\n", warning)
+	}
+	for _, problem := range diag.Errors {
+		fmt.Fprintf(out, "error: %s\n", problem)
+	}
+
+	if len(diag.Errors) == 0 {
+		fmt.Fprintf(out, "Config file %s is valid\n", manager.GetConfigPath())
+	}
+	fmt.Fprintln(out, "\nEffective ignore patterns:")
+	for _, pattern := range effective.patterns {
+		fmt.Fprintf(out, "  %s\n", pattern)
+	}
+
+	if len(diag.Errors) > 0 {
+		return fmt.Errorf("config file %s has %d error(s)", manager.GetConfigPath(), len(diag.Errors))
+	}
+	return nil
+}
+
+// ignoreSet collects ignore patterns in order, skipping blank lines,
+// comments and repeats
+type ignoreSet struct {
+	patterns []string
+	seen     map[string]bool
+}
+
+func (s *ignoreSet) add(patterns ...string) {
+	if s.seen == nil {
+		s.seen = make(map[string]bool)
+	}
+	for _, pattern := range patterns {
+		line := strings.TrimSpace(pattern)
+		if line == "" || strings.HasPrefix(line, "#") || s.seen[line] {
+			continue
+		}
+		s.seen[line] = true
+		s.patterns = append(s.patterns, line)
+	}
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/richardamare/ai-digest/internal/config"
+	"github.com/richardamare/ai-digest/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       string
+		ignore       string
+		noDefaults   bool
+		wantErr      string
+		wantErrors   int
+		wantWarnings int
+		wantPatterns []string
+		skipPatterns []string
+	}{
+		{
+			name:         "valid",
+			config:       `{"defaultIgnores": ["node_modules", "*.tmp"], "ignoreFile": ".aidigestignore"}`,
+			ignore:       "# generated\n*.tmp\ndist/\n",
+			wantPatterns: []string{"node_modules", "*.tmp", "dist/"},
+		},
+		{
+			name:         "unknown key",
+			config:       `{"defaultIgnores": [], "ignoreFile": ".aidigestignore", "ignoreFiles": ["x"]}`,
+			wantWarnings: 1,
+		},
+		{
+			name:         "bad patterns",
+			config:       `{"defaultIgnores": ["file[.txt", "*.bak"], "ignoreFile": ".aidigestignore"}`,
+			ignore:       "!bad[\ndist/\n",
+			wantErr:      "has 2 error(s)",
+			wantErrors:   2,
+			wantPatterns: []string{"*.bak", "dist/"},
+			skipPatterns: []string{"file[.txt", "!bad["},
+		},
+		{
+			name:         "unknown key and bad pattern",
+			config:       `{"defaultIgnores": ["[x"], "ignoreFile": ".aidigestignore", "typeLimit": {}}`,
+			wantErr:      "has 1 error(s)",
+			wantErrors:   1,
+			wantWarnings: 1,
+		},
+		{
+			name:         "defaults disabled",
+			config:       `{"defaultIgnores": ["*.bak"], "ignoreFile": ".aidigestignore"}`,
+			noDefaults:   true,
+			wantPatterns: []string{"*.bak"},
+			skipPatterns: []string{"node_modules", "package-lock.json"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			configFile = filepath.Join(dir, "ai-digest.json")
+			noDefaultIgnores = tt.noDefaults
+			t.Cleanup(func() { configFile, noDefaultIgnores = "", false })
+
+			if err := os.WriteFile(configFile, []byte(tt.config), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, ".aidigestignore"), []byte(tt.ignore), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+			cmd := &cobra.Command{}
+			cmd.SetOut(&out)
+			err := validateConfig(cmd, nil)
+
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("validateConfig() error = %v, want success", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("validateConfig() error = %v, want %q", err, tt.wantErr)
+			}
+
+			output := out.String()
+			if n := strings.Count(output, "error: "); n != tt.wantErrors {
+				t.Errorf("got %d errors, want %d:\n%s", n, tt.wantErrors, output)
+			}
+			if n := strings.Count(output, "warning: "); n != tt.wantWarnings {
+				t.Errorf("got %d warnings, want %d:\n%s", n, tt.wantWarnings, output)
+			}
+
+			_, list, _ := strings.Cut(output, "Effective ignore patterns:\n")
+			lines := strings.Split(strings.TrimSpace(list), "\n")
+			seen := make(map[string]int)
+			for _, line := range lines {
+				seen[strings.TrimSpace(line)]++
+			}
+			for _, pattern := range tt.wantPatterns {
+				if seen[pattern] != 1 {
+					t.Errorf("effective set lists %q %d times, want once:\n%s", pattern, seen[pattern], list)
+				}
+			}
+			for _, pattern := range tt.skipPatterns {
+				if seen[pattern] != 0 {
+					t.Errorf("effective set lists %q, want it left out:\n%s", pattern, list)
+				}
+			}
+		})
+	}
+}
+
+func TestInitConfigWithIgnore(t *testing.T) {
+	dir := t.TempDir()
+	configFile = filepath.Join(dir, "ai-digest.json")
+	withIgnore = true
+	t.Cleanup(func() { configFile, withIgnore = "", false })
+	ignorePath := filepath.Join(dir, ".aidigestignore")
+
+	if err := initConfig(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("initConfig() error = %v", err)
+	}
+	if _, err := os.Stat(configFile); err != nil {
+		t.Errorf("config file not created: %v", err)
+	}
+	data, err := os.ReadFile(ignorePath)
+	if err != nil {
+		t.Fatalf("ignore file not created: %v", err)
+	}
+	if string(data) != config.DefaultIgnoreTemplate {
+		t.Errorf("ignore file = %q, want the template", data)
+	}
+	for _, pattern := range strings.Split(string(data), "\n") {
+		if err := utils.ValidatePattern(pattern); err != nil {
+			t.Errorf("template pattern: %v", err)
+		}
+	}
+
+	// An existing ignore file is never overwritten, and nothing is created
+	if err := os.Remove(configFile); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(ignorePath, []byte("custom\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := initConfig(&cobra.Command{}, nil); err == nil || !strings.Contains(err.Error(), "ignore file already exists") {
+		t.Errorf("initConfig() error = %v, want the existing ignore file reported", err)
+	}
+	if _, err := os.Stat(configFile); !os.IsNotExist(err) {
+		t.Errorf("config file created despite the error: %v", err)
+	}
+	if data, _ := os.ReadFile(ignorePath); string(data) != "custom\n" {
+		t.Errorf("ignore file overwritten with %q", data)
+	}
+}
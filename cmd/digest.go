@@ -1,11 +1,20 @@
 package cmd
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/richardamare/ai-digest/internal/config"
 	"github.com/richardamare/ai-digest/internal/processor"
+	"github.com/richardamare/ai-digest/internal/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -16,11 +25,106 @@ var (
 	useDefaultIgnores bool
 	removeWhitespace  bool
 	showOutputFiles   bool
+	showIgnored       bool
 	ignoreFile        string
 	splitOutput       bool
 	maxFileSizeMB     int
 	outputPattern     string
 	chunkSize         int
+	parallelWrite     bool
+	sinceLastRun      bool
+	stateFile         string
+	dateLayout        string
+	dedent            bool
+	detectLanguage    bool
+	wrapMarkdownWidth int
+	markdownPlain     bool
+	embeddedBOMMode   string
+	duplicateHeaders  string
+	gitAuthors        bool
+	gitHeader         bool
+	instructions      []string
+	maxTokensPerFile  int
+	excludeContent    string
+	includeContent    string
+	reportDuplicates  bool
+	reportClones      bool
+	outputMode        string
+	collapseImports   bool
+	prioritize        []string
+	orderFile         string
+	splitOverlap      int
+	splitResume       bool
+	alsoCombined      string
+	splitIndex        string
+	partHeaders       bool
+	pruneNoise        bool
+	outputEncoding    string
+	parentIgnores     bool
+	ignoreCeiling     string
+	newlinesBetween   int
+	blockSeparator    string
+	headerMetadata    bool
+	hashHeaders       bool
+	theme             string
+	includeFile       string
+	stripTrailingWS   bool
+	markdownMode      string
+	compactBinaries   bool
+	maxBinarySizeKB   int64
+	quickEstimate     bool
+	onlyTracked       bool
+	flushInterval     int64
+	outputEOL         string
+	collectTodos      bool
+	preserveBlank     int
+	transformOrder    []string
+	headerFormat      string
+	includeEnvExample bool
+	skipVendored      bool
+	maxRuntime        time.Duration
+	strict            bool
+	hashAlgo          string
+	skipGenHeader     bool
+	excludeTests      bool
+	testPatterns      []string
+	onlyTests         bool
+	withTestedSource  bool
+	compact           bool
+	compareBaseline   bool
+	promoteFM         bool
+	stripFM           bool
+	commitRange       string
+	maxReadRate       int64
+	concurrency       int
+	signaturesOnly    bool
+	maxAge            string
+	groupByAge        bool
+	perLanguage       bool
+	force             bool
+	binaryManifest    string
+	resultsFile       string
+	resultsContent    bool
+	jsonCompact       bool
+	jsonStream        bool
+	emitEmptyBinaries bool
+	trimPrefix        bool
+	pathDepth         int
+	maxParts          int
+	lockFile          string
+	verifyLock        bool
+	langOverrides     []string
+	hardLimitTokens   int
+	stdinList         bool
+	stdinListNul      bool
+	summarizeOver     int
+	editorConfig      bool
+	resolveIncludes   bool
+	noteSymlinks      bool
+	cleanStale        bool
+	treatText         []string
+	treatBinary       []string
+	binarySample      int
 )
 
 var digestCmd = &cobra.Command{
@@ -32,7 +136,9 @@ It handles text and binary files, respects ignore patterns, and provides various
 Examples:
   ai-digest digest -i /path/to/project -o output.md
   ai-digest digest -i /path/to/project -o output.md --split --max-size 5
-  ai-digest digest -i /path/to/project -o output.md --split --output-pattern "part_%d.md"`,
+  ai-digest digest -i /path/to/project -o output.md --split --max-size 0
+  ai-digest digest -i /path/to/project -o output.md --split --output-pattern "part_%d.md"
+  ai-digest digest -i /path/to/project -o "digest-{date}-{gitsha}.md"`,
 	RunE:    runDigest,
 	PreRunE: validateFlags,
 }
@@ -40,38 +146,307 @@ Examples:
 func init() {
 	// Required flags
 	digestCmd.Flags().StringVarP(&inputDir, "input", "i", ".",
-		"Input directory containing the codebase")
+		"Input directory containing the codebase, or a single file to digest")
+	digestCmd.Flags().StringVar(&configFile, "config", "",
+		"Config file path (defaults to ./ai-digest.json)")
 	digestCmd.Flags().StringVarP(&outputFile, "output", "o", "codebase.md",
-		"Output markdown file path")
+		"Output markdown file path, with .md added if it has no extension (supports {date} and {gitsha} placeholders)")
 
 	// Optional flags
 	digestCmd.Flags().BoolVar(&useDefaultIgnores, "no-default-ignores", true,
 		"Disable default ignore patterns")
 	digestCmd.Flags().BoolVar(&removeWhitespace, "whitespace-removal", false,
 		"Enable whitespace removal for non-sensitive files")
+	digestCmd.Flags().BoolVar(&stripTrailingWS, "strip-trailing-ws", false,
+		"Trim trailing whitespace and trailing blank lines in all files")
+	digestCmd.Flags().StringVar(&markdownMode, "markdown-mode", processor.MarkdownModeRaw,
+		"Wrapping for markdown files: raw (four backticks), fenced (dynamic fence) or escape")
+	digestCmd.Flags().IntVar(&preserveBlank, "preserve-blank-lines", 0,
+		"With --whitespace-removal, keep at most N consecutive blank lines instead of collapsing all whitespace (0 collapses)")
+	digestCmd.Flags().StringSliceVar(&transformOrder, "content-transform-order", nil,
+		"Order to apply enabled content transforms in ("+strings.Join(processor.DefaultTransformOrder, ", ")+")")
+	digestCmd.Flags().BoolVar(&noteSymlinks, "note-symlinks", false,
+		"Write a short note with the target of each symlink instead of following it")
+	digestCmd.Flags().BoolVar(&resolveIncludes, "resolve-includes", false,
+		"Inline files referenced by '<!-- include: path -->' lines, each in its own fence")
+	digestCmd.Flags().BoolVar(&editorConfig, "respect-editorconfig", false,
+		"Apply trim_trailing_whitespace and insert_final_newline from .editorconfig files")
+	digestCmd.Flags().IntVar(&wrapMarkdownWidth, "word-wrap-markdown", 0,
+		"Reflow prose paragraphs in markdown files to this width, leaving code, lists and tables intact (0 to disable)")
+	digestCmd.Flags().BoolVar(&markdownPlain, "transform-markdown-to-plaintext", false,
+		"Strip headings, links and emphasis syntax from markdown files, keeping code blocks")
+	digestCmd.Flags().BoolVar(&dedent, "dedent", false,
+		"Remove common leading indentation for non-sensitive files")
+	digestCmd.Flags().BoolVar(&detectLanguage, "detect-language", false,
+		"Judge whitespace sensitivity by file name, shebang and content, not just the extension")
+	digestCmd.Flags().BoolVar(&collapseImports, "collapse-imports", false,
+		"Collapse leading import blocks in Go, JS/TS and Python files")
+	digestCmd.Flags().StringVar(&embeddedBOMMode, "embedded-bom", processor.EmbeddedBOMWarn,
+		"Handling of UTF-8 BOMs in the middle of files: warn, strip or fail")
+	digestCmd.Flags().StringVar(&duplicateHeaders, "duplicate-headers", processor.DuplicateHeadersDisambiguate,
+		"Handling of files whose headers come out identical: disambiguate (append the path) or fail")
+	digestCmd.Flags().BoolVar(&gitAuthors, "git-authors", false,
+		"Add a primary authors line to each file header (requires git)")
+	digestCmd.Flags().BoolVar(&gitHeader, "git-header", false,
+		"Start the output with the git branch, HEAD commit and origin URL (skipped outside a repository)")
+	digestCmd.Flags().StringArrayVar(&instructions, "instructions", nil,
+		"Start the output with this text as a blockquoted preamble (repeat for more paragraphs)")
+	digestCmd.Flags().IntVar(&summarizeOver, "summarize-over", 0,
+		"Replace text files above this many estimated tokens with their first and last lines and an outline (0 to disable)")
+	digestCmd.Flags().IntVar(&hardLimitTokens, "hard-limit-tokens", 0,
+		"Abort once the output would exceed this many estimated tokens, keeping the output written so far (exit code 4)")
+	digestCmd.Flags().IntVar(&maxTokensPerFile, "max-tokens-per-file", 0,
+		"Truncate each file to this many estimated tokens (0 for no limit)")
+	digestCmd.Flags().StringVar(&excludeContent, "exclude-content-regex", "",
+		"Skip text files whose content matches this regex")
+	digestCmd.Flags().StringVar(&includeContent, "include-content-regex", "",
+		"Only include text files whose content matches this regex")
+	digestCmd.Flags().BoolVar(&reportDuplicates, "report-duplicates", false,
+		"Report groups of files with identical content")
+	digestCmd.Flags().BoolVar(&reportClones, "report-clones", false,
+		"Report blocks of code duplicated within or across files, ignoring whitespace (analysis only)")
+	digestCmd.Flags().StringSliceVar(&prioritize, "prioritize", nil,
+		"Globs whose matching files are written first, in the given order (e.g. README.md,main.go)")
+	digestCmd.Flags().StringVar(&orderFile, "order-file", "",
+		"File of relative paths, one per line, written first in the listed order")
+	digestCmd.Flags().BoolVar(&pruneNoise, "prune-noise", false,
+		"Skip minified, generated, oversized (>1MB), lockfile and data files")
+	digestCmd.Flags().IntVar(&newlinesBetween, "newline-between-files", 1,
+		"Number of blank lines between file blocks")
+	digestCmd.Flags().StringVar(&blockSeparator, "block-separator", "",
+		"Custom separator line between file blocks (e.g. '---'), overrides --newline-between-files")
+	digestCmd.Flags().IntVar(&pathDepth, "path-depth", 0,
+		"Show only the last N path components in file headers, adding more where files would collide (0 for full paths)")
+	digestCmd.Flags().BoolVar(&trimPrefix, "trim-common-prefix", false,
+		"Strip the directory prefix shared by all files from headers, noting it once at the top")
+	digestCmd.Flags().StringArrayVar(&langOverrides, "language-override", nil,
+		"Fence language for an extension or glob as PATTERN=LANGUAGE (e.g. 'src/matlab/**/*.m=matlab'), repeatable")
+	digestCmd.Flags().StringVar(&headerFormat, "header-format", utils.DefaultHeaderFormat,
+		"File header template using {path}, {lang}, {size} and {index}")
+	digestCmd.Flags().BoolVar(&headerMetadata, "header-metadata", false,
+		"Add file size and estimated tokens to each file header")
+	digestCmd.Flags().BoolVar(&hashHeaders, "hash-headers", false,
+		"Add a short hash of each file's raw content to its header, using --hash-algo")
+	digestCmd.Flags().StringVar(&theme, "theme", processor.ThemeFancy,
+		"How the stats summary is shown: fancy (icons and sections), plain (no icons) or minimal (one line per metric)")
+	digestCmd.Flags().BoolVar(&compactBinaries, "compact-binaries", false,
+		"List binary and SVG files in a single table at the end")
+	digestCmd.Flags().Int64Var(&maxBinarySizeKB, "max-binary-size", 0,
+		"Embed binary files up to this many KB as base64; larger files get a placeholder (0 never embeds)")
+	digestCmd.Flags().StringVar(&resultsFile, "results-json", "",
+		"Also write every file result to this JSON document (versioned, for editor integrations)")
+	digestCmd.Flags().BoolVar(&resultsContent, "results-json-content", false,
+		"Include file content in the --results-json document")
+	digestCmd.Flags().BoolVar(&jsonCompact, "json-compact", false,
+		"Write the --results-json document and --binary-manifest on one line instead of indented")
+	digestCmd.Flags().BoolVar(&jsonStream, "output-json-stream", false,
+		"Write file results to the output as JSON lines in completion order instead of the digest, keeping memory bounded")
+	digestCmd.Flags().StringVar(&binaryManifest, "binary-manifest", processor.BinaryManifestNone,
+		"Emit a JSON manifest of binary files (path, type, size, mime): none, block (in the digest) or sidecar (<output>.binaries.json)")
+	digestCmd.Flags().BoolVar(&emitEmptyBinaries, "emit-empty-binary-section", false,
+		"Emit the binary manifest even when no binary files were found")
+	digestCmd.Flags().BoolVar(&quickEstimate, "quick-estimate", false,
+		"Print an approximate token estimate from a sample of files without writing output")
+	digestCmd.Flags().BoolVar(&collectTodos, "collect-todos", false,
+		"Add a summary of TODO/FIXME/HACK markers at the top of the output")
+	digestCmd.Flags().BoolVar(&includeEnvExample, "include-env-examples", false,
+		"Include .env.example and .env.sample files that the default ignores exclude")
+	digestCmd.Flags().BoolVar(&skipVendored, "skip-vendored", false,
+		"Also ignore vendored dependency directories such as third_party/, Pods/ and bower_components/")
+	digestCmd.Flags().DurationVar(&maxRuntime, "max-runtime", 0,
+		"Stop after this long (e.g. 30s, 5m), keeping the output written so far")
+	digestCmd.Flags().BoolVar(&strict, "strict", false,
+		"Fail on the first file that can't be processed (e.g. invalid UTF-8) instead of skipping it")
+	digestCmd.Flags().StringVar(&hashAlgo, "hash-algo", utils.DefaultHashAlgo,
+		"Hash algorithm for duplicate detection ("+strings.Join(utils.HasherNames(), ", ")+")")
+	digestCmd.Flags().BoolVar(&skipGenHeader, "skip-generated-by-header", false,
+		"Skip files whose first lines mark them as generated (e.g. '// Code generated ... DO NOT EDIT.')")
+	digestCmd.Flags().BoolVar(&excludeTests, "exclude-tests", false,
+		"Skip test files and directories by common conventions (*_test.go, *.spec.ts, test_*.py, tests/, ...)")
+	digestCmd.Flags().StringSliceVar(&testPatterns, "test-pattern", nil,
+		"Extra gitignore-style patterns identifying test files (only used with --exclude-tests or --only-tests)")
+	digestCmd.Flags().BoolVar(&onlyTests, "only-tests", false,
+		"Include only test files, identified as for --exclude-tests")
+	digestCmd.Flags().BoolVar(&withTestedSource, "with-tested-source", false,
+		"With --only-tests, also include the source file next to each test (e.g. foo.go for foo_test.go)")
+	digestCmd.Flags().BoolVar(&compact, "compact", false,
+		"Enable token-saving defaults: --strip-trailing-ws, --whitespace-removal --preserve-blank-lines 1, --collapse-imports and --compact-binaries")
+	digestCmd.Flags().BoolVar(&compareBaseline, "compare", false,
+		"Report token savings against the untransformed content")
+	digestCmd.Flags().BoolVar(&promoteFM, "promote-frontmatter", false,
+		"Show the title from a file's YAML front matter in its header")
+	digestCmd.Flags().BoolVar(&stripFM, "strip-frontmatter", false,
+		"With --promote-frontmatter, remove the front matter block from the file body")
+	digestCmd.Flags().StringVar(&commitRange, "commit-range", "",
+		"Only include files changed in a git commit range (e.g. main..feature)")
+	digestCmd.Flags().Int64Var(&maxReadRate, "max-read-bytes-per-sec", 0,
+		"Limit aggregate file read throughput in bytes per second (0 for unlimited)")
+	digestCmd.Flags().IntVar(&concurrency, "concurrency", 0,
+		"Number of files to read at once (0 picks a level for the input filesystem: low for network mounts and spinning disks, higher for local SSDs)")
+	digestCmd.Flags().BoolVar(&signaturesOnly, "signatures-only", false,
+		"Emit a directory tree and only top-level declarations for supported languages (Go); other files are kept whole")
+	digestCmd.Flags().StringVar(&maxAge, "max-age", "",
+		"Only include files modified within this age (e.g. 30d, 2w, 12h)")
+	digestCmd.Flags().BoolVar(&groupByAge, "group-by-age", false,
+		"Group files into sections by last-modified age (30 days, 90 days, older)")
+	digestCmd.Flags().StringSliceVar(&treatText, "treat-as-text", nil,
+		"Extensions to always read as text, in addition to the config's treatAsText (e.g. .dat)")
+	digestCmd.Flags().StringSliceVar(&treatBinary, "treat-as-binary", nil,
+		"Extensions to always list as binary, in addition to the config's treatAsBinary (e.g. .svg)")
+	digestCmd.Flags().IntVar(&binarySample, "binary-sample-bytes", utils.DefaultBinarySampleSize,
+		"Number of leading bytes read to tell text from binary files")
+	digestCmd.Flags().BoolVarP(&force, "force", "f", false,
+		"Overwrite existing output files without asking")
+	digestCmd.Flags().BoolVar(&stdinList, "stdin-list", false,
+		"Read the paths to process from stdin, one per line, instead of walking the input directory")
+	digestCmd.Flags().BoolVarP(&stdinListNul, "null", "0", false,
+		"With --stdin-list, paths are separated by NUL bytes (e.g. find -print0)")
 	digestCmd.Flags().BoolVar(&showOutputFiles, "show-output-files", false,
 		"Display a list of files included in the output")
+	digestCmd.Flags().BoolVar(&showIgnored, "show-ignored", false,
+		"Display a list of ignored files and the pattern that excluded each one")
+	digestCmd.Flags().StringVar(&outputEncoding, "output-encoding", utils.EncodingUTF8,
+		"Encoding of the output files: utf-8, utf-16le or utf-16be (Shift-JIS is not supported)")
+	digestCmd.Flags().StringVar(&outputEOL, "output-eol", "",
+		"Line ending for the output: lf or crlf (defaults to keeping source endings)")
+	digestCmd.Flags().StringVar(&outputMode, "output-mode", "0644",
+		"Octal permissions for created output files")
+	digestCmd.Flags().StringVar(&dateLayout, "date-layout", "2006-01-02",
+		"Go time layout used for the {date} output placeholder")
 	digestCmd.Flags().StringVar(&ignoreFile, "ignore-file", ".aidigestignore",
-		"Custom ignore file name")
+		"Custom ignore file name, or - to read patterns from stdin")
+	digestCmd.Flags().StringVar(&includeFile, "include-file", "",
+		"File of patterns that files must match to be included, or - to read from stdin")
+
+	digestCmd.Flags().BoolVar(&onlyTracked, "only-tracked", false,
+		"Only include files tracked by git")
+	digestCmd.Flags().BoolVar(&parentIgnores, "respect-ignore-from-parents", false,
+		"Apply .gitignore and ignore files from directories above the input directory")
+	digestCmd.Flags().StringVar(&ignoreCeiling, "ignore-ceiling", "",
+		"Highest directory searched for parent ignore files (defaults to the git repository root)")
 
 	// Split-specific flags
 	digestCmd.Flags().BoolVar(&splitOutput, "split", false,
 		"Split output into multiple files")
 	digestCmd.Flags().IntVar(&maxFileSizeMB, "max-size", 10,
-		"Maximum size of each output file in MB (only used with --split)")
+		"Maximum size of each output file in MB, or 0 for one file per source file (only used with --split)")
 	digestCmd.Flags().StringVar(&outputPattern, "output-pattern", "",
-		"Pattern for split output files (e.g., 'part_%d.md')")
+		"Pattern for split output files (e.g., 'part_%d.md'); .md is added if it has no extension")
+	digestCmd.Flags().IntVar(&splitOverlap, "split-overlap", 0,
+		"Number of trailing files repeated at the top of the next part (only used with --split)")
+	digestCmd.Flags().IntVar(&maxParts, "max-parts", 0,
+		"Maximum number of split parts, growing parts beyond --max-size if needed (0 for no limit)")
+	digestCmd.Flags().BoolVar(&cleanStale, "clean-stale", false,
+		"Remove split parts left over from a previous run with more parts (only used with --split)")
+	digestCmd.Flags().StringVar(&alsoCombined, "also-combined", "",
+		"Also write the whole digest to this single file (only used with --split)")
+	digestCmd.Flags().StringVar(&splitIndex, "index", "",
+		"Write a markdown index linking each part and listing its files (only used with --split)")
+	digestCmd.Flags().BoolVar(&partHeaders, "output-split-manifest-in-each-part", false,
+		"Open each part with \"Part N of M\" and the range of files it holds (only used with --split)")
+	digestCmd.Flags().BoolVar(&splitResume, "split-resume", false,
+		"Leave split parts whose content is unchanged untouched on disk (only used with --split)")
+	digestCmd.Flags().Int64Var(&flushInterval, "flush-interval", 0,
+		"Flush split output every N bytes (0 flushes only near the size limit)")
 	digestCmd.Flags().IntVar(&chunkSize, "chunk-size", 1,
 		"Size of processing chunks in MB")
+	digestCmd.Flags().BoolVar(&perLanguage, "output-per-language", false,
+		"Write one output file per language, e.g. codebase.go.md and codebase.py.md")
+	digestCmd.Flags().BoolVar(&parallelWrite, "parallel-write", false,
+		"Write split output parts in the background (only used with --split)")
+
+	// Incremental flags
+	digestCmd.Flags().BoolVar(&sinceLastRun, "since-last-run", false,
+		"Only include files changed since the previous run")
+	digestCmd.Flags().StringVar(&lockFile, "lockfile", "",
+		"Record the size and hash of each included file in this lockfile")
+	digestCmd.Flags().BoolVar(&verifyLock, "verify-lock", false,
+		"Check that the included files match --lockfile instead of writing output")
+	digestCmd.Flags().StringVar(&stateFile, "state-file", "",
+		"State file for --since-last-run (defaults to ai-digest.state.json next to the config)")
 
 	rootCmd.AddCommand(digestCmd)
 }
 
+// blankLinesBetween converts --newline-between-files to the processor
+// setting, where 0 selects the default spacing
+func blankLinesBetween(n int) int {
+	if n == 0 {
+		return processor.NoNewlinesBetweenFiles
+	}
+	return n
+}
+
 func validateFlags(cmd *cobra.Command, args []string) error {
-	// Validate input directory
-	if _, err := os.Stat(inputDir); os.IsNotExist(err) {
+	if compact {
+		applyCompactPreset(cmd)
+	}
+
+	// Validate stdin usage, which can only feed one option
+	if inputDir == utils.StdinPatternSource {
+		return fmt.Errorf("input cannot be read from stdin")
+	}
+	if ignoreFile == utils.StdinPatternSource && includeFile == utils.StdinPatternSource {
+		return fmt.Errorf("ignore-file and include-file cannot both read from stdin")
+	}
+	if stdinList && (ignoreFile == utils.StdinPatternSource || includeFile == utils.StdinPatternSource) {
+		return fmt.Errorf("stdin-list cannot be combined with reading ignore-file or include-file from stdin")
+	}
+	if stdinListNul && !stdinList {
+		return fmt.Errorf("null requires --stdin-list")
+	}
+
+	for _, ext := range treatBinary {
+		if slices.ContainsFunc(treatText, func(s string) bool { return strings.EqualFold(s, ext) }) {
+			return fmt.Errorf("extension %s cannot be treated as both text and binary", ext)
+		}
+	}
+
+	if excludeTests && onlyTests {
+		return fmt.Errorf("only-tests cannot be combined with --exclude-tests")
+	}
+
+	if len(testPatterns) > 0 && !excludeTests && !onlyTests {
+		return fmt.Errorf("test-pattern requires --exclude-tests or --only-tests")
+	}
+
+	if withTestedSource && !onlyTests {
+		return fmt.Errorf("with-tested-source requires --only-tests")
+	}
+
+	if binarySample <= 0 {
+		return fmt.Errorf("binary-sample-bytes must be positive")
+	}
+
+	// Validate input directory, or the single input file
+	info, err := os.Stat(inputDir)
+	if os.IsNotExist(err) {
 		return fmt.Errorf("input directory does not exist: %s", inputDir)
 	}
+	repoDir := inputDir
+	if err == nil && info.Mode().IsRegular() {
+		repoDir = filepath.Dir(inputDir)
+	}
+
+	// The result stream is JSON lines, not a digest, so it gets its own
+	// default name and extension
+	outputExt := utils.DefaultOutputExt
+	if jsonStream {
+		outputExt = utils.JSONStreamExt
+		if !cmd.Flags().Changed("output") {
+			outputFile = strings.TrimSuffix(outputFile, filepath.Ext(outputFile)) + outputExt
+		}
+	}
+
+	// Expand output placeholders
+	if outputFile, err = utils.ExpandOutputTemplate(outputFile, dateLayout, repoDir); err != nil {
+		return fmt.Errorf("failed to expand output path: %w", err)
+	}
+	if outputPattern, err = utils.ExpandOutputTemplate(outputPattern, dateLayout, repoDir); err != nil {
+		return fmt.Errorf("failed to expand output pattern: %w", err)
+	}
+	outputFile = utils.WithDefaultExt(outputFile, outputExt)
+	outputPattern = utils.WithDefaultExt(outputPattern, outputExt)
 
 	// Validate and create output directory
 	outputDir := filepath.Dir(outputFile)
@@ -79,9 +454,148 @@ func validateFlags(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 
+	// Validate output mode
+	if _, err := parseOutputMode(outputMode); err != nil {
+		return err
+	}
+
+	// Validate output encoding
+	if !utils.IsSupportedOutputEncoding(outputEncoding) {
+		return fmt.Errorf("output-encoding must be one of utf-8, utf-16le or utf-16be")
+	}
+
+	// Validate output line ending
+	switch outputEOL {
+	case "", utils.LineEndingLF, utils.LineEndingCRLF:
+	default:
+		return fmt.Errorf("output-eol must be lf or crlf")
+	}
+
 	// Validate max file size
-	if maxFileSizeMB <= 0 {
-		return fmt.Errorf("max-size must be greater than 0")
+	if maxFileSizeMB < 0 || (maxFileSizeMB == 0 && !splitOutput) {
+		return fmt.Errorf("max-size must be greater than 0, or 0 with --split for one file per source file")
+	}
+
+	// Validate split overlap
+	if splitOverlap < 0 {
+		return fmt.Errorf("split-overlap must not be negative")
+	}
+
+	if perLanguage && (splitOutput || groupByAge) {
+		return fmt.Errorf("output-per-language cannot be combined with --split or --group-by-age")
+	}
+
+	if jsonStream && (splitOutput || perLanguage || resultsFile != "") {
+		return fmt.Errorf("output-json-stream cannot be combined with --split, --output-per-language or --results-json")
+	}
+
+	if orderFile != "" && (groupByAge || stdinList) {
+		return fmt.Errorf("order-file cannot be combined with --group-by-age or --stdin-list")
+	}
+
+	if verifyLock && lockFile == "" {
+		return fmt.Errorf("verify-lock requires --lockfile")
+	}
+
+	if summarizeOver < 0 {
+		return fmt.Errorf("summarize-over must not be negative")
+	}
+
+	if hardLimitTokens < 0 {
+		return fmt.Errorf("hard-limit-tokens must not be negative")
+	}
+
+	// Validate part count
+	if maxParts < 0 {
+		return fmt.Errorf("max-parts must not be negative")
+	}
+	if maxParts > 0 && !splitOutput {
+		return fmt.Errorf("max-parts requires --split")
+	}
+
+	if splitResume && !splitOutput {
+		return fmt.Errorf("split-resume requires --split")
+	}
+
+	if alsoCombined != "" && !splitOutput {
+		return fmt.Errorf("also-combined requires --split")
+	}
+
+	if splitIndex != "" && !splitOutput {
+		return fmt.Errorf("index requires --split")
+	}
+
+	if partHeaders && !splitOutput {
+		return fmt.Errorf("output-split-manifest-in-each-part requires --split")
+	}
+
+	if partHeaders && splitResume {
+		return fmt.Errorf("output-split-manifest-in-each-part cannot be used with --split-resume")
+	}
+
+	// Validate flush interval
+	if flushInterval < 0 {
+		return fmt.Errorf("flush-interval must not be negative")
+	}
+
+	// Validate blank line preservation
+	if cmd.Flags().Changed("preserve-blank-lines") {
+		if preserveBlank < 0 {
+			return fmt.Errorf("preserve-blank-lines must not be negative")
+		}
+		if !removeWhitespace {
+			return fmt.Errorf("preserve-blank-lines requires --whitespace-removal")
+		}
+	}
+
+	// Validate hash algorithm
+	if _, err := utils.NewHasher(hashAlgo); err != nil {
+		return err
+	}
+
+	// Validate front matter options
+	if stripFM && !promoteFM {
+		return fmt.Errorf("strip-frontmatter requires --promote-frontmatter")
+	}
+
+	// Validate max age
+	if maxAge != "" {
+		if _, err := utils.ParseAge(maxAge); err != nil {
+			return err
+		}
+	}
+
+	// Validate read throttle
+	if maxReadRate < 0 {
+		return fmt.Errorf("max-read-bytes-per-sec must not be negative")
+	}
+
+	if maxBinarySizeKB < 0 {
+		return fmt.Errorf("max-binary-size must not be negative")
+	}
+
+	if wrapMarkdownWidth < 0 {
+		return fmt.Errorf("word-wrap-markdown must not be negative")
+	}
+
+	if pathDepth < 0 {
+		return fmt.Errorf("path-depth must not be negative")
+	}
+	if pathDepth > 0 && trimPrefix {
+		return fmt.Errorf("path-depth cannot be combined with --trim-common-prefix")
+	}
+
+	if resultsContent && resultsFile == "" {
+		return fmt.Errorf("results-json-content requires --results-json")
+	}
+
+	if concurrency < 0 {
+		return fmt.Errorf("concurrency must not be negative")
+	}
+
+	// Validate max runtime
+	if maxRuntime < 0 {
+		return fmt.Errorf("max-runtime must not be negative")
 	}
 
 	// Validate chunk size
@@ -89,6 +603,67 @@ func validateFlags(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("chunk-size must be greater than 0")
 	}
 
+	// Validate per-file token limit
+	if maxTokensPerFile < 0 {
+		return fmt.Errorf("max-tokens-per-file must not be negative")
+	}
+
+	// Validate block spacing
+	if newlinesBetween < 0 {
+		return fmt.Errorf("newline-between-files must not be negative")
+	}
+	if strings.Contains(blockSeparator, "```") || strings.Contains(blockSeparator, "\n") {
+		return fmt.Errorf("block-separator must be a single line without code fences")
+	}
+
+	// Validate header format
+	if err := utils.ValidateHeaderFormat(headerFormat); err != nil {
+		return err
+	}
+
+	// Validate language overrides
+	for _, spec := range langOverrides {
+		if _, err := utils.ParseLanguageOverride(spec); err != nil {
+			return err
+		}
+	}
+
+	// Validate markdown mode
+	switch markdownMode {
+	case processor.MarkdownModeRaw, processor.MarkdownModeFenced, processor.MarkdownModeEscape:
+	default:
+		return fmt.Errorf("markdown-mode must be one of raw, fenced or escape")
+	}
+
+	// Validate binary manifest mode
+	switch binaryManifest {
+	case processor.BinaryManifestNone, processor.BinaryManifestBlock, processor.BinaryManifestSidecar:
+	default:
+		return fmt.Errorf("binary-manifest must be one of none, block or sidecar")
+	}
+	if emitEmptyBinaries && binaryManifest == processor.BinaryManifestNone {
+		return fmt.Errorf("emit-empty-binary-section requires --binary-manifest")
+	}
+
+	// Validate embedded BOM mode
+	switch embeddedBOMMode {
+	case processor.EmbeddedBOMWarn, processor.EmbeddedBOMStrip, processor.EmbeddedBOMFail:
+	default:
+		return fmt.Errorf("embedded-bom must be one of warn, strip or fail")
+	}
+
+	switch duplicateHeaders {
+	case processor.DuplicateHeadersDisambiguate, processor.DuplicateHeadersFail:
+	default:
+		return fmt.Errorf("duplicate-headers must be one of disambiguate or fail")
+	}
+
+	switch theme {
+	case processor.ThemeFancy, processor.ThemePlain, processor.ThemeMinimal:
+	default:
+		return fmt.Errorf("theme must be one of fancy, plain or minimal")
+	}
+
 	// Validate output pattern if provided
 	if splitOutput && outputPattern != "" {
 		_ = fmt.Sprintf(outputPattern, 1)
@@ -98,18 +673,160 @@ func validateFlags(cmd *cobra.Command, args []string) error {
 }
 
 func runDigest(cmd *cobra.Command, args []string) error {
+	manager := config.NewManager(configFile)
+	if sinceLastRun && stateFile == "" {
+		stateFile = manager.GetStatePath()
+	}
+
+	mode, err := parseOutputMode(outputMode)
+	if err != nil {
+		return err
+	}
+
+	settings, err := manager.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
 	// Create processor configuration
 	config := processor.ProcessorConfig{
-		InputDir:          inputDir,
-		OutputFile:        outputFile,
-		UseDefaultIgnores: useDefaultIgnores,
-		RemoveWhitespace:  removeWhitespace,
-		ShowOutputFiles:   showOutputFiles,
-		IgnoreFile:        ignoreFile,
-		Split:             splitOutput,
-		MaxFileSizeMB:     maxFileSizeMB,
-		OutputFilePattern: outputPattern,
-		ChunkSize:         chunkSize * 1024 * 1024, // Convert to bytes
+		InputDir:             inputDir,
+		OutputFile:           outputFile,
+		UseDefaultIgnores:    useDefaultIgnores,
+		RemoveWhitespace:     removeWhitespace,
+		ShowOutputFiles:      showOutputFiles,
+		ShowIgnored:          showIgnored,
+		IgnoreFile:           ignoreFile,
+		Split:                splitOutput,
+		MaxFileSizeMB:        maxFileSizeMB,
+		SplitPerFile:         splitOutput && maxFileSizeMB == 0,
+		OutputFilePattern:    outputPattern,
+		ChunkSize:            chunkSize * 1024 * 1024, // Convert to bytes
+		ParallelWrite:        parallelWrite,
+		SplitOverlap:         splitOverlap,
+		SplitResume:          splitResume,
+		AlsoCombined:         alsoCombined,
+		SplitIndex:           splitIndex,
+		PartHeaders:          partHeaders,
+		MaxParts:             maxParts,
+		CleanStale:           cleanStale,
+		LockFile:             lockFile,
+		HardLimitTokens:      hardLimitTokens,
+		SummarizeOver:        summarizeOver,
+		RespectEditorConfig:  editorConfig,
+		ResolveIncludes:      resolveIncludes,
+		NoteSymlinks:         noteSymlinks,
+		VerifyLock:           verifyLock,
+		Dedent:               dedent,
+		DetectLanguage:       detectLanguage,
+		WrapMarkdown:         wrapMarkdownWidth,
+		MarkdownPlainText:    markdownPlain,
+		CollapseImports:      collapseImports,
+		EmbeddedBOMMode:      embeddedBOMMode,
+		DuplicateHeaders:     duplicateHeaders,
+		GitAuthors:           gitAuthors,
+		GitHeader:            gitHeader,
+		Instructions:         instructions,
+		MaxTokensPerFile:     maxTokensPerFile,
+		ExcludeContent:       excludeContent,
+		IncludeContent:       includeContent,
+		ReportDuplicates:     reportDuplicates,
+		ReportClones:         reportClones,
+		Strict:               strict,
+		HashAlgo:             hashAlgo,
+		SkipGeneratedHeader:  skipGenHeader,
+		ExcludeTests:         excludeTests,
+		TestPatterns:         testPatterns,
+		OnlyTests:            onlyTests,
+		WithTestedSource:     withTestedSource,
+		CompareBaseline:      compareBaseline,
+		PromoteFrontMatter:   promoteFM,
+		SignaturesOnly:       signaturesOnly,
+		GroupByAge:           groupByAge,
+		OutputPerLanguage:    perLanguage,
+		TrimCommonPrefix:     trimPrefix,
+		PathDepth:            pathDepth,
+		StripFrontMatter:     stripFM,
+		OutputMode:           mode,
+		Prioritize:           prioritize,
+		OrderFile:            orderFile,
+		OutputEncoding:       outputEncoding,
+		ParentIgnores:        parentIgnores,
+		IgnoreCeiling:        ignoreCeiling,
+		NewlinesBetweenFiles: blankLinesBetween(newlinesBetween),
+		BlockSeparator:       blockSeparator,
+		HeaderMetadata:       headerMetadata,
+		HashHeaders:          hashHeaders,
+		Theme:                theme,
+		IncludeFile:          includeFile,
+		StripTrailingWS:      stripTrailingWS,
+		MarkdownMode:         markdownMode,
+		TypeLimits:           typeLimits(settings.TypeLimits),
+		TreatAsText:          append(settings.TreatAsText, treatText...),
+		TreatAsBinary:        append(settings.TreatAsBinary, treatBinary...),
+		BinarySampleBytes:    binarySample,
+		CompactBinaries:      compactBinaries,
+		MaxBinarySize:        maxBinarySizeKB * 1024,
+		BinaryManifest:       binaryManifest,
+		ResultsFile:          resultsFile,
+		ResultsContent:       resultsContent,
+		JSONCompact:          jsonCompact,
+		JSONStream:           jsonStream,
+		EmitEmptyManifest:    emitEmptyBinaries,
+		QuickEstimate:        quickEstimate,
+		OnlyTracked:          onlyTracked,
+		CommitRange:          commitRange,
+		MaxReadBytesPerSec:   maxReadRate,
+		Concurrency:          concurrency,
+		FlushInterval:        flushInterval,
+		OutputEOL:            outputEOL,
+		CollectTodos:         collectTodos,
+		PreserveBlankLines:   preserveBlank,
+		TransformOrder:       transformOrder,
+		HeaderFormat:         headerFormat,
+		SinceLastRun:         sinceLastRun,
+		StateFile:            stateFile,
+	}
+
+	if pruneNoise {
+		config.ApplyPruneNoisePreset()
+	}
+
+	if stdinList {
+		config.UseFileList = true
+		if config.FileList, err = utils.ReadPathList(os.Stdin, stdinListNul); err != nil {
+			return fmt.Errorf("failed to read path list: %w", err)
+		}
+	}
+
+	// Flag overrides take precedence over the config's
+	for _, spec := range langOverrides {
+		override, err := utils.ParseLanguageOverride(spec)
+		if err != nil {
+			return err
+		}
+		config.LanguageOverrides = append(config.LanguageOverrides, override)
+	}
+	config.LanguageOverrides = append(config.LanguageOverrides, settings.LanguageOverrides...)
+
+	if maxAge != "" {
+		if config.MaxAge, err = utils.ParseAge(maxAge); err != nil {
+			return err
+		}
+	}
+
+	if skipVendored {
+		config.ExtraIgnores = append(config.ExtraIgnores, utils.VendoredIgnores...)
+	}
+
+	if includeEnvExample {
+		config.ExtraIgnores = append(config.ExtraIgnores, utils.EnvExampleIncludes...)
+	}
+
+	if !force && !config.SplitResume {
+		if err := confirmOverwrite(config, os.Stdin, utils.IsTerminal(os.Stdin), os.Stdout); err != nil {
+			return err
+		}
 	}
 
 	// Create processor instance
@@ -118,10 +835,94 @@ func runDigest(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create processor: %w", err)
 	}
 
+	ctx := cmd.Context()
+	if maxRuntime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, maxRuntime)
+		defer cancel()
+	}
+
 	// Process the codebase
-	if err := proc.Process(); err != nil {
+	if err := proc.Process(ctx); err != nil {
 		return fmt.Errorf("processing failed: %w", err)
 	}
 
 	return nil
 }
+
+// applyCompactPreset enables the token-saving flags bundled by --compact,
+// leaving any the user set explicitly untouched
+func applyCompactPreset(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	if !flags.Changed("strip-trailing-ws") {
+		stripTrailingWS = true
+	}
+	if !flags.Changed("whitespace-removal") {
+		removeWhitespace = true
+	}
+	if !flags.Changed("preserve-blank-lines") && removeWhitespace {
+		preserveBlank = 1
+	}
+	if !flags.Changed("collapse-imports") {
+		collapseImports = true
+	}
+	if !flags.Changed("compact-binaries") {
+		compactBinaries = true
+	}
+}
+
+// parseOutputMode parses an octal permission string such as "0600"
+func parseOutputMode(s string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode == 0 || mode > 0777 {
+		return 0, fmt.Errorf("output-mode must be an octal permission between 0001 and 0777: %s", s)
+	}
+	return os.FileMode(mode), nil
+}
+
+// typeLimits converts per-extension limits from the config file to processor limits
+func typeLimits(limits map[string]config.TypeLimit) map[string]processor.TypeLimit {
+	result := make(map[string]processor.TypeLimit, len(limits))
+	for ext, limit := range limits {
+		ext = strings.ToLower(ext)
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		result[ext] = processor.TypeLimit{
+			MaxSize:  limit.MaxSizeKB * 1024,
+			MaxDepth: limit.MaxDepth,
+		}
+	}
+	return result
+}
+
+// confirmOverwrite asks on in before replacing existing output files when
+// interactive is set. Without a terminal to ask on, outputs are overwritten
+// as they always were, so scripts and CI keep working.
+func confirmOverwrite(cfg processor.ProcessorConfig, in io.Reader, interactive bool, out io.Writer) error {
+	if !interactive {
+		return nil
+	}
+
+	existing, err := cfg.ExistingOutputs()
+	if err != nil {
+		return err
+	}
+	if len(existing) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(out, "%s Output already exists: %s\n", utils.IconWarning, strings.Join(existing, ", "))
+	fmt.Fprint(out, "   Overwrite? [y/N] ")
+
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("failed to read answer: %w", err)
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	default:
+		return fmt.Errorf("aborted: output not overwritten")
+	}
+}
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"bytes"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/richardamare/ai-digest/internal/config"
+	"github.com/richardamare/ai-digest/internal/processor"
+	"github.com/spf13/cobra"
+)
+
+func TestConfirmOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "codebase.md")
+	if err := os.WriteFile(existing, []byte("old digest\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		output      string
+		interactive bool
+		answer      string
+		wantErr     bool
+		wantPrompt  bool
+	}{
+		{name: "not a terminal overwrites", output: existing},
+		{name: "confirmed", output: existing, interactive: true, answer: "y\n", wantPrompt: true},
+		{name: "confirmed in full", output: existing, interactive: true, answer: "Yes\n", wantPrompt: true},
+		{name: "declined", output: existing, interactive: true, answer: "n\n", wantErr: true, wantPrompt: true},
+		{name: "no answer", output: existing, interactive: true, answer: "", wantErr: true, wantPrompt: true},
+		{name: "new output", output: filepath.Join(dir, "new.md"), interactive: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			cfg := processor.ProcessorConfig{OutputFile: tt.output}
+
+			err := confirmOverwrite(cfg, strings.NewReader(tt.answer), tt.interactive, &out)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("confirmOverwrite() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if prompted := strings.Contains(out.String(), "Overwrite?"); prompted != tt.wantPrompt {
+				t.Errorf("prompted = %v, want %v (output %q)", prompted, tt.wantPrompt, out.String())
+			}
+		})
+	}
+}
+
+func TestTypeLimits(t *testing.T) {
+	got := typeLimits(map[string]config.TypeLimit{
+		"LOG": {MaxSizeKB: 2},
+		".go": {MaxDepth: 3},
+	})
+
+	want := map[string]processor.TypeLimit{
+		".log": {MaxSize: 2048},
+		".go":  {MaxDepth: 3},
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("typeLimits() = %v, want %v", got, want)
+	}
+}
+
+func TestParseOutputMode(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    os.FileMode
+		wantErr bool
+	}{
+		{"644", 0644, false},
+		{"0600", 0600, false},
+		{"0", 0, true},
+		{"1000", 0, true},
+		{"rw-r--r--", 0, true},
+		{"999", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseOutputMode(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseOutputMode(%q) = %o, %v, want %o, error %v", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateStdinSources(t *testing.T) {
+	tests := []struct {
+		name    string
+		ignore  string
+		include string
+		list    bool
+		wantErr string
+	}{
+		{"ignore and include", "-", "-", false, "cannot both read from stdin"},
+		{"stdin list and ignore", "-", "", true, "stdin-list cannot be combined"},
+		{"stdin list and include", ".aidigestignore", "-", true, "stdin-list cannot be combined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ignoreFile, includeFile, stdinList = tt.ignore, tt.include, tt.list
+			t.Cleanup(func() { ignoreFile, includeFile, stdinList = ".aidigestignore", "", false })
+
+			if err := validateFlags(&cobra.Command{}, nil); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateFlags() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateMaxRuntime(t *testing.T) {
+	maxRuntime = -time.Second
+	t.Cleanup(func() { maxRuntime = 0 })
+
+	if err := validateFlags(&cobra.Command{}, nil); err == nil || !strings.Contains(err.Error(), "max-runtime") {
+		t.Errorf("validateFlags() error = %v, want max-runtime rejected", err)
+	}
+}
+
+func TestApplyCompactPreset(t *testing.T) {
+	t.Cleanup(func() {
+		stripTrailingWS, removeWhitespace, preserveBlank, collapseImports, compactBinaries = false, false, 0, false, false
+	})
+
+	cmd := &cobra.Command{}
+	cmd.Flags().BoolVar(&collapseImports, "collapse-imports", false, "")
+	if err := cmd.Flags().Set("collapse-imports", "false"); err != nil {
+		t.Fatal(err)
+	}
+
+	applyCompactPreset(cmd)
+
+	if !stripTrailingWS || !removeWhitespace || preserveBlank != 1 || !compactBinaries {
+		t.Errorf("preset left strip=%v whitespace=%v preserve=%d binaries=%v, want all enabled",
+			stripTrailingWS, removeWhitespace, preserveBlank, compactBinaries)
+	}
+	// Flags given explicitly win over the preset
+	if collapseImports {
+		t.Error("preset overrode an explicit --collapse-imports=false")
+	}
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/richardamare/ai-digest/internal/config"
+	"github.com/richardamare/ai-digest/internal/processor"
+	"github.com/spf13/cobra"
+)
+
+// doctorSample is the number of excluded paths doctor lists
+var doctorSample int
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Explain which files a digest would include and why",
+	Long: `Report the effective configuration, the ignore patterns in the order they
+apply and where each came from, and a sample of excluded files with the
+pattern that excludes them. Also warns about patterns that match nothing and
+flags that behave differently than their names suggest. No digest is written.`,
+	Example: `  ai-digest doctor
+  ai-digest doctor -i /path/to/project --sample 50`,
+	RunE: runDoctor,
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	doctorCmd.Flags().StringVarP(&inputDir, "input", "i", ".",
+		"Input directory containing the codebase")
+	doctorCmd.Flags().BoolVar(&useDefaultIgnores, "no-default-ignores", true,
+		"Disable default ignore patterns")
+	doctorCmd.Flags().StringVar(&ignoreFile, "ignore-file", ".aidigestignore",
+		"Custom ignore file name")
+	doctorCmd.Flags().StringVar(&includeFile, "include-file", "",
+		"File of patterns that files must match to be included")
+	doctorCmd.Flags().BoolVar(&parentIgnores, "respect-ignore-from-parents", false,
+		"Apply .gitignore and ignore files from directories above the input directory")
+	doctorCmd.Flags().StringVar(&ignoreCeiling, "ignore-ceiling", "",
+		"Highest directory searched for parent ignore files (defaults to the git repository root)")
+	doctorCmd.Flags().StringVar(&configFile, "config", "",
+		"config file path (defaults to ./ai-digest.json)")
+	doctorCmd.Flags().IntVar(&doctorSample, "sample", 20,
+		"Number of excluded paths to list, 0 for all")
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	if _, err := os.Stat(inputDir); os.IsNotExist(err) {
+		return fmt.Errorf("input directory does not exist: %s", inputDir)
+	}
+
+	var warnings []string
+
+	manager := config.NewManager(configFile)
+	fmt.Printf("Config file: %s\n", manager.GetConfigPath())
+	if manager.Exists() {
+		settings, diag, err := manager.Validate()
+		if err != nil {
+			return err
+		}
+		warnings = append(warnings, diag.Warnings...)
+		warnings = append(warnings, diag.Errors...)
+		if settings != nil && settings.IgnoreFile != "" && settings.IgnoreFile != ignoreFile {
+			warnings = append(warnings, fmt.Sprintf("config ignoreFile %q is not used by digest; pass --ignore-file %s to use it",
+				settings.IgnoreFile, settings.IgnoreFile))
+		}
+	} else {
+		fmt.Println("  (not found, using defaults)")
+	}
+	output, err := manager.Show()
+	if err != nil {
+		return fmt.Errorf("failed to show config: %w", err)
+	}
+	fmt.Println(output)
+
+	if cmd.Flags().Changed("no-default-ignores") && useDefaultIgnores {
+		warnings = append(warnings, "--no-default-ignores keeps default ignores enabled; use --no-default-ignores=false to disable them")
+	}
+
+	proc, err := processor.NewProcessor(processor.ProcessorConfig{
+		InputDir:          inputDir,
+		UseDefaultIgnores: useDefaultIgnores,
+		IgnoreFile:        ignoreFile,
+		IncludeFile:       includeFile,
+		ParentIgnores:     parentIgnores,
+		IgnoreCeiling:     ignoreCeiling,
+		Diagnose:          true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create processor: %w", err)
+	}
+
+	diag, err := proc.Diagnose(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("failed to collect files: %w", err)
+	}
+
+	fmt.Println("\nIgnore patterns:")
+	for _, source := range diag.Sources {
+		fmt.Printf("  %s (%d)\n", source.Source, len(source.Patterns))
+		for _, pattern := range source.Patterns {
+			fmt.Printf("    %s\n", pattern)
+		}
+	}
+
+	fmt.Printf("\nFiles included: %d\n", diag.Included)
+	fmt.Printf("Paths excluded: %d\n", len(diag.Excluded))
+	for i, e := range diag.Excluded {
+		if doctorSample > 0 && i == doctorSample {
+			fmt.Printf("  ... and %d more (use --sample 0 to list all)\n", len(diag.Excluded)-i)
+			break
+		}
+		path := e.Path
+		if e.Dir {
+			path += "/"
+		}
+		if e.Rule != nil {
+			fmt.Printf("  %s  <- %s (%s)\n", path, e.Rule.Pattern, e.Rule.Source)
+		} else {
+			fmt.Printf("  %s  <- %s\n", path, e.Reason)
+		}
+	}
+
+	for _, rule := range diag.Unused {
+		warnings = append(warnings, fmt.Sprintf("pattern %q in %s matches nothing", rule.Pattern, rule.Source))
+	}
+
+	if len(warnings) > 0 {
+		fmt.Println("\nWarnings:")
+		for _, warning := range warnings {
+			fmt.Printf("  %s\n", warning)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/richardamare/ai-digest/internal/processor"
+	"github.com/spf13/cobra"
+)
+
+var languagesCmd = &cobra.Command{
+	Use:   "languages",
+	Short: "List the languages in a codebase",
+	Long: `Walk the input directory, respecting ignore patterns, and print a table of
+languages by file count and size. No digest is written.`,
+	Example: `  ai-digest languages
+  ai-digest languages -i /path/to/project`,
+	RunE: runLanguages,
+}
+
+func init() {
+	rootCmd.AddCommand(languagesCmd)
+
+	languagesCmd.Flags().StringVarP(&inputDir, "input", "i", ".",
+		"Input directory containing the codebase")
+	languagesCmd.Flags().BoolVar(&useDefaultIgnores, "no-default-ignores", true,
+		"Disable default ignore patterns")
+	languagesCmd.Flags().StringVar(&ignoreFile, "ignore-file", ".aidigestignore",
+		"Custom ignore file name, or - to read patterns from stdin")
+}
+
+func runLanguages(cmd *cobra.Command, args []string) error {
+	if _, err := os.Stat(inputDir); os.IsNotExist(err) {
+		return fmt.Errorf("input directory does not exist: %s", inputDir)
+	}
+
+	proc, err := processor.NewProcessor(processor.ProcessorConfig{
+		InputDir:          inputDir,
+		UseDefaultIgnores: useDefaultIgnores,
+		IgnoreFile:        ignoreFile,
+		ListLanguages:     true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create processor: %w", err)
+	}
+
+	return proc.Process(cmd.Context())
+}
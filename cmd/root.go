@@ -1,18 +1,33 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
+	"github.com/richardamare/ai-digest/internal/processor"
+	"github.com/richardamare/ai-digest/internal/utils"
 	"github.com/spf13/cobra"
 )
 
+// noEmoji replaces emoji in console output with ASCII tags
+var noEmoji bool
+
+// Exit codes for runs that stop early with partial output
+const (
+	exitTimeout    = 3 // Truncated by --max-runtime
+	exitTokenLimit = 4 // Stopped by --hard-limit-tokens
+)
+
 var (
 	rootCmd = &cobra.Command{
 		Use:     "ai-digest",
 		Short:   "AI Digest - Code aggregation tool for AI assistants",
 		Long:    `AI Digest aggregates your codebase into a single markdown file for easy sharing with AI assistants.`,
 		Version: "1.0.0",
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			utils.SetEmoji(!noEmoji && utils.IsUTF8Terminal())
+		},
 	}
 )
 
@@ -20,10 +35,18 @@ var (
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
+		if errors.Is(err, processor.ErrTimeout) {
+			os.Exit(exitTimeout)
+		}
+		if errors.Is(err, processor.ErrTokenLimit) {
+			os.Exit(exitTokenLimit)
+		}
 		os.Exit(1)
 	}
 }
 
 func init() {
 	rootCmd.AddCommand(digestCmd)
+	rootCmd.PersistentFlags().BoolVar(&noEmoji, "no-emoji", false,
+		"Use plain ASCII tags instead of emoji in console output (automatic when stdout isn't a UTF-8 terminal)")
 }
@@ -1,21 +1,62 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"github.com/richardamare/ai-digest/internal/utils"
 )
 
 const (
 	// DefaultConfigFile is the default configuration filename
 	DefaultConfigFile = "ai-digest.json"
+
+	// DefaultStateFile is the default filename for persisted run state
+	DefaultStateFile = "ai-digest.state.json"
 )
 
+// DefaultIgnoreTemplate is the starter content for a scaffolded ignore file
+const DefaultIgnoreTemplate = `# AI Digest ignore file
+# Uses .gitignore syntax. Prefix a pattern with ! to re-include a file.
+
+# Dependencies
+node_modules/
+vendor/
+
+# Build outputs
+dist/
+build/
+
+# IDE files
+.vscode/
+.idea/
+
+# Logs
+*.log
+
+# Environment files
+.env*
+`
+
 // Config represents the application configuration
 type Config struct {
-	DefaultIgnores []string `json:"defaultIgnores"`
-	IgnoreFile     string   `json:"ignoreFile"`
+	DefaultIgnores []string             `json:"defaultIgnores"`
+	IgnoreFile     string               `json:"ignoreFile"`
+	TypeLimits     map[string]TypeLimit `json:"typeLimits,omitempty"`
+	TreatAsText    []string             `json:"treatAsText,omitempty"`   // Extensions always read as text
+	TreatAsBinary  []string             `json:"treatAsBinary,omitempty"` // Extensions always listed as binary
+
+	LanguageOverrides []utils.LanguageOverride `json:"languageOverrides,omitempty"` // Fence languages by extension or glob
+}
+
+// TypeLimit restricts files with a given extension
+type TypeLimit struct {
+	MaxSizeKB int64 `json:"maxSizeKB,omitempty"` // Maximum file size, 0 for no limit
+	MaxDepth  int   `json:"maxDepth,omitempty"`  // Maximum path depth, 1 for top level only, 0 for no limit
 }
 
 // Manager handles configuration file operations
@@ -69,6 +110,25 @@ func (m *Manager) Init() error {
 	return m.Save(cfg)
 }
 
+// GetIgnorePath returns the path of the ignore file next to the configuration file
+func (m *Manager) GetIgnorePath(ignoreFile string) string {
+	return filepath.Join(filepath.Dir(m.configPath), ignoreFile)
+}
+
+// InitIgnoreFile creates a starter ignore file next to the configuration file
+func (m *Manager) InitIgnoreFile(ignoreFile string) error {
+	path := m.GetIgnorePath(ignoreFile)
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("ignore file already exists: %s", path)
+	}
+
+	if err := os.WriteFile(path, []byte(DefaultIgnoreTemplate), 0644); err != nil {
+		return fmt.Errorf("failed to write ignore file: %w", err)
+	}
+
+	return nil
+}
+
 // Load reads and parses the configuration file
 func (m *Manager) Load() (*Config, error) {
 	data, err := os.ReadFile(m.configPath)
@@ -89,6 +149,72 @@ func (m *Manager) Load() (*Config, error) {
 	return &cfg, nil
 }
 
+// Diagnostics holds the problems found while validating a configuration file
+type Diagnostics struct {
+	Errors   []string
+	Warnings []string
+}
+
+// Validate loads the configuration file and checks it for unknown keys and
+// invalid values. A config that fails to parse is returned as nil.
+func (m *Manager) Validate() (*Config, *Diagnostics, error) {
+	data, err := os.ReadFile(m.configPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	diag := &Diagnostics{}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		diag.Errors = append(diag.Errors, fmt.Sprintf("failed to parse config file: %v", err))
+		return nil, diag, nil
+	}
+
+	// Decode again strictly to surface keys the loader silently ignores
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&Config{}); err != nil {
+		diag.Warnings = append(diag.Warnings, strings.TrimPrefix(err.Error(), "json: "))
+	}
+
+	if cfg.IgnoreFile == "" {
+		diag.Warnings = append(diag.Warnings, "ignoreFile is empty")
+	}
+
+	for ext, limit := range cfg.TypeLimits {
+		if !strings.HasPrefix(ext, ".") {
+			diag.Warnings = append(diag.Warnings, fmt.Sprintf("typeLimits key %q should start with a dot", ext))
+		}
+		if limit.MaxSizeKB < 0 {
+			diag.Errors = append(diag.Errors, fmt.Sprintf("typeLimits %q: maxSizeKB must not be negative", ext))
+		}
+		if limit.MaxDepth < 0 {
+			diag.Errors = append(diag.Errors, fmt.Sprintf("typeLimits %q: maxDepth must not be negative", ext))
+		}
+	}
+
+	for _, o := range cfg.LanguageOverrides {
+		if o.Pattern == "" || o.Language == "" {
+			diag.Errors = append(diag.Errors, "languageOverrides entries need both a pattern and a language")
+		} else if err := utils.ValidatePattern(o.Pattern); err != nil {
+			diag.Errors = append(diag.Errors, fmt.Sprintf("languageOverrides: %v", err))
+		}
+	}
+
+	textExts := make(map[string]bool, len(cfg.TreatAsText))
+	for _, ext := range cfg.TreatAsText {
+		textExts[strings.ToLower(ext)] = true
+	}
+	for _, ext := range cfg.TreatAsBinary {
+		if textExts[strings.ToLower(ext)] {
+			diag.Errors = append(diag.Errors, fmt.Sprintf("extension %q is listed in both treatAsText and treatAsBinary", ext))
+		}
+	}
+
+	return &cfg, diag, nil
+}
+
 // Save writes the configuration to file
 func (m *Manager) Save(cfg Config) error {
 	data, err := json.MarshalIndent(cfg, "", "  ")
@@ -123,6 +249,11 @@ func (m *Manager) GetConfigPath() string {
 	return m.configPath
 }
 
+// GetStatePath returns the run state file path next to the configuration file
+func (m *Manager) GetStatePath() string {
+	return filepath.Join(filepath.Dir(m.configPath), DefaultStateFile)
+}
+
 // Exists checks if the configuration file exists
 func (m *Manager) Exists() bool {
 	_, err := os.Stat(m.configPath)
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestGetStatePath(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name       string
+		configPath string
+		want       string
+	}{
+		{"explicit config", filepath.Join(dir, "team", "digest.json"), filepath.Join(dir, "team", DefaultStateFile)},
+		{"config in dir", filepath.Join(dir, DefaultConfigFile), filepath.Join(dir, DefaultStateFile)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewManager(tt.configPath).GetStatePath(); got != tt.want {
+				t.Errorf("GetStatePath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
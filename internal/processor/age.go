@@ -0,0 +1,43 @@
+package processor
+
+import (
+	"sort"
+	"time"
+)
+
+// ageBucket groups files by how recently they were modified
+type ageBucket struct {
+	title  string
+	maxAge time.Duration // Upper bound of the bucket, 0 for unbounded
+}
+
+// ageBuckets are the sections used by GroupByAge, newest first
+var ageBuckets = []ageBucket{
+	{title: "Modified in the last 30 days", maxAge: 30 * 24 * time.Hour},
+	{title: "Modified in the last 90 days", maxAge: 90 * 24 * time.Hour},
+	{title: "Older", maxAge: 0},
+}
+
+// ageBucketFor returns the index of the bucket a file falls into
+func (p *Processor) ageBucketFor(relPath string) int {
+	age := p.now.Sub(p.modTimes[relPath])
+	for i, bucket := range ageBuckets {
+		if bucket.maxAge == 0 || age <= bucket.maxAge {
+			return i
+		}
+	}
+	return len(ageBuckets) - 1
+}
+
+// groupFilesByAge stably reorders files so each age bucket is contiguous
+func (p *Processor) groupFilesByAge(files []string) []string {
+	sort.SliceStable(files, func(i, j int) bool {
+		return p.ageBucketFor(files[i]) < p.ageBucketFor(files[j])
+	})
+	return files
+}
+
+// formatAgeHeading renders the section heading for an age bucket
+func (p *Processor) formatAgeHeading(bucket int) string {
+	return "# " + ageBuckets[bucket].title + "\n\n" + p.blockSeparator()
+}
@@ -0,0 +1,150 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/richardamare/ai-digest/internal/utils"
+)
+
+// Labels for pattern sources that aren't read from an ignore file
+const (
+	sourceDefaults = "built-in defaults"
+	sourcePresets  = "presets"
+)
+
+// Exclusion is a path left out of the digest and the reason why
+type Exclusion struct {
+	Path   string
+	Dir    bool              // Whole directory skipped
+	Rule   *utils.IgnoreRule // Pattern responsible, nil when excluded for another reason
+	Reason string            // Set when Rule is nil
+}
+
+// Diagnosis describes how ignore rules apply to the input
+type Diagnosis struct {
+	Sources  []utils.ScopedPatterns // Ignore patterns by source in the order they apply
+	Included int                    // Files that pass the ignore rules
+	Excluded []Exclusion            // Excluded files and directories in walk order
+	Unused   []*utils.IgnoreRule    // Patterns from ignore files that matched nothing
+}
+
+// ignoreSources lists the patterns the matcher is built from, labelled by
+// where they came from
+func ignoreSources(cfg ProcessorConfig, patterns []string) []utils.ScopedPatterns {
+	var sources []utils.ScopedPatterns
+	if cfg.UseDefaultIgnores {
+		sources = append(sources, utils.ScopedPatterns{Source: sourceDefaults, Patterns: utils.DefaultIgnores})
+	}
+	if len(cfg.ExtraIgnores) > 0 {
+		sources = append(sources, utils.ScopedPatterns{Source: sourcePresets, Patterns: cfg.ExtraIgnores})
+	}
+
+	source := filepath.Join(cfg.InputDir, cfg.IgnoreFile)
+	if cfg.IgnoreFile == utils.StdinPatternSource {
+		source = "stdin"
+	}
+	return append(sources, utils.ScopedPatterns{Source: source, Patterns: patterns})
+}
+
+// Diagnose walks the input the way collectFiles does and records which
+// paths are excluded and by which pattern. No output is written.
+func (p *Processor) Diagnose(ctx context.Context) (*Diagnosis, error) {
+	diag := &Diagnosis{Sources: p.ignoreSources}
+	explainer := utils.NewIgnoreExplainer(p.ignoreSources)
+
+	root := p.config.InputDir
+	if p.singleFile != "" {
+		root = filepath.Join(root, p.singleFile)
+	}
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(p.config.InputDir, path)
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if relPath == "." {
+				return nil
+			}
+			rule := explainer.Explain(relPath + "/")
+			if p.matcher.SkipsDir(relPath) {
+				diag.Excluded = append(diag.Excluded, exclusion(relPath, true, rule))
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rule := explainer.Explain(relPath)
+		switch {
+		case p.matcher.ShouldIgnore(relPath):
+			diag.Excluded = append(diag.Excluded, exclusion(relPath, false, rule))
+		case p.isStateFile(path):
+			diag.Excluded = append(diag.Excluded, Exclusion{Path: relPath, Reason: "state file or lockfile"})
+		case p.include != nil && !p.include.Matches(relPath):
+			diag.Excluded = append(diag.Excluded, Exclusion{Path: relPath, Reason: "not matched by include file"})
+		default:
+			diag.Included++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, source := range p.ignoreSources {
+		if source.Source != sourceDefaults && source.Source != sourcePresets {
+			diag.Unused = append(diag.Unused, explainer.Unused(source.Source)...)
+		}
+	}
+
+	return diag, nil
+}
+
+// explainIgnored returns why collectFiles left out the file at relPath
+func (p *Processor) explainIgnored(explainer *utils.IgnoreExplainer, relPath, path string) Exclusion {
+	switch {
+	case p.matcher.ShouldIgnore(relPath):
+		return exclusion(relPath, false, explainer.Explain(relPath))
+	case p.isStateFile(path):
+		return Exclusion{Path: relPath, Reason: "state file or lockfile"}
+	case p.include != nil && !p.include.Matches(relPath):
+		return Exclusion{Path: relPath, Reason: "not matched by include file"}
+	case p.tracked != nil && !p.tracked[filepath.ToSlash(relPath)]:
+		return Exclusion{Path: relPath, Reason: "not tracked by git"}
+	default:
+		return Exclusion{Path: relPath, Reason: "not changed in commit range"}
+	}
+}
+
+// String describes the exclusion as "path  <- pattern (source)"
+func (e Exclusion) String() string {
+	path := e.Path
+	if e.Dir {
+		path += "/"
+	}
+	if e.Rule != nil {
+		return fmt.Sprintf("%s  <- %s (%s)", path, e.Rule.Pattern, e.Rule.Source)
+	}
+	return fmt.Sprintf("%s  <- %s", path, e.Reason)
+}
+
+// exclusion records an ignored path, falling back to a generic reason when
+// the explainer can't attribute it to a single pattern
+func exclusion(path string, dir bool, rule *utils.IgnoreRule) Exclusion {
+	e := Exclusion{Path: path, Dir: dir, Rule: rule}
+	if rule == nil {
+		e.Reason = "ignore patterns"
+	}
+	return e
+}
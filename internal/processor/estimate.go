@@ -0,0 +1,117 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/richardamare/ai-digest/internal/utils"
+)
+
+const defaultEstimateSampleSize = 50
+
+// Estimate is an approximate token count extrapolated from a sample of files
+type Estimate struct {
+	TotalFiles   int
+	SampledFiles int
+	TotalSize    int64
+	Tokens       int // Best estimate
+	TokensLow    int // Lower bound of the ~95% confidence range
+	TokensHigh   int // Upper bound of the ~95% confidence range
+}
+
+// sampleFiles picks up to n files spread evenly across the list, so the
+// sample is deterministic and covers every part of the tree
+func sampleFiles(files []string, n int) []string {
+	if n <= 0 || len(files) <= n {
+		return files
+	}
+
+	sample := make([]string, 0, n)
+	stride := float64(len(files)) / float64(n)
+	for i := 0; i < n; i++ {
+		sample = append(sample, files[int(float64(i)*stride)])
+	}
+	return sample
+}
+
+// EstimateTokens stats every file for an exact total size, reads only a
+// sample to measure tokens per byte, and extrapolates the total token count.
+// The range is the mean ratio plus or minus two standard errors.
+func EstimateTokens(inputDir string, files []string, sampleSize int) (Estimate, error) {
+	est := Estimate{TotalFiles: len(files)}
+
+	for _, file := range files {
+		info, err := os.Stat(filepath.Join(inputDir, file))
+		if err != nil {
+			return est, err
+		}
+		est.TotalSize += info.Size()
+	}
+
+	var ratios []float64
+	for _, file := range sampleFiles(files, sampleSize) {
+		content, err := os.ReadFile(filepath.Join(inputDir, file))
+		if err != nil {
+			return est, err
+		}
+		if len(content) == 0 {
+			continue
+		}
+		ratios = append(ratios, float64(utils.EstimateTokenCount(string(content)))/float64(len(content)))
+	}
+	est.SampledFiles = len(ratios)
+
+	if len(ratios) == 0 {
+		return est, nil
+	}
+
+	var sum float64
+	for _, r := range ratios {
+		sum += r
+	}
+	mean := sum / float64(len(ratios))
+
+	var variance float64
+	for _, r := range ratios {
+		variance += (r - mean) * (r - mean)
+	}
+	if len(ratios) > 1 {
+		variance /= float64(len(ratios) - 1)
+	}
+	margin := 2 * math.Sqrt(variance/float64(len(ratios)))
+
+	size := float64(est.TotalSize)
+	est.Tokens = int(mean * size)
+	est.TokensLow = int(math.Max(0, mean-margin) * size)
+	est.TokensHigh = int((mean + margin) * size)
+
+	return est, nil
+}
+
+// quickEstimate collects files and prints an extrapolated token estimate
+// without writing any output
+func (p *Processor) quickEstimate(ctx context.Context) error {
+	files, err := p.collectFiles(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return stopError(ctx)
+		}
+		return fmt.Errorf("failed to collect files: %w", err)
+	}
+
+	est, err := EstimateTokens(p.config.InputDir, files, defaultEstimateSampleSize)
+	if err != nil {
+		return fmt.Errorf("failed to estimate tokens: %w", err)
+	}
+
+	fmt.Printf("\n%s Quick Estimate (approximate)\n", utils.IconEstimate)
+	fmt.Println(utils.Rule(31))
+	fmt.Printf("   %s Files:                   %5d (sampled %d)\n", utils.Bullet, est.TotalFiles, est.SampledFiles)
+	fmt.Printf("   %s Total Size:              %.2f MB\n", utils.Bullet, float64(est.TotalSize)/(1024*1024))
+	fmt.Printf("   %s Estimated Tokens:        ~%d (range %d %s %d)\n", utils.Bullet, est.Tokens, est.TokensLow, utils.RangeDash, est.TokensHigh)
+	fmt.Printf("   %s Note: Extrapolated from a sample; no output was written\n", utils.IconNote)
+	return nil
+}
@@ -0,0 +1,59 @@
+package processor
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/richardamare/ai-digest/internal/utils"
+)
+
+func TestSampleFiles(t *testing.T) {
+	files := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}
+
+	if got, want := sampleFiles(files, 3), []string{"a", "d", "g"}; !slices.Equal(got, want) {
+		t.Errorf("sampleFiles(10 files, 3) = %q, want %q", got, want)
+	}
+	if got := sampleFiles(files, 20); !slices.Equal(got, files) {
+		t.Errorf("sampleFiles(10 files, 20) = %q, want every file", got)
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	files := make(map[string]string)
+	var names []string
+	for i := range 20 {
+		name := fmt.Sprintf("file%02d.txt", i)
+		files[name] = strings.Repeat("word ", 40) + "\n"
+		names = append(names, name)
+	}
+	cfg := digestFixture(t, files)
+
+	// Identical files have one tokens-per-byte ratio, so a sample is exact
+	est, err := EstimateTokens(cfg.InputDir, names, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := 20 * utils.EstimateTokenCount(files[names[0]])
+	if est.TotalFiles != 20 || est.SampledFiles != 5 || est.TotalSize != int64(20*len(files[names[0]])) {
+		t.Errorf("EstimateTokens() = %+v, want 20 files, 5 sampled, %d bytes", est, 20*len(files[names[0]]))
+	}
+	if est.Tokens != want || est.TokensLow != want || est.TokensHigh != want {
+		t.Errorf("EstimateTokens() tokens = %d (%d–%d), want exactly %d", est.Tokens, est.TokensLow, est.TokensHigh, want)
+	}
+}
+
+func TestQuickEstimateWritesNoOutput(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{"main.go": "package main\n"})
+	cfg.QuickEstimate = true
+
+	if _, err := processDigest(t, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(cfg.OutputFile); !os.IsNotExist(err) {
+		t.Errorf("quick estimate wrote %s: %v", cfg.OutputFile, err)
+	}
+}
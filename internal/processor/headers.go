@@ -0,0 +1,41 @@
+package processor
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Duplicate header handling modes
+const (
+	DuplicateHeadersDisambiguate = "disambiguate"
+	DuplicateHeadersFail         = "fail"
+)
+
+// checkDuplicateHeader reports a block whose header was already emitted by
+// another file, so header rewrites can't silently merge two files in the
+// output. In disambiguate mode the file path is appended to the repeated
+// header; in fail mode the run stops.
+func (p *Processor) checkDuplicateHeader(result *FileResult, seen map[string]string) error {
+	if result.Header == "" {
+		return nil
+	}
+
+	first, ok := seen[result.Header]
+	if !ok {
+		seen[result.Header] = result.RelativePath
+		return nil
+	}
+
+	if p.config.DuplicateHeaders == DuplicateHeadersFail {
+		return fmt.Errorf("%s and %s have the same header %q", first, result.RelativePath, result.Header)
+	}
+
+	header := fmt.Sprintf("%s [%s]", result.Header, filepath.ToSlash(result.RelativePath))
+	p.logger.LogWarning("%s has the same header as %s; written as %q", result.RelativePath, first, header)
+
+	result.Content = header + strings.TrimPrefix(result.Content, result.Header)
+	result.Header = header
+	seen[header] = result.RelativePath
+	return nil
+}
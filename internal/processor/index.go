@@ -0,0 +1,103 @@
+package processor
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/richardamare/ai-digest/internal/utils"
+)
+
+// noteFile marks relPath as the file whose content is written next, so the
+// part receiving it lists it in the split index
+func (w *multiFileWriter) noteFile(relPath string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.nextFile = relPath
+}
+
+// recordFile adds the noted file to the files of part, which is 1-based
+func (w *multiFileWriter) recordFile(part int) {
+	if w.nextFile == "" {
+		return
+	}
+	for len(w.partFiles) < part {
+		w.partFiles = append(w.partFiles, nil)
+	}
+	w.partFiles[part-1] = append(w.partFiles[part-1], w.nextFile)
+	w.nextFile = ""
+}
+
+// recordOverlap notes the files repeated at the top of part, which is 1-based
+func (w *multiFileWriter) recordOverlap(part int, files []string) {
+	if len(files) == 0 {
+		return
+	}
+	for len(w.overlap) < part {
+		w.overlap = append(w.overlap, nil)
+	}
+	w.overlap[part-1] = files
+}
+
+// writeSplitIndex writes a markdown index linking each split part, with its
+// size and the files it contains. It runs once the parts are closed.
+func (p *Processor) writeSplitIndex() error {
+	mw, ok := p.writer.(*multiFileWriter)
+	if !ok {
+		return nil
+	}
+
+	indexDir := filepath.Dir(p.config.SplitIndex)
+
+	var buf strings.Builder
+	buf.WriteString("# Digest Index\n\n")
+	fmt.Fprintf(&buf, "%d parts, %d files\n", mw.fileIndex, p.stats.IncludedCount)
+
+	for i := 1; i <= mw.fileIndex; i++ {
+		path := mw.getCurrentPathForIndex(i)
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("failed to stat part %s: %w", path, err)
+		}
+
+		link, err := filepath.Rel(indexDir, path)
+		if err != nil {
+			link = path
+		}
+		link = (&url.URL{Path: filepath.ToSlash(link)}).EscapedPath()
+
+		var files []string
+		if i <= len(mw.partFiles) {
+			files = mw.partFiles[i-1]
+		}
+
+		fmt.Fprintf(&buf, "\n## [Part %d](%s)\n\n", i, link)
+		fmt.Fprintf(&buf, "%s, %d files\n", utils.FormatSize(info.Size()), len(files))
+		if len(files) > 0 {
+			buf.WriteString("\n")
+		}
+		for _, file := range files {
+			fmt.Fprintf(&buf, "- `%s`\n", file)
+		}
+	}
+
+	if err := os.MkdirAll(indexDir, 0755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+	file, err := createOutputFile(p.config.SplitIndex, p.config.OutputMode)
+	if err != nil {
+		return fmt.Errorf("failed to create split index: %w", err)
+	}
+	if _, err := file.WriteString(buf.String()); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write split index: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write split index: %w", err)
+	}
+
+	p.logger.Log("Created split index: %s", utils.IconFile, p.config.SplitIndex)
+	return nil
+}
@@ -0,0 +1,248 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/richardamare/ai-digest/internal/utils"
+)
+
+// noLanguage labels text files without an extension
+const noLanguage = "(none)"
+
+// Output names for blocks that have no extension-based language
+const (
+	otherLanguage  = "other"  // Text files without an extension
+	binaryLanguage = "binary" // Binary and SVG placeholders
+)
+
+// LanguageStat summarizes the collected files of one language
+type LanguageStat struct {
+	Language string
+	Files    int
+	Size     int64
+}
+
+// classifyLanguage returns the fence language of a text file, or the file
+// type of a binary one, matching how the file would appear in a digest
+func (p *Processor) classifyLanguage(fullPath string) (string, error) {
+	_, isText, err := p.fileTypes.DetectFileType(fullPath)
+	if err != nil {
+		return "", err
+	}
+
+	if !isText || p.fileTypes.ShouldTreatAsBinary(fullPath) {
+		return utils.GetFileType(fullPath), nil
+	}
+
+	if ext := strings.TrimPrefix(filepath.Ext(fullPath), "."); ext != "" {
+		return strings.ToLower(ext), nil
+	}
+	return noLanguage, nil
+}
+
+// Languages groups the collected files by language, largest total size first
+func (p *Processor) Languages(ctx context.Context) ([]LanguageStat, error) {
+	files, err := p.collectFiles(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, stopError(ctx)
+		}
+		return nil, fmt.Errorf("failed to collect files: %w", err)
+	}
+
+	byLanguage := make(map[string]*LanguageStat)
+	for _, relPath := range files {
+		fullPath := filepath.Join(p.config.InputDir, relPath)
+
+		info, err := os.Stat(fullPath)
+		if err != nil {
+			return nil, err
+		}
+
+		language, err := p.classifyLanguage(fullPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to classify %s: %w", relPath, err)
+		}
+
+		stat, ok := byLanguage[language]
+		if !ok {
+			stat = &LanguageStat{Language: language}
+			byLanguage[language] = stat
+		}
+		stat.Files++
+		stat.Size += info.Size()
+	}
+
+	stats := make([]LanguageStat, 0, len(byLanguage))
+	for _, stat := range byLanguage {
+		stats = append(stats, *stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Size != stats[j].Size {
+			return stats[i].Size > stats[j].Size
+		}
+		return stats[i].Language < stats[j].Language
+	})
+
+	return stats, nil
+}
+
+// listLanguages prints the language breakdown without writing any output
+func (p *Processor) listLanguages(ctx context.Context) error {
+	stats, err := p.Languages(ctx)
+	if err != nil {
+		return err
+	}
+
+	var totalFiles int
+	var totalSize int64
+	for _, stat := range stats {
+		totalFiles += stat.Files
+		totalSize += stat.Size
+	}
+
+	fmt.Printf("\n%s Languages\n", utils.IconLanguages)
+	fmt.Println(utils.Rule(15))
+	fmt.Printf("   %-20s %7s %12s %7s\n", "Language", "Files", "Size", "Share")
+	for _, stat := range stats {
+		share := 0.0
+		if totalSize > 0 {
+			share = float64(stat.Size) / float64(totalSize) * 100
+		}
+		fmt.Printf("   %-20s %7d %12s %6.1f%%\n", stat.Language, stat.Files, utils.FormatSize(stat.Size), share)
+	}
+	fmt.Printf("   %-20s %7d %12s\n", "Total", totalFiles, utils.FormatSize(totalSize))
+
+	return nil
+}
+
+// outputLanguage returns the language whose output a result is written to
+// with OutputPerLanguage
+func outputLanguage(result FileResult) string {
+	if result.FileType != "text" {
+		return binaryLanguage
+	}
+	if ext := strings.TrimPrefix(filepath.Ext(result.RelativePath), "."); ext != "" {
+		return strings.ToLower(ext)
+	}
+	return otherLanguage
+}
+
+// languageOutputPath inserts language before the output file's extension,
+// so digest.md becomes digest.go.md
+func languageOutputPath(outputFile, language string) string {
+	ext := filepath.Ext(outputFile)
+	return strings.TrimSuffix(outputFile, ext) + "." + language + ext
+}
+
+// existingLanguageOutputs returns per-language outputs left by a previous run
+func existingLanguageOutputs(outputFile string) ([]string, error) {
+	dir := filepath.Dir(outputFile)
+	base := filepath.Base(outputFile)
+	ext := filepath.Ext(base)
+	prefix := strings.TrimSuffix(base, ext) + "."
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && name != base && strings.HasPrefix(name, prefix) && strings.HasSuffix(name, ext) {
+			paths = append(paths, filepath.Join(dir, name))
+		}
+	}
+	return paths, nil
+}
+
+// languageWriter routes each file block to an output file for its language.
+// Blocks written without a language, such as summaries, go to the base output file.
+type languageWriter struct {
+	config  ProcessorConfig
+	stats   *ProcessorStats
+	logger  *utils.Logger
+	writers map[string]*singleFileWriter
+	current string // Language of the blocks being written
+}
+
+func newLanguageWriter(cfg ProcessorConfig, stats *ProcessorStats, logger *utils.Logger) *languageWriter {
+	stats.LanguageOutputs = make(map[string]int64)
+	return &languageWriter{
+		config:  cfg,
+		stats:   stats,
+		logger:  logger,
+		writers: make(map[string]*singleFileWriter),
+	}
+}
+
+// setLanguage selects the output that following writes go to
+func (w *languageWriter) setLanguage(language string) {
+	w.current = language
+}
+
+func (w *languageWriter) Write(content string) error {
+	writer, ok := w.writers[w.current]
+	if !ok {
+		cfg := w.config
+		if w.current != "" {
+			cfg.OutputFile = languageOutputPath(w.config.OutputFile, w.current)
+		}
+
+		var err error
+		if writer, err = newSingleFileWriter(cfg); err != nil {
+			return err
+		}
+		w.writers[w.current] = writer
+		w.stats.NumberOfFiles++
+		w.logger.Log("Created new file: %s", utils.IconFile, cfg.OutputFile)
+	}
+
+	if err := writer.Write(content); err != nil {
+		return err
+	}
+
+	if w.current != "" {
+		w.stats.mu.Lock()
+		w.stats.LanguageOutputs[w.current] += int64(utils.EncodedLen(content, w.config.OutputEncoding))
+		w.stats.mu.Unlock()
+	}
+	return nil
+}
+
+func (w *languageWriter) Close() error {
+	var firstErr error
+	for _, writer := range w.writers {
+		if err := writer.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// languageOutputsSection lists the per-language output files and their sizes
+func (p *Processor) languageOutputsSection() statsSection {
+	section := statsSection{Icon: utils.IconLanguages, Title: "Language Outputs"}
+
+	languages := make([]string, 0, len(p.stats.LanguageOutputs))
+	for language := range p.stats.LanguageOutputs {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+
+	for _, language := range languages {
+		section.Lines = append(section.Lines, note(utils.Bullet, fmt.Sprintf("%-12s %-30s %10s", language,
+			filepath.Base(languageOutputPath(p.config.OutputFile, language)),
+			utils.FormatSize(p.stats.LanguageOutputs[language]))))
+	}
+	return section
+}
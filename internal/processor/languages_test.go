@@ -0,0 +1,94 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLanguages(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{
+		"a.go":            "package a\n",
+		"b/b.GO":          "package b\n" + strings.Repeat("\n", 10),
+		"x.py":            strings.Repeat("#\n", 25),
+		"README":          "read\n",
+		"logo.png":        "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR",
+		"drop.tmp":        "ignored\n",
+		".aidigestignore": "*.tmp\n.aidigestignore\n",
+	})
+	cfg.ListLanguages = true
+
+	p, err := NewProcessor(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err := p.Languages(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := fmt.Sprintf("%+v", stats)
+	want := "[{Language:py Files:1 Size:50} {Language:go Files:2 Size:30} {Language:Image Files:1 Size:16} {Language:(none) Files:1 Size:5}]"
+	if got != want {
+		t.Errorf("Languages() = %s, want %s", got, want)
+	}
+
+	if err := p.Process(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(cfg.OutputFile); !os.IsNotExist(err) {
+		t.Errorf("listing languages wrote %s: %v", cfg.OutputFile, err)
+	}
+}
+
+func TestOutputPerLanguage(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{
+		"a.go":     "package a\n",
+		"b/b.GO":   "package b\n",
+		"x.py":     "print(1)\n",
+		"README":   "read\n",
+		"logo.png": "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR",
+	})
+	cfg.OutputPerLanguage = true
+	p, err := processDigest(t, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.stats.NumberOfFiles != 4 {
+		t.Errorf("NumberOfFiles = %d, want 4", p.stats.NumberOfFiles)
+	}
+
+	want := map[string][]string{
+		"go":     {"a.go", "b/b.GO"},
+		"py":     {"x.py"},
+		"binary": {"logo.png"},
+	}
+	for language, files := range want {
+		path := languageOutputPath(cfg.OutputFile, language)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Errorf("missing output for %s: %v", language, err)
+			continue
+		}
+		if got := fileHeaders(string(data)); fmt.Sprint(got) != fmt.Sprint(files) {
+			t.Errorf("%s headers = %q, want %q", language, got, files)
+		}
+	}
+
+	// Files without an extension have no dotted header for fileHeaders to find
+	other, err := os.ReadFile(languageOutputPath(cfg.OutputFile, "other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(other), "# README\n") {
+		t.Errorf("other output = %q, want the README block", other)
+	}
+}
+
+func TestLanguageOutputPath(t *testing.T) {
+	if got, want := languageOutputPath("out/digest.md", "go"), "out/digest.go.md"; got != want {
+		t.Errorf("languageOutputPath() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,166 @@
+package processor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/richardamare/ai-digest/internal/utils"
+)
+
+// ErrLockMismatch is returned when the included files differ from a lockfile
+var ErrLockMismatch = errors.New("inputs do not match lockfile")
+
+// Lockfile records the size and content hash of each included file
+type Lockfile struct {
+	Algo  string               `json:"algo"`
+	Files map[string]LockEntry `json:"files"`
+}
+
+// LockEntry is the recorded state of one included file
+type LockEntry struct {
+	Size int64  `json:"size"`
+	Hash string `json:"hash"`
+}
+
+// LockDiff lists the files that differ between a lockfile and the current run
+type LockDiff struct {
+	Changed []string
+	Added   []string
+	Removed []string
+}
+
+// Empty reports whether the lockfile matched
+func (d LockDiff) Empty() bool {
+	return len(d.Changed) == 0 && len(d.Added) == 0 && len(d.Removed) == 0
+}
+
+// LoadLockfile reads a lockfile
+func LoadLockfile(path string) (*Lockfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lockfile: %w", err)
+	}
+
+	var lock Lockfile
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("failed to parse lockfile: %w", err)
+	}
+	if lock.Files == nil {
+		lock.Files = make(map[string]LockEntry)
+	}
+
+	return &lock, nil
+}
+
+// Save writes the lockfile
+func (l *Lockfile) Save(path string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal lockfile: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write lockfile: %w", err)
+	}
+
+	return nil
+}
+
+// Diff compares the lockfile against the current one
+func (l *Lockfile) Diff(current *Lockfile) LockDiff {
+	var diff LockDiff
+	for path, entry := range current.Files {
+		prev, ok := l.Files[path]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, path)
+		case prev != entry:
+			diff.Changed = append(diff.Changed, path)
+		}
+	}
+	for path := range l.Files {
+		if _, ok := current.Files[path]; !ok {
+			diff.Removed = append(diff.Removed, path)
+		}
+	}
+
+	sort.Strings(diff.Changed)
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	return diff
+}
+
+// buildLock hashes the included files with the named algorithm
+func (p *Processor) buildLock(algo string, files []string) (*Lockfile, error) {
+	hasher, err := utils.NewHasher(algo)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := &Lockfile{Algo: algo, Files: make(map[string]LockEntry, len(files))}
+	for _, relPath := range files {
+		fullPath := filepath.Join(p.config.InputDir, relPath)
+
+		info, err := os.Stat(fullPath)
+		if err != nil {
+			return nil, err
+		}
+		hash, err := hasher.HashFile(fullPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash %s: %w", relPath, err)
+		}
+
+		lock.Files[filepath.ToSlash(relPath)] = LockEntry{Size: info.Size(), Hash: hash}
+	}
+
+	return lock, nil
+}
+
+// writeLock records the included files in the lockfile
+func (p *Processor) writeLock() error {
+	lock, err := p.buildLock(p.config.HashAlgo, p.stats.IncludedFiles)
+	if err != nil {
+		return err
+	}
+	return lock.Save(p.config.LockFile)
+}
+
+// verifyLock compares the included files with the lockfile, printing any
+// differences, and returns ErrLockMismatch if there are some
+func (p *Processor) verifyLock() error {
+	prev, err := LoadLockfile(p.config.LockFile)
+	if err != nil {
+		return err
+	}
+
+	current, err := p.buildLock(prev.Algo, p.stats.IncludedFiles)
+	if err != nil {
+		return err
+	}
+
+	diff := prev.Diff(current)
+	if diff.Empty() {
+		fmt.Printf("\n%s %d files match %s\n", utils.IconSuccess, len(current.Files), p.config.LockFile)
+		return nil
+	}
+
+	fmt.Printf("\n%s Inputs differ from %s\n", utils.IconError, p.config.LockFile)
+	for _, group := range []struct {
+		label string
+		paths []string
+	}{
+		{"Changed", diff.Changed},
+		{"Added", diff.Added},
+		{"Removed", diff.Removed},
+	} {
+		for _, path := range group.paths {
+			fmt.Printf("   %s %-8s %s\n", utils.Bullet, group.label+":", path)
+		}
+	}
+
+	return ErrLockMismatch
+}
@@ -0,0 +1,62 @@
+package processor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/richardamare/ai-digest/internal/utils"
+)
+
+func TestLockfileDiff(t *testing.T) {
+	prev := &Lockfile{Files: map[string]LockEntry{
+		"same.txt":    {Size: 1, Hash: "a"},
+		"changed.txt": {Size: 1, Hash: "b"},
+		"removed.txt": {Size: 1, Hash: "c"},
+	}}
+	current := &Lockfile{Files: map[string]LockEntry{
+		"same.txt":    {Size: 1, Hash: "a"},
+		"changed.txt": {Size: 1, Hash: "x"},
+		"added.txt":   {Size: 1, Hash: "d"},
+	}}
+
+	diff := prev.Diff(current)
+	if !slices.Equal(diff.Changed, []string{"changed.txt"}) ||
+		!slices.Equal(diff.Added, []string{"added.txt"}) ||
+		!slices.Equal(diff.Removed, []string{"removed.txt"}) {
+		t.Errorf("Diff() = %+v", diff)
+	}
+	if diff.Empty() || !prev.Diff(prev).Empty() {
+		t.Error("Empty() should report only matching lockfiles")
+	}
+}
+
+func TestVerifyLock(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{"a.txt": "a\n", "b.txt": "b\n"})
+	cfg.HashAlgo = utils.DefaultHashAlgo
+	cfg.LockFile = filepath.Join(filepath.Dir(cfg.OutputFile), "digest.lock")
+	runDigest(t, cfg)
+
+	lock, err := LoadLockfile(cfg.LockFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lock.Files) != 2 || lock.Algo != utils.DefaultHashAlgo {
+		t.Fatalf("lockfile = %+v, want both files", lock)
+	}
+
+	verify := cfg
+	verify.VerifyLock = true
+	if _, err := processDigest(t, verify); err != nil {
+		t.Errorf("unchanged inputs: Process() error = %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(cfg.InputDir, "b.txt"), []byte("B\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := processDigest(t, verify); !errors.Is(err, ErrLockMismatch) {
+		t.Errorf("changed input: Process() error = %v, want ErrLockMismatch", err)
+	}
+}
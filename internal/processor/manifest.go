@@ -0,0 +1,81 @@
+package processor
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/richardamare/ai-digest/internal/utils"
+)
+
+// Binary manifest destinations
+const (
+	BinaryManifestNone    = "none"
+	BinaryManifestBlock   = "block"
+	BinaryManifestSidecar = "sidecar"
+)
+
+// BinaryManifestEntry describes one binary file listed in the manifest
+type BinaryManifestEntry struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+	Size int64  `json:"size"`
+	MIME string `json:"mime,omitempty"`
+}
+
+// buildBinaryManifest converts binary results to manifest entries
+func buildBinaryManifest(binaries []FileResult) []BinaryManifestEntry {
+	entries := make([]BinaryManifestEntry, 0, len(binaries))
+	for _, b := range binaries {
+		entries = append(entries, BinaryManifestEntry{
+			Path: filepath.ToSlash(b.RelativePath),
+			Type: b.FileType,
+			Size: b.Size,
+			MIME: b.MIMEType,
+		})
+	}
+	return entries
+}
+
+// binaryManifestPath returns the sidecar path next to the output file, so
+// digest.md gets digest.binaries.json
+func binaryManifestPath(outputFile string) string {
+	return strings.TrimSuffix(outputFile, filepath.Ext(outputFile)) + ".binaries.json"
+}
+
+// writeBinaryManifest emits the manifest as a JSON block in the digest or as
+// a sidecar file. An empty manifest is skipped unless EmitEmptyManifest is set.
+func (p *Processor) writeBinaryManifest(binaries []FileResult) error {
+	if len(binaries) == 0 && !p.config.EmitEmptyManifest {
+		return nil
+	}
+
+	data, err := p.marshalOutput(buildBinaryManifest(binaries))
+	if err != nil {
+		return fmt.Errorf("failed to marshal binary manifest: %w", err)
+	}
+
+	switch p.config.BinaryManifest {
+	case BinaryManifestBlock:
+		block := fmt.Sprintf("# Binary manifest\n\n```json\n%s\n```\n%s", data, p.blockSeparator())
+		if err := p.write(block); err != nil {
+			return fmt.Errorf("failed to write binary manifest: %w", err)
+		}
+	case BinaryManifestSidecar:
+		path := binaryManifestPath(p.config.OutputFile)
+		file, err := createOutputFile(path, p.config.OutputMode)
+		if err != nil {
+			return fmt.Errorf("failed to create binary manifest: %w", err)
+		}
+		if _, err := file.Write(append(data, '\n')); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to write binary manifest: %w", err)
+		}
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("failed to write binary manifest: %w", err)
+		}
+		p.logger.Log("Created binary manifest: %s", utils.IconFile, path)
+	}
+
+	return nil
+}
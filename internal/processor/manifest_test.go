@@ -0,0 +1,75 @@
+package processor
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+const pngHeader = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+
+func TestBinaryManifestSidecar(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{
+		"a.txt":        "a\n",
+		"img/logo.png": pngHeader,
+	})
+	cfg.BinaryManifest = BinaryManifestSidecar
+	digest := runDigest(t, cfg)
+
+	data, err := os.ReadFile(binaryManifestPath(cfg.OutputFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []BinaryManifestEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatalf("manifest is not valid JSON: %v\n%s", err, data)
+	}
+	if len(entries) != 1 || entries[0].Path != "img/logo.png" || entries[0].Size != int64(len(pngHeader)) || entries[0].MIME != "image/png" {
+		t.Errorf("entries = %+v, want img/logo.png", entries)
+	}
+	if strings.Contains(digest, "# Binary manifest") {
+		t.Error("sidecar manifest should not be written into the digest")
+	}
+}
+
+func TestBinaryManifestBlock(t *testing.T) {
+	tests := []struct {
+		name      string
+		emitEmpty bool
+		want      bool
+	}{
+		{"empty skipped", false, false},
+		{"empty emitted", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := digestFixture(t, map[string]string{"a.txt": "a\n"})
+			cfg.BinaryManifest = BinaryManifestBlock
+			cfg.EmitEmptyManifest = tt.emitEmpty
+			digest := runDigest(t, cfg)
+
+			got := strings.Contains(digest, "# Binary manifest\n\n```json\n[]\n```\n")
+			if got != tt.want {
+				t.Errorf("manifest block present = %v, want %v:\n%s", got, tt.want, digest)
+			}
+		})
+	}
+}
+
+func TestBinaryManifestCompact(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{"logo.png": pngHeader})
+	cfg.BinaryManifest = BinaryManifestSidecar
+	cfg.JSONCompact = true
+	runDigest(t, cfg)
+
+	data, err := os.ReadFile(binaryManifestPath(cfg.OutputFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"path":"logo.png","type":"Image","size":16,"mime":"image/png"}]` + "\n"
+	if string(data) != want {
+		t.Errorf("compact manifest = %q, want %q", data, want)
+	}
+}
@@ -0,0 +1,89 @@
+package processor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/richardamare/ai-digest/internal/utils"
+)
+
+// partHeader returns the line opening part, stating its position among total
+// parts and the range of files it holds. first is the 1-based number of the
+// part's first file across all parts. Files repeated from earlier parts by
+// SplitOverlap are listed separately, since they precede the range.
+func partHeader(part, total, first int, files, repeated []string) string {
+	header := fmt.Sprintf("Part %d of %d", part, total)
+	switch len(files) {
+	case 0:
+	case 1:
+		header += fmt.Sprintf(", file %d (%s)", first, files[0])
+	default:
+		header += fmt.Sprintf(", files %d–%d (%s to %s)", first, first+len(files)-1, files[0], files[len(files)-1])
+	}
+
+	switch len(repeated) {
+	case 0:
+	case 1:
+		header += fmt.Sprintf(", after 1 repeated file (%s)", repeated[0])
+	default:
+		header += fmt.Sprintf(", after %d repeated files (%s to %s)", len(repeated), repeated[0], repeated[len(repeated)-1])
+	}
+	return header + "\n\n"
+}
+
+// writePartHeaders prepends a header to every part once the number of parts
+// is known. Headers are not counted towards the part size limit.
+func (w *multiFileWriter) writePartHeaders() error {
+	first := 1
+	for i := 1; i <= w.fileIndex; i++ {
+		var files, repeated []string
+		if i <= len(w.partFiles) {
+			files = w.partFiles[i-1]
+		}
+		if i <= len(w.overlap) {
+			repeated = w.overlap[i-1]
+		}
+
+		path := w.getCurrentPathForIndex(i)
+		if err := w.prependToPart(path, partHeader(i, w.fileIndex, first, files, repeated)); err != nil {
+			return fmt.Errorf("failed to write part header to %s: %w", path, err)
+		}
+		first += len(files)
+	}
+	return nil
+}
+
+// prependToPart rewrites the part at path with header inserted after its BOM
+func (w *multiFileWriter) prependToPart(path, header string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	bom := utils.OutputBOM(w.config.OutputEncoding)
+	content = bytes.TrimPrefix(content, bom)
+
+	tmpPath := path + resumeSuffix
+	file, err := createOutputFile(tmpPath, w.config.OutputMode)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.Write(bom)
+	if _, err := io.WriteString(utils.NewEncodingWriter(&buf, w.config.OutputEncoding), header); err != nil {
+		file.Close()
+		return err
+	}
+	buf.Write(content)
+
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
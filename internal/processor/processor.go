@@ -3,36 +3,174 @@ package processor
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"math"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 	"unicode/utf8"
 
 	"github.com/richardamare/ai-digest/internal/utils"
 )
 
 const (
-	maxConcurrency = 10
-	maxFileSize    = 10 * 1024 * 1024 // 10MB
+	maxFileSize   = 10 * 1024 * 1024 // 10MB
+	maxGitAuthors = 3
+
+	defaultOutputMode os.FileMode = 0644
+
+	contentFilterScanSize = 64 * 1024 // 64KB scanned by content filters
+
+	pruneNoiseMaxInputSize = 1 * 1024 * 1024 // 1MB input file limit for --prune-noise
+)
+
+// ErrTimeout is returned when a run is stopped by its context deadline. Output
+// written before the deadline is flushed and left in place.
+var ErrTimeout = errors.New("run truncated by timeout")
+
+// ErrTokenLimit is returned when writing a block would exceed HardLimitTokens.
+// Blocks written before the limit are flushed and left in place.
+var ErrTokenLimit = errors.New("output would exceed the hard token limit")
+
+// Markdown wrapping modes
+const (
+	MarkdownModeRaw    = "raw"
+	MarkdownModeFenced = "fenced"
+	MarkdownModeEscape = "escape"
 )
 
+// Embedded BOM handling modes
+const (
+	EmbeddedBOMWarn  = "warn"
+	EmbeddedBOMStrip = "strip"
+	EmbeddedBOMFail  = "fail"
+)
+
+// NoNewlinesBetweenFiles sets NewlinesBetweenFiles to emit no blank lines
+// between blocks, since its zero value means the default spacing
+const NoNewlinesBetweenFiles = -1
+
 var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
 
 // ProcessorConfig holds all configuration options
 type ProcessorConfig struct {
-	InputDir          string
-	OutputFile        string
-	UseDefaultIgnores bool
-	RemoveWhitespace  bool
-	ShowOutputFiles   bool
-	IgnoreFile        string
-	Split             bool
-	MaxFileSizeMB     int    // Used when Split is true
-	OutputFilePattern string // Used when Split is true
-	ChunkSize         int    // Buffer size for writing
+	InputDir             string
+	OutputFile           string
+	UseDefaultIgnores    bool
+	RemoveWhitespace     bool
+	ShowOutputFiles      bool
+	ShowIgnored          bool
+	IgnoreFile           string
+	Split                bool
+	MaxFileSizeMB        int                      // Used when Split is true
+	OutputFilePattern    string                   // Used when Split is true
+	ChunkSize            int                      // Buffer size for writing
+	ParallelWrite        bool                     // Write split parts from a background goroutine
+	SplitOverlap         int                      // Files repeated at the top of the next split part
+	SplitResume          bool                     // Keep existing split parts whose content is unchanged
+	AlsoCombined         string                   // With Split, also write the whole digest to this file
+	SplitIndex           string                   // With Split, write a markdown index of the parts to this file
+	PartHeaders          bool                     // With Split, open each part with "Part N of M" and its file range
+	MaxParts             int                      // Maximum number of split parts, 0 for no limit
+	CleanStale           bool                     // Remove split parts left over from a previous run with more parts
+	LockFile             string                   // Lockfile recording included file sizes and hashes
+	VerifyLock           bool                     // Check inputs against LockFile instead of writing output
+	LanguageOverrides    []utils.LanguageOverride // Fence languages for ambiguous extensions or globs, first match wins
+	HardLimitTokens      int                      // Abort once the output would exceed this many estimated tokens, 0 for no limit
+	UseFileList          bool                     // Process FileList instead of walking InputDir
+	SummarizeOver        int                      // Replace text files above this many estimated tokens with a summary, 0 to disable
+	RespectEditorConfig  bool                     // Apply .editorconfig whitespace rules per file
+	FileList             []string                 // Paths to process, relative to InputDir or absolute
+	Dedent               bool                     // Strip common leading indentation
+	DetectLanguage       bool                     // Decide whitespace sensitivity from file names, shebangs and content too
+	CollapseImports      bool                     // Replace leading import sections with a marker
+	EmbeddedBOMMode      string                   // How to handle non-leading BOMs: warn, strip or fail
+	DuplicateHeaders     string                   // How to handle blocks with identical headers: disambiguate or fail
+	GitAuthors           bool                     // Add a primary authors line to each file header
+	GitHeader            bool                     // Start the output with the git branch, commit and remote
+	Instructions         []string                 // Paragraphs written as a blockquoted preamble before everything else
+	ResolveIncludes      bool                     // Inline files referenced by "<!-- include: path -->" lines
+	WrapMarkdown         int                      // Reflow prose in markdown files to this width, 0 to leave it as is
+	MarkdownPlainText    bool                     // Strip headings, links and emphasis syntax from markdown files
+	NoteSymlinks         bool                     // Write a note with the target of each symlink instead of following it
+	MaxTokensPerFile     int                      // Truncate file content to this many tokens, 0 for no limit
+	ExcludeContent       string                   // Skip files whose content matches this regex
+	IncludeContent       string                   // Only keep files whose content matches this regex
+	ReportDuplicates     bool                     // Report groups of files with identical content
+	ReportClones         bool                     // Report blocks of code duplicated in included text files
+	Strict               bool                     // Abort on the first file that fails to process instead of skipping it
+	HashAlgo             string                   // Hash algorithm for content hashing, see utils.HasherNames
+	OutputMode           os.FileMode              // Permissions for created output files
+	Prioritize           []string                 // Globs whose matches are written first, in order
+	OrderFile            string                   // File of relative paths written first, in the listed order
+	ExtraIgnores         []string                 // Patterns applied after the defaults, before the ignore file
+	SkipMinified         bool                     // Skip files that look minified
+	SkipGenerated        bool                     // Skip files with generated-code markers
+	SkipGeneratedHeader  bool                     // Skip files whose first lines mark them as generated
+	ExcludeTests         bool                     // Skip test files and directories matched by TestFilePatterns
+	OnlyTests            bool                     // Keep only test files, the inverse of ExcludeTests
+	WithTestedSource     bool                     // With OnlyTests, also keep the source files next to each test
+	TestPatterns         []string                 // Extra patterns identifying test files, with ExcludeTests or OnlyTests
+	MaxAge               time.Duration            // Skip files last modified longer ago than this, 0 for no limit
+	GroupByAge           bool                     // Group output into sections by last-modified age
+	OutputPerLanguage    bool                     // Write one output file per language instead of a single file
+	TrimCommonPrefix     bool                     // Strip the directory prefix shared by all files from headers
+	SignaturesOnly       bool                     // Emit a directory tree and only top-level declarations of supported languages
+	PromoteFrontMatter   bool                     // Show the front matter title in file headers
+	StripFrontMatter     bool                     // With PromoteFrontMatter, drop the front matter block from the body
+	CompareBaseline      bool                     // Report token savings of content transforms against untransformed content
+	MaxInputFileSize     int64                    // Skip input files larger than this many bytes, 0 for no limit
+	MaxBinarySize        int64                    // Embed binary files up to this many bytes as base64, 0 to never embed
+	PruneNoise           bool                     // Set when the prune-noise preset was applied
+	OutputEncoding       string                   // Encoding of written output, defaults to UTF-8
+	ParentIgnores        bool                     // Apply ignore files from directories above InputDir
+	NewlinesBetweenFiles int                      // Blank lines emitted after each file block; 0 uses the default of 1, NoNewlinesBetweenFiles emits none
+	BlockSeparator       string                   // Custom separator line between blocks, overrides NewlinesBetweenFiles
+	HeaderMetadata       bool                     // Add size and estimated tokens to file headers
+	Theme                string                   // How the stats report is rendered: fancy, plain or minimal
+	HashHeaders          bool                     // Add a short hash of the raw file content to file headers
+	IncludeFile          string                   // File of patterns files must match to be included, "-" for stdin
+	StripTrailingWS      bool                     // Trim end-of-line whitespace and trailing blank lines
+	MarkdownMode         string                   // How markdown files are wrapped: raw, fenced or escape
+	TypeLimits           map[string]TypeLimit     // Per-extension size and depth limits
+	TreatAsText          []string                 // Extensions forced to be read as text
+	TreatAsBinary        []string                 // Extensions forced to be listed as binary
+	BinarySampleBytes    int                      // Leading bytes read to tell text from binary files, 0 for the default
+	CompactBinaries      bool                     // List binary files in one table instead of separate blocks
+	ResultsFile          string                   // Also write every file result to this JSON document
+	ResultsContent       bool                     // Include file content in the results document
+	JSONCompact          bool                     // Write the results document and binary manifest without indentation
+	BinaryManifest       string                   // Where to emit the JSON binary manifest: none, block or sidecar
+	EmitEmptyManifest    bool                     // Emit the binary manifest even when there are no binaries
+	QuickEstimate        bool                     // Only print a sampled token estimate, writing no output
+	ListLanguages        bool                     // Only print a breakdown of files by language, writing no output
+	Diagnose             bool                     // Only explain which files are excluded, writing no output
+	JSONStream           bool                     // Write file results as JSON lines in completion order instead of the digest
+	OnlyTracked          bool                     // Restrict collection to files tracked by git
+	MaxReadBytesPerSec   int64                    // Limit aggregate file read throughput, 0 for unlimited
+	Concurrency          int                      // Files processed at once, 0 to pick from the input filesystem
+	CommitRange          string                   // Restrict collection to files changed in this git range, e.g. main..feature
+	FlushInterval        int64                    // Flush split parts every this many bytes, 0 to flush only near the size limit
+	OutputEOL            string                   // Line ending for output, lf or crlf; empty keeps source endings
+	CollectTodos         bool                     // Emit a summary of TODO/FIXME/HACK markers first
+	PreserveBlankLines   int                      // With RemoveWhitespace, cap blank-line runs at N instead of collapsing; 0 collapses
+	HeaderFormat         string                   // File header template with {path}, {lang}, {size} and {index} fields
+	PathDepth            int                      // Show only this many trailing path components in headers, 0 for the full path
+	SplitPerFile         bool                     // With Split, write each block to its own output file
+	TransformOrder       []string                 // Content transform names in application order; unlisted ones follow in default order
+	IgnoreCeiling        string                   // Highest directory searched for parent ignore files
+	SinceLastRun         bool                     // Only include files changed since the last run
+	StateFile            string                   // Used when SinceLastRun is true
 }
 
 // ProcessorStats tracks all processing statistics
@@ -41,15 +179,34 @@ type ProcessorStats struct {
 	TotalFiles       int
 	IncludedCount    int
 	IgnoredCount     int
+	SkippedDirCount  int // Ignored directories that were not descended into
+	UnchangedCount   int
+	EmbeddedBOMCount int
+	TruncatedCount   int
+	SummarizedCount  int
+	SymlinkCount     int
+	FilteredCount    int
+	PrunedCount      int
+	GeneratedCount   int
+	BaselineTokens   int // Estimated tokens of text content before transforms
+	OutputTokens     int // Estimated tokens of text content after transforms
+	TypeLimitedCount int
+	AgeFilteredCount int
+	TestFileCount    int
+	FilesByHash      map[string][]string  // Included files grouped by content hash
+	Clones           []utils.CloneCluster // Duplicated code blocks, if ReportClones is set
 	BinaryCount      int
 	TotalSize        int64
 	IncludedFiles    []string
-	NumberOfFiles    int    // Number of output files created
-	AverageFileSize  int64  // Average size per output file
-	SmallestFile     string // Name of smallest output file
-	SmallestFileSize int64  // Size of smallest output file
-	LargestFile      string // Name of largest output file
-	LargestFileSize  int64  // Size of largest output file
+	IgnoredFiles     []Exclusion      // Ignored files and skipped directories, if ShowIgnored is set
+	NumberOfFiles    int              // Number of output files created
+	AverageFileSize  int64            // Average size per output file
+	SmallestFile     string           // Name of smallest output file
+	SmallestFileSize int64            // Size of smallest output file
+	LargestFile      string           // Name of largest output file
+	LargestFileSize  int64            // Size of largest output file
+	LanguageOutputs  map[string]int64 // Bytes written per language, if OutputPerLanguage is set
+	CombinedSize     int64            // Size of the combined file, if AlsoCombined is set
 }
 
 // fileWriter is an interface for writing content
@@ -58,6 +215,12 @@ type fileWriter interface {
 	Close() error
 }
 
+// discardWriter drops all content, used when no output is produced
+type discardWriter struct{}
+
+func (discardWriter) Write(content string) error { return nil }
+func (discardWriter) Close() error               { return nil }
+
 // singleFileWriter writes to a single output file
 type singleFileWriter struct {
 	file   *os.File
@@ -75,6 +238,28 @@ type multiFileWriter struct {
 	outputSize  int64
 	logger      *utils.Logger
 	mu          sync.Mutex
+	recent      []string     // Last SplitOverlap contents written, oldest first
+	sinceFlush  int64        // Bytes written since the last periodic flush
+	hasher      utils.Hasher // Compares new parts with existing ones, if SplitResume is set
+	partSize    int64        // Part size chosen to fit MaxParts, overriding MaxFileSizeMB if set
+	nextFile    string       // File whose content is written next, if SplitIndex, PartHeaders or SplitPerFile is set
+	partFiles   [][]string   // Files written to each part, if SplitIndex, PartHeaders or SplitPerFile is set
+	recentFiles []string     // File of each entry in recent, "" for blocks that aren't files
+	overlap     [][]string   // Files repeated at the top of each part, if SplitOverlap is set
+	pending     []string     // Blocks held for the next file's part, if SplitPerFile is set
+
+	// Parallel write state, only used when ParallelWrite is set
+	partIndex int
+	jobs      chan writeJob
+	free      chan *bytes.Buffer
+	done      chan struct{}
+	writeErr  error
+}
+
+// writeJob is a unit of work for the background writer
+type writeJob struct {
+	buffer *bytes.Buffer
+	rotate bool
 }
 
 // Processor handles file processing and output writing
@@ -84,10 +269,115 @@ type Processor struct {
 	writer  fileWriter
 	logger  *utils.Logger
 	matcher *utils.IgnoreMatcher
+	include *utils.IgnoreMatcher // Only files matching these patterns are kept, if set
+	tests   *utils.IgnoreMatcher // Test files to skip, or to keep with OnlyTests
+	tracked map[string]bool      // Files tracked by git, if OnlyTracked is set
+	changed map[string]bool      // Files changed in CommitRange, if set
+	deleted []string             // Files deleted in CommitRange, listed but not emitted
+
+	prevState *RunState // State from the previous run, if SinceLastRun is set
+	nextState *RunState // State recorded during this run
+
+	excludeContent *regexp.Regexp
+	includeContent *regexp.Regexp
+
+	transforms []ContentTransformer // Enabled content transforms in application order
+	hasher     utils.Hasher         // Content hash used for duplicate detection
+
+	now      time.Time            // Reference time for file ages
+	modTimes map[string]time.Time // Modification times by relative path, if GroupByAge is set
+	limiter  *utils.RateLimiter   // Read throttle shared by all workers, if MaxReadBytesPerSec is set
+	workers  int                  // Files processed at once
+
+	trimPrefix string                  // Directory prefix stripped from headers, if TrimCommonPrefix is set
+	shortPaths map[string]string       // Header paths by relative path, if PathDepth is set
+	languages  *utils.LanguageResolver // Fence language lookup with overrides
+	fileTypes  *utils.FileTypeDetector // Text/binary classification with TreatAsText/TreatAsBinary
+
+	writtenTokens int                 // Estimated tokens written so far, tracked if HardLimitTokens is set
+	editorConfig  *utils.EditorConfig // .editorconfig resolver, if RespectEditorConfig is set
+	singleFile    string              // Name of the only file to collect when the input is a file
+
+	ignoreSources []utils.ScopedPatterns // Ignore patterns by source in matcher order, for Diagnose
+	order         []string               // Paths from OrderFile, written first in this order
+	combined      fileWriter             // Second sink receiving every write, if AlsoCombined is set
+	includes      *utils.IncludeResolver // Include directive expander, if ResolveIncludes is set
+	results       []FileResult           // Results in output order, if ResultsFile is set
+}
+
+// ExistingOutputs returns the output files this configuration would
+// overwrite: the output file, split parts, or per-language outputs
+func (c ProcessorConfig) ExistingOutputs() ([]string, error) {
+	if c.QuickEstimate || c.ListLanguages || c.VerifyLock || c.Diagnose {
+		return nil, nil
+	}
+
+	var existing []string
+	switch {
+	case c.Split:
+		parts := &multiFileWriter{config: c}
+		for i := 1; ; i++ {
+			path := parts.getCurrentPathForIndex(i)
+			if _, err := os.Stat(path); err != nil {
+				break
+			}
+			existing = append(existing, path)
+		}
+		for _, path := range []string{c.AlsoCombined, c.SplitIndex} {
+			if path == "" {
+				continue
+			}
+			if _, err := os.Stat(path); err == nil {
+				existing = append(existing, path)
+			}
+		}
+		return existing, nil
+	case c.OutputPerLanguage:
+		outputs, err := existingLanguageOutputs(c.OutputFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list existing outputs: %w", err)
+		}
+		existing = outputs
+	}
+
+	if _, err := os.Stat(c.OutputFile); err == nil {
+		existing = append(existing, c.OutputFile)
+	}
+	if c.BinaryManifest == BinaryManifestSidecar {
+		path := binaryManifestPath(c.OutputFile)
+		if _, err := os.Stat(path); err == nil {
+			existing = append(existing, path)
+		}
+	}
+	if c.ResultsFile != "" {
+		if _, err := os.Stat(c.ResultsFile); err == nil {
+			existing = append(existing, c.ResultsFile)
+		}
+	}
+	return existing, nil
+}
+
+// ApplyPruneNoisePreset enables the bundle of settings that drop minified,
+// generated, oversized and lockfile/data noise from the digest
+func (c *ProcessorConfig) ApplyPruneNoisePreset() {
+	c.PruneNoise = true
+	c.SkipMinified = true
+	c.SkipGenerated = true
+	if c.MaxInputFileSize == 0 {
+		c.MaxInputFileSize = pruneNoiseMaxInputSize
+	}
+	c.ExtraIgnores = append(c.ExtraIgnores, utils.NoiseIgnores...)
 }
 
 // NewProcessor creates a new processor instance
 func NewProcessor(cfg ProcessorConfig) (*Processor, error) {
+	// A single input file is digested relative to its parent directory
+	var singleFile string
+	if info, err := os.Stat(cfg.InputDir); err == nil && info.Mode().IsRegular() {
+		singleFile = filepath.Base(cfg.InputDir)
+		cfg.InputDir = filepath.Dir(cfg.InputDir)
+	}
+
 	if cfg.ChunkSize == 0 {
 		cfg.ChunkSize = 1 * 1024 * 1024 // Default 1MB chunk size
 	}
@@ -96,265 +386,1453 @@ func NewProcessor(cfg ProcessorConfig) (*Processor, error) {
 		cfg.MaxFileSizeMB = 10 // Default 10MB max file size
 	}
 
-	stats := &ProcessorStats{}
-	logger := utils.NewLogger(false)
+	if cfg.NewlinesBetweenFiles == 0 {
+		cfg.NewlinesBetweenFiles = 1 // Default one blank line between blocks
+	}
 
-	// Create output directory if needed
-	if err := os.MkdirAll(filepath.Dir(cfg.OutputFile), 0755); err != nil {
-		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	if cfg.OutputMode == 0 {
+		cfg.OutputMode = defaultOutputMode
 	}
 
-	var writer fileWriter
-	var err error
+	if cfg.OutputEncoding == "" {
+		cfg.OutputEncoding = utils.EncodingUTF8
+	}
 
-	if cfg.Split {
-		writer, err = newMultiFileWriter(cfg, stats, logger)
-	} else {
-		writer, err = newSingleFileWriter(cfg)
+	if cfg.HeaderFormat == "" {
+		cfg.HeaderFormat = utils.DefaultHeaderFormat
+	}
+
+	if cfg.HashAlgo == "" {
+		cfg.HashAlgo = utils.DefaultHashAlgo
 	}
 
+	hasher, err := utils.NewHasher(cfg.HashAlgo)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Processor{
-		config:  cfg,
-		stats:   stats,
-		writer:  writer,
-		logger:  logger,
-		matcher: utils.NewIgnoreMatcher(nil, cfg.UseDefaultIgnores),
-	}, nil
-}
-
-// Process handles the entire processing workflow
-func (p *Processor) Process() error {
-	defer p.writer.Close()
-
-	// Collect and process files
-	files, err := p.collectFiles()
+	transforms, err := buildTransforms(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to collect files: %w", err)
+		return nil, err
 	}
 
-	results := p.processFiles(files)
+	stats := &ProcessorStats{}
+	logger := utils.NewLogger(false)
 
-	// Write results
-	for result := range results {
-		if result.Error != nil {
-			p.logger.LogError("Error processing %s: %v", result.RelativePath, result.Error)
-			continue
-		}
+	// Create output directory if needed
+	if err := os.MkdirAll(filepath.Dir(cfg.OutputFile), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
 
-		if err := p.writer.Write(result.Content); err != nil {
-			return fmt.Errorf("failed to write content: %w", err)
+	patterns, err := utils.ReadPatternSource(cfg.InputDir, cfg.IgnoreFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ignore file: %w", err)
+	}
+
+	var include *utils.IgnoreMatcher
+	if cfg.IncludeFile != "" {
+		includePatterns, err := utils.ReadPatternSource(cfg.InputDir, cfg.IncludeFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read include file: %w", err)
 		}
+		include = utils.NewIgnoreMatcher(includePatterns, false)
+	}
 
-		p.updateStats(result)
+	var tests *utils.IgnoreMatcher
+	if cfg.ExcludeTests || cfg.OnlyTests {
+		tests = utils.NewIgnoreMatcher(append(append([]string{}, utils.TestFilePatterns...), cfg.TestPatterns...), false)
 	}
 
-	p.printStats()
-	return nil
-}
+	var order []string
+	if cfg.OrderFile != "" {
+		f, err := os.Open(cfg.OrderFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read order file: %w", err)
+		}
+		order, err = utils.ReadPathList(f, false)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read order file: %w", err)
+		}
+	}
 
-func newSingleFileWriter(cfg ProcessorConfig) (*singleFileWriter, error) {
-	file, err := os.Create(cfg.OutputFile)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create output file: %w", err)
+	var tracked map[string]bool
+	if cfg.OnlyTracked {
+		if tracked, err = utils.GitTrackedFiles(cfg.InputDir); err != nil {
+			return nil, err
+		}
 	}
 
-	return &singleFileWriter{
-		file:   file,
-		writer: bufio.NewWriterSize(file, cfg.ChunkSize),
-	}, nil
-}
+	var changed map[string]bool
+	var deleted []string
+	if cfg.CommitRange != "" {
+		if changed, deleted, err = utils.GitChangedFiles(cfg.InputDir, cfg.CommitRange); err != nil {
+			return nil, err
+		}
+	}
 
-func (w *singleFileWriter) Write(content string) error {
-	_, err := w.writer.WriteString(content)
-	return err
-}
+	var writer fileWriter
 
-func (w *singleFileWriter) Close() error {
-	if err := w.writer.Flush(); err != nil {
-		return err
+	if cfg.QuickEstimate || cfg.ListLanguages || cfg.VerifyLock || cfg.Diagnose {
+		writer = discardWriter{}
+	} else if cfg.Split {
+		writer, err = newMultiFileWriter(cfg, stats, logger)
+	} else if cfg.OutputPerLanguage {
+		writer = newLanguageWriter(cfg, stats, logger)
+	} else {
+		writer, err = newSingleFileWriter(cfg)
 	}
-	return w.file.Close()
-}
 
-func newMultiFileWriter(cfg ProcessorConfig, stats *ProcessorStats, logger *utils.Logger) (*multiFileWriter, error) {
-	w := &multiFileWriter{
-		config: cfg,
-		stats:  stats,
-		logger: logger,
-		buffer: bytes.NewBuffer(make([]byte, 0, cfg.ChunkSize)),
+	if err != nil {
+		return nil, err
 	}
 
-	// Create first file
-	if err := w.createNewFile(); err != nil {
-		return nil, err
+	var combined fileWriter
+	if cfg.Split && cfg.AlsoCombined != "" {
+		if combined, err = newCombinedWriter(cfg); err != nil {
+			writer.Close()
+			return nil, err
+		}
 	}
 
-	return w, nil
-}
+	p := &Processor{
+		config:  cfg,
+		stats:   stats,
+		writer:  writer,
+		logger:  logger,
+		matcher: utils.NewIgnoreMatcher(append(append([]string{}, cfg.ExtraIgnores...), patterns...), cfg.UseDefaultIgnores),
+		include: include,
+		tests:   tests,
+		tracked: tracked,
+		changed: changed,
+		deleted: deleted,
+
+		transforms: transforms,
+		hasher:     hasher,
+
+		now:      time.Now(),
+		modTimes: make(map[string]time.Time),
+
+		singleFile: singleFile,
+		languages:  utils.NewLanguageResolver(cfg.LanguageOverrides),
+		fileTypes:  utils.NewFileTypeDetector(cfg.TreatAsText, cfg.TreatAsBinary, cfg.BinarySampleBytes),
+		order:      order,
+		combined:   combined,
+	}
 
-func (w *multiFileWriter) Write(content string) error {
-	w.mu.Lock()
-	defer w.mu.Unlock()
+	p.ignoreSources = ignoreSources(cfg, patterns)
 
-	if !utf8.ValidString(content) {
-		return fmt.Errorf("invalid UTF-8 content detected")
+	if cfg.RespectEditorConfig {
+		p.editorConfig = utils.NewEditorConfig()
 	}
 
-	contentSize := int64(len(content))
-
-	// If this is the first write or current file would exceed size limit
-	if w.writer == nil || w.outputSize+contentSize > int64(w.config.MaxFileSizeMB)*1024*1024 {
-		if err := w.createNewFile(); err != nil {
-			return fmt.Errorf("failed to create new file: %w", err)
-		}
-		w.outputSize = 0
+	if cfg.ResolveIncludes {
+		p.includes = utils.NewIncludeResolver(cfg.InputDir)
 	}
 
-	if _, err := w.writer.WriteString(content); err != nil {
-		return fmt.Errorf("failed to write content: %w", err)
+	p.workers = cfg.Concurrency
+	if p.workers <= 0 {
+		p.workers = utils.ConcurrencyFor(utils.ProbeFilesystem(cfg.InputDir), runtime.NumCPU())
 	}
 
-	w.outputSize += contentSize
-	w.updateFileStats(w.getCurrentPath(), w.outputSize)
+	if cfg.MaxReadBytesPerSec > 0 {
+		p.limiter = utils.NewRateLimiter(cfg.MaxReadBytesPerSec)
+	}
 
-	// If we're approaching the size limit, flush the writer
-	if w.outputSize >= int64(w.config.MaxFileSizeMB)*1024*1024 {
-		if err := w.writer.Flush(); err != nil {
-			return fmt.Errorf("failed to flush writer: %w", err)
+	if cfg.ParentIgnores {
+		scoped, err := utils.FindAncestorIgnorePatterns(cfg.InputDir, cfg.IgnoreCeiling, []string{".gitignore", cfg.IgnoreFile})
+		if err != nil {
+			writer.Close()
+			return nil, fmt.Errorf("failed to read parent ignore files: %w", err)
+		}
+		for _, s := range scoped {
+			p.matcher.AddScoped(s)
 		}
+		p.ignoreSources = append(p.ignoreSources, scoped...)
 	}
 
-	return nil
-}
-
-func (w *multiFileWriter) Close() error {
-	if w.writer != nil {
-		if err := w.writer.Flush(); err != nil {
-			return fmt.Errorf("failed to flush writer: %w", err)
+	if cfg.ExcludeContent != "" {
+		p.excludeContent, err = regexp.Compile(cfg.ExcludeContent)
+		if err != nil {
+			writer.Close()
+			return nil, fmt.Errorf("invalid exclude content regex: %w", err)
 		}
 	}
-	if w.currentFile != nil {
-		if err := w.currentFile.Close(); err != nil {
-			return fmt.Errorf("failed to close file: %w", err)
+
+	if cfg.IncludeContent != "" {
+		p.includeContent, err = regexp.Compile(cfg.IncludeContent)
+		if err != nil {
+			writer.Close()
+			return nil, fmt.Errorf("invalid include content regex: %w", err)
 		}
 	}
 
-	// Calculate final stats
-	if err := w.calculateFinalStats(); err != nil {
-		return fmt.Errorf("failed to calculate final stats: %w", err)
+	if cfg.SinceLastRun {
+		state, err := LoadRunState(cfg.StateFile)
+		if err != nil {
+			writer.Close()
+			return nil, err
+		}
+		p.prevState = state
+		p.nextState = NewRunState()
 	}
 
-	return nil
+	return p, nil
 }
 
-func (w *multiFileWriter) createNewFile() error {
-	// Flush and close current file if it exists
-	if w.writer != nil {
-		if err := w.writer.Flush(); err != nil {
-			return fmt.Errorf("failed to flush writer: %w", err)
-		}
-	}
-	if w.currentFile != nil {
-		if err := w.currentFile.Close(); err != nil {
-			return fmt.Errorf("failed to close file: %w", err)
+// Process handles the entire processing workflow
+func (p *Processor) Process(ctx context.Context) error {
+	// The writer is closed before printing stats on success, since split
+	// output sizes are only final once every part is flushed
+	closed := false
+	defer func() {
+		if !closed {
+			p.closeWriters()
 		}
-	}
+	}()
 
-	// Create new file
-	w.fileIndex++
-	path := w.getCurrentPath()
+	if p.config.QuickEstimate {
+		return p.quickEstimate(ctx)
+	}
 
-	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+	if p.config.ListLanguages {
+		return p.listLanguages(ctx)
 	}
 
-	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	// Collect and process files
+	files, err := p.collectFiles(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+		if ctx.Err() != nil {
+			return stopError(ctx)
+		}
+		return fmt.Errorf("failed to collect files: %w", err)
 	}
 
-	if _, err := file.Write(utf8BOM); err != nil {
-		file.Close()
-		return fmt.Errorf("failed to write UTF-8 BOM: %w", err)
+	if p.config.JSONStream {
+		if err := p.streamResults(ctx, files); err != nil {
+			if ctx.Err() != nil {
+				p.logger.LogWarning("Stopped after %d of %d files; output is partial", p.stats.IncludedCount, len(files))
+				return stopError(ctx)
+			}
+			return err
+		}
+		if p.nextState != nil {
+			if err := p.nextState.Save(p.config.StateFile); err != nil {
+				return err
+			}
+		}
+		closed = true
+		if err := p.closeWriters(); err != nil {
+			return fmt.Errorf("failed to close output: %w", err)
+		}
+		p.printStats()
+		return nil
 	}
 
-	w.currentFile = file
-	w.writer = bufio.NewWriterSize(file, w.config.ChunkSize)
-	w.stats.NumberOfFiles++
-
-	w.logger.Log("Created new file: %s", "📄", path)
-	return nil
-}
-
-func (w *multiFileWriter) getCurrentPath() string {
-	dir := filepath.Dir(w.config.OutputFile)
-	base := filepath.Base(w.config.OutputFile)
-	ext := filepath.Ext(base)
-	nameWithoutExt := strings.TrimSuffix(base, ext)
+	if w, ok := p.writer.(*multiFileWriter); ok && p.config.MaxParts > 0 {
+		total, err := p.estimateOutputSize(files)
+		if err != nil {
+			return fmt.Errorf("failed to estimate output size: %w", err)
+		}
+		if w.fitParts(total) {
+			p.logger.LogWarning("Parts will exceed --max-size to fit in %d parts (about %s each)",
+				p.config.MaxParts, utils.FormatSize(w.partSize))
+		}
+	}
 
-	if w.config.OutputFilePattern != "" {
-		return filepath.Join(dir, fmt.Sprintf(w.config.OutputFilePattern, w.fileIndex))
+	if preamble := formatInstructions(p.config.Instructions); preamble != "" {
+		if err := p.write(preamble); err != nil {
+			return fmt.Errorf("failed to write instructions: %w", err)
+		}
 	}
 
-	return filepath.Join(dir, fmt.Sprintf("%s_part%d%s", nameWithoutExt, w.fileIndex, ext))
-}
+	if p.config.GitHeader {
+		if info, err := utils.GitMetadata(p.config.InputDir); err == nil {
+			if err := p.write(formatGitHeader(info)); err != nil {
+				return fmt.Errorf("failed to write git header: %w", err)
+			}
+		}
+	}
 
-func (w *multiFileWriter) updateFileStats(path string, size int64) {
-	w.stats.mu.Lock()
-	defer w.stats.mu.Unlock()
+	if p.config.PathDepth > 0 {
+		p.shortPaths = utils.TrailingComponents(files, p.config.PathDepth)
+	}
 
-	if w.stats.SmallestFileSize == 0 || size < w.stats.SmallestFileSize {
-		w.stats.SmallestFile = path
-		w.stats.SmallestFileSize = size
+	if p.config.TrimCommonPrefix {
+		p.trimPrefix = utils.CommonDirPrefix(files)
+		if p.trimPrefix != "" {
+			if err := p.write(fmt.Sprintf("Paths are relative to %s\n\n", p.trimPrefix)); err != nil {
+				return fmt.Errorf("failed to write common prefix: %w", err)
+			}
+		}
 	}
 
-	if size > w.stats.LargestFileSize {
-		w.stats.LargestFile = path
-		w.stats.LargestFileSize = size
+	if p.config.SignaturesOnly {
+		if err := p.write(p.formatTree(files)); err != nil {
+			return fmt.Errorf("failed to write structure: %w", err)
+		}
 	}
-}
 
-func (p *Processor) collectFiles() ([]string, error) {
-	var files []string
+	if p.config.CollectTodos {
+		summary, err := p.formatTodoSummary(ctx, files)
+		if ctx.Err() != nil {
+			return stopError(ctx)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to collect TODOs: %w", err)
+		}
+		if err := p.write(summary); err != nil {
+			return fmt.Errorf("failed to write TODO summary: %w", err)
+		}
+	}
 
-	p.logger.Log("Collecting files from %s", "🔍", p.config.InputDir)
+	results := p.processFiles(ctx, files)
+	var binaries, manifest []FileResult
+	headers := make(map[string]string)
+	bucket := -1
 
-	err := filepath.Walk(p.config.InputDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+	// Write results
+	for result := range results {
+		if ctx.Err() != nil {
+			break
 		}
 
-		if info.IsDir() {
-			return nil
+		if result.Error != nil {
+			if p.config.Strict {
+				return fmt.Errorf("failed to process %s: %w", result.RelativePath, result.Error)
+			}
+			p.logger.LogError("Error processing %s: %v", result.RelativePath, result.Error)
+			continue
 		}
+		p.recordResult(result)
 
-		relPath, err := filepath.Rel(p.config.InputDir, path)
-		if err != nil {
-			return err
+		if result.Filtered {
+			p.stats.mu.Lock()
+			p.stats.FilteredCount++
+			p.stats.mu.Unlock()
+			continue
 		}
 
-		if p.matcher.ShouldIgnore(relPath) {
+		if result.Pruned {
 			p.stats.mu.Lock()
-			p.stats.IgnoredCount++
+			p.stats.PrunedCount++
 			p.stats.mu.Unlock()
-			return nil
+			continue
 		}
 
-		files = append(files, relPath)
+		if result.Generated {
+			p.stats.mu.Lock()
+			p.stats.GeneratedCount++
+			p.stats.mu.Unlock()
+			continue
+		}
+
+		if result.EmbeddedBOM {
+			if p.config.EmbeddedBOMMode == EmbeddedBOMFail {
+				return fmt.Errorf("%s contains embedded UTF-8 BOMs", result.RelativePath)
+			}
+			p.logger.LogWarning("%s contains embedded UTF-8 BOMs", result.RelativePath)
+		}
+
+		if p.config.BinaryManifest != "" && p.config.BinaryManifest != BinaryManifestNone && result.FileType != "text" && result.SymlinkTarget == "" {
+			manifest = append(manifest, result)
+		}
+
+		// Binaries are held back and listed together at the end
+		if p.config.CompactBinaries && result.FileType != "text" && !result.Embedded && result.SymlinkTarget == "" {
+			binaries = append(binaries, result)
+			p.updateStats(result)
+			continue
+		}
+
+		if err := p.checkDuplicateHeader(&result, headers); err != nil {
+			return err
+		}
+		if p.results != nil {
+			recorded := &p.results[len(p.results)-1]
+			recorded.Header = result.Header
+			if p.config.ResultsContent {
+				recorded.Content = result.Content
+			}
+		}
+
+		if p.config.GroupByAge {
+			if b := p.ageBucketFor(result.RelativePath); b != bucket {
+				bucket = b
+				if err := p.write(p.formatAgeHeading(b)); err != nil {
+					return fmt.Errorf("failed to write age heading: %w", err)
+				}
+			}
+		}
+
+		if lw, ok := p.writer.(*languageWriter); ok {
+			lw.setLanguage(outputLanguage(result))
+		}
+		if mw, ok := p.writer.(*multiFileWriter); ok && (p.config.SplitIndex != "" || p.config.PartHeaders || p.config.SplitPerFile) {
+			mw.noteFile(result.RelativePath)
+		}
+
+		if err := p.write(result.Content); err != nil {
+			if errors.Is(err, ErrTokenLimit) {
+				p.logger.LogWarning("Stopped after %d of %d files at the %d token limit; output is partial",
+					p.stats.IncludedCount, len(files), p.config.HardLimitTokens)
+				return err
+			}
+			return fmt.Errorf("failed to write content: %w", err)
+		}
+
+		p.updateStats(result)
+	}
+
+	if lw, ok := p.writer.(*languageWriter); ok {
+		lw.setLanguage("")
+	}
+
+	if len(binaries) > 0 {
+		if err := p.write(p.formatBinaryList(binaries)); err != nil {
+			return fmt.Errorf("failed to write binary list: %w", err)
+		}
+	}
+
+	if ctx.Err() == nil {
+		if err := p.writeBinaryManifest(manifest); err != nil {
+			return err
+		}
+	}
+
+	if deleted := p.visibleDeletedFiles(); len(deleted) > 0 {
+		if err := p.write(p.formatDeletedList(deleted)); err != nil {
+			return fmt.Errorf("failed to write deleted file list: %w", err)
+		}
+	}
+
+	// The state isn't saved since files past the cutoff were never emitted
+	if ctx.Err() != nil {
+		p.logger.LogWarning("Stopped after %d of %d files; output is partial", p.stats.IncludedCount, len(files))
+		return stopError(ctx)
+	}
+
+	if p.config.VerifyLock {
+		return p.verifyLock()
+	}
+
+	if p.nextState != nil {
+		if err := p.nextState.Save(p.config.StateFile); err != nil {
+			return err
+		}
+	}
+
+	if p.config.LockFile != "" {
+		if err := p.writeLock(); err != nil {
+			return err
+		}
+	}
+
+	if p.config.ResultsFile != "" {
+		if err := p.writeResults(); err != nil {
+			return err
+		}
+	}
+
+	if p.config.ReportClones {
+		if err := p.findClones(ctx); err != nil {
+			if ctx.Err() != nil {
+				return stopError(ctx)
+			}
+			return fmt.Errorf("failed to detect duplicated code: %w", err)
+		}
+	}
+
+	closed = true
+	if err := p.closeWriters(); err != nil {
+		return fmt.Errorf("failed to close output: %w", err)
+	}
+
+	if p.config.SplitIndex != "" {
+		if err := p.writeSplitIndex(); err != nil {
+			return err
+		}
+	}
+
+	p.printStats()
+	return nil
+}
+
+// stopError maps a done context to the error reported for the run
+func stopError(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return ErrTimeout
+	}
+	return ctx.Err()
+}
+
+// write emits a block to the output, applying the output line ending last
+func (p *Processor) write(content string) error {
+	if p.config.HardLimitTokens > 0 {
+		tokens := utils.EstimateTokenCount(content)
+		if p.writtenTokens+tokens > p.config.HardLimitTokens {
+			return ErrTokenLimit
+		}
+		p.writtenTokens += tokens
+	}
+
+	if p.config.OutputEOL != "" {
+		content = utils.NormalizeLineEndings(content, p.config.OutputEOL)
+	}
+	if p.combined != nil {
+		if err := p.combined.Write(content); err != nil {
+			return fmt.Errorf("failed to write combined output: %w", err)
+		}
+	}
+	return p.writer.Write(content)
+}
+
+// newCombinedWriter creates the single file that receives the whole digest
+// alongside split parts
+func newCombinedWriter(cfg ProcessorConfig) (fileWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(cfg.AlsoCombined), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create combined output directory: %w", err)
+	}
+
+	cfg.OutputFile = cfg.AlsoCombined
+	return newSingleFileWriter(cfg)
+}
+
+// closeWriters closes the output writer and the combined file, recording
+// the combined file's final size
+func (p *Processor) closeWriters() error {
+	err := p.writer.Close()
+	if p.combined != nil {
+		if cerr := p.combined.Close(); err == nil {
+			err = cerr
+		}
+		if info, serr := os.Stat(p.config.AlsoCombined); serr == nil {
+			p.stats.CombinedSize = info.Size()
+		}
+	}
+	return err
+}
+
+// createOutputFile creates or truncates an output file with the given permissions,
+// applying them explicitly so the result doesn't depend on the umask
+func createOutputFile(path string, mode os.FileMode) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := file.Chmod(mode); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return file, nil
+}
+
+func newSingleFileWriter(cfg ProcessorConfig) (*singleFileWriter, error) {
+	file, err := createOutputFile(cfg.OutputFile, cfg.OutputMode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output file: %w", err)
+	}
+
+	// UTF-8 output is written without a BOM; other encodings need one
+	if cfg.OutputEncoding != utils.EncodingUTF8 {
+		if _, err := file.Write(utils.OutputBOM(cfg.OutputEncoding)); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to write BOM: %w", err)
+		}
+	}
+
+	return &singleFileWriter{
+		file:   file,
+		writer: bufio.NewWriterSize(utils.NewEncodingWriter(file, cfg.OutputEncoding), cfg.ChunkSize),
+	}, nil
+}
+
+func (w *singleFileWriter) Write(content string) error {
+	_, err := w.writer.WriteString(content)
+	return err
+}
+
+func (w *singleFileWriter) Close() error {
+	if err := w.writer.Flush(); err != nil {
+		return err
+	}
+	return w.file.Close()
+}
+
+func newMultiFileWriter(cfg ProcessorConfig, stats *ProcessorStats, logger *utils.Logger) (*multiFileWriter, error) {
+	w := &multiFileWriter{
+		config: cfg,
+		stats:  stats,
+		logger: logger,
+		buffer: bytes.NewBuffer(make([]byte, 0, cfg.ChunkSize)),
+	}
+
+	if cfg.SplitResume {
+		hasher, err := utils.NewHasher(utils.DefaultHashAlgo)
+		if err != nil {
+			return nil, err
+		}
+		w.hasher = hasher
+	}
+
+	// Create first file
+	if err := w.createNewFile(); err != nil {
+		return nil, err
+	}
+
+	if cfg.ParallelWrite {
+		w.startBackgroundWriter()
+	}
+
+	return w, nil
+}
+
+func (w *multiFileWriter) Write(content string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !utf8.ValidString(content) {
+		return fmt.Errorf("invalid UTF-8 content detected")
+	}
+
+	// With SplitPerFile, blocks between files open the next file's part
+	// rather than a part of their own
+	if w.config.SplitPerFile && w.partHasFile() {
+		if w.nextFile == "" {
+			w.pending = append(w.pending, content)
+			return nil
+		}
+		content = strings.Join(w.pending, "") + content
+		w.pending = nil
+	}
+
+	if w.config.ParallelWrite {
+		return w.writeParallel(content)
+	}
+
+	contentSize := w.encodedLen(content)
+
+	// If this is the first write or current file would exceed size limit
+	if w.writer == nil || w.shouldRotate(contentSize) {
+		overlap, repeated := w.overlapFor(contentSize)
+		if err := w.createNewFile(); err != nil {
+			return fmt.Errorf("failed to create new file: %w", err)
+		}
+		w.outputSize = 0
+		w.recordOverlap(w.fileIndex, repeated)
+
+		if _, err := w.writer.WriteString(overlap); err != nil {
+			return fmt.Errorf("failed to write overlap: %w", err)
+		}
+		w.outputSize += w.encodedLen(overlap)
+	}
+
+	if _, err := w.writer.WriteString(content); err != nil {
+		return fmt.Errorf("failed to write content: %w", err)
+	}
+	w.remember(content)
+	w.recordFile(w.fileIndex)
+
+	if err := w.flushPeriodically(contentSize); err != nil {
+		return err
+	}
+
+	w.outputSize += contentSize
+
+	// If we're approaching the size limit, flush the writer
+	if w.outputSize >= w.maxPartSize() {
+		if err := w.writer.Flush(); err != nil {
+			return fmt.Errorf("failed to flush writer: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// shouldRotate reports whether content must start a new output file, either
+// because it would exceed the size limit or because each block gets its own file
+func (w *multiFileWriter) shouldRotate(contentSize int64) bool {
+	if w.config.MaxParts > 0 && w.currentPart() >= w.config.MaxParts {
+		return false
+	}
+
+	if w.config.SplitPerFile {
+		return w.partHasFile()
+	}
+	return w.outputSize+contentSize > w.maxPartSize()
+}
+
+// partHasFile reports whether a file's content was written to the current part
+func (w *multiFileWriter) partHasFile() bool {
+	part := w.currentPart()
+	return part > 0 && part <= len(w.partFiles) && len(w.partFiles[part-1]) > 0
+}
+
+// flushPending writes blocks held after the last file to the last part
+func (w *multiFileWriter) flushPending() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	content := strings.Join(w.pending, "")
+	w.pending = nil
+	if content == "" {
+		return nil
+	}
+
+	w.outputSize += w.encodedLen(content)
+	if w.config.ParallelWrite {
+		w.buffer.WriteString(content)
+		return nil
+	}
+	if _, err := w.writer.WriteString(content); err != nil {
+		return fmt.Errorf("failed to write content: %w", err)
+	}
+	return nil
+}
+
+// currentPart returns the 1-based index of the part being filled. With
+// ParallelWrite, fileIndex belongs to the background writer, so the
+// producer reads its own partIndex instead.
+func (w *multiFileWriter) currentPart() int {
+	if w.config.ParallelWrite {
+		return w.partIndex
+	}
+	return w.fileIndex
+}
+
+// maxPartSize returns the size limit of each part in bytes
+func (w *multiFileWriter) maxPartSize() int64 {
+	if w.partSize > 0 {
+		return w.partSize
+	}
+	return int64(w.config.MaxFileSizeMB) * 1024 * 1024
+}
+
+// fitParts sizes parts so totalSize bytes fit in MaxParts parts, returning
+// true when that needs parts larger than MaxFileSizeMB
+func (w *multiFileWriter) fitParts(totalSize int64) bool {
+	if w.config.MaxParts <= 0 || w.config.SplitPerFile {
+		return false
+	}
+
+	needed := (totalSize + int64(w.config.MaxParts) - 1) / int64(w.config.MaxParts)
+	if needed <= w.maxPartSize() {
+		return false
+	}
+
+	w.partSize = needed
+	return true
+}
+
+// encodedLen returns the size of content once written in the output encoding
+func (w *multiFileWriter) encodedLen(content string) int64 {
+	return int64(utils.EncodedLen(content, w.config.OutputEncoding))
+}
+
+// flushPeriodically flushes the writer once FlushInterval bytes have
+// accumulated, bounding buffered data independently of the part size
+func (w *multiFileWriter) flushPeriodically(written int64) error {
+	if w.config.FlushInterval <= 0 {
+		return nil
+	}
+
+	w.sinceFlush += written
+	if w.sinceFlush < w.config.FlushInterval {
+		return nil
+	}
+
+	w.sinceFlush = 0
+	if err := w.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush writer: %w", err)
+	}
+	return nil
+}
+
+// remember keeps content for repetition at the top of the next part
+func (w *multiFileWriter) remember(content string) {
+	if w.config.SplitOverlap <= 0 {
+		return
+	}
+
+	w.recent = append(w.recent, content)
+	w.recentFiles = append(w.recentFiles, w.nextFile)
+	if drop := len(w.recent) - w.config.SplitOverlap; drop > 0 {
+		w.recent = w.recent[drop:]
+		w.recentFiles = w.recentFiles[drop:]
+	}
+}
+
+// overlapFor returns the most recent contents that fit in a new part
+// alongside the next contentSize bytes, dropping the oldest first, and the
+// files among them
+func (w *multiFileWriter) overlapFor(contentSize int64) (string, []string) {
+	budget := w.maxPartSize() - contentSize
+
+	start := len(w.recent)
+	var size int64
+	for start > 0 && size+w.encodedLen(w.recent[start-1]) <= budget {
+		start--
+		size += w.encodedLen(w.recent[start])
+	}
+
+	var files []string
+	for _, file := range w.recentFiles[start:] {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return strings.Join(w.recent[start:], ""), files
+}
+
+func (w *multiFileWriter) Close() error {
+	if err := w.flushPending(); err != nil {
+		return err
+	}
+
+	if w.config.ParallelWrite {
+		if err := w.stopBackgroundWriter(); err != nil {
+			return err
+		}
+	}
+
+	if w.writer != nil {
+		if err := w.writer.Flush(); err != nil {
+			return fmt.Errorf("failed to flush writer: %w", err)
+		}
+	}
+	if w.currentFile != nil {
+		if err := w.closeCurrentFile(); err != nil {
+			return err
+		}
+	}
+
+	if w.config.PartHeaders {
+		if err := w.writePartHeaders(); err != nil {
+			return err
+		}
+	}
+
+	if err := w.handleStaleParts(); err != nil {
+		return err
+	}
+
+	// Calculate final stats
+	if err := w.calculateFinalStats(); err != nil {
+		return fmt.Errorf("failed to calculate final stats: %w", err)
+	}
+
+	return nil
+}
+
+// startBackgroundWriter launches the goroutine that writes filled buffers
+// and rotates files, so producers can keep filling the next buffer
+func (w *multiFileWriter) startBackgroundWriter() {
+	w.partIndex = w.fileIndex
+	w.jobs = make(chan writeJob, 2)
+	w.free = make(chan *bytes.Buffer, 1)
+	w.free <- bytes.NewBuffer(make([]byte, 0, w.config.ChunkSize))
+	w.done = make(chan struct{})
+
+	go w.runBackgroundWriter()
+}
+
+func (w *multiFileWriter) runBackgroundWriter() {
+	defer close(w.done)
+
+	for job := range w.jobs {
+		if w.writeErr != nil {
+			continue
+		}
+
+		if job.rotate {
+			if err := w.createNewFile(); err != nil {
+				w.writeErr = fmt.Errorf("failed to create new file: %w", err)
+			}
+			continue
+		}
+
+		if _, err := w.writer.Write(job.buffer.Bytes()); err != nil {
+			w.writeErr = fmt.Errorf("failed to write content: %w", err)
+		} else if err := w.flushPeriodically(int64(job.buffer.Len())); err != nil {
+			w.writeErr = err
+		}
+
+		job.buffer.Reset()
+		w.free <- job.buffer
+	}
+}
+
+// writeParallel accounts for content on the producer side and hands
+// completed buffers to the background writer
+func (w *multiFileWriter) writeParallel(content string) error {
+	contentSize := w.encodedLen(content)
+
+	if w.shouldRotate(contentSize) {
+		overlap, repeated := w.overlapFor(contentSize)
+		w.handOffBuffer()
+		w.jobs <- writeJob{rotate: true}
+		w.partIndex++
+		w.outputSize = 0
+		w.recordOverlap(w.partIndex, repeated)
+
+		w.buffer.WriteString(overlap)
+		w.outputSize += w.encodedLen(overlap)
+	}
+
+	w.buffer.WriteString(content)
+	w.remember(content)
+	w.recordFile(w.partIndex)
+	w.outputSize += contentSize
+
+	if w.buffer.Len() >= w.config.ChunkSize {
+		w.handOffBuffer()
+	}
+
+	return nil
+}
+
+// handOffBuffer sends the active buffer to the background writer and
+// waits for the spare one to become available
+func (w *multiFileWriter) handOffBuffer() {
+	if w.buffer.Len() == 0 {
+		return
+	}
+
+	w.jobs <- writeJob{buffer: w.buffer}
+	w.buffer = <-w.free
+}
+
+// stopBackgroundWriter drains pending buffers and waits for the writer to exit
+func (w *multiFileWriter) stopBackgroundWriter() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.handOffBuffer()
+	close(w.jobs)
+	<-w.done
+
+	return w.writeErr
+}
+
+func (w *multiFileWriter) createNewFile() error {
+	// Flush and close current file if it exists
+	if w.writer != nil {
+		if err := w.writer.Flush(); err != nil {
+			return fmt.Errorf("failed to flush writer: %w", err)
+		}
+	}
+	if w.currentFile != nil {
+		if err := w.closeCurrentFile(); err != nil {
+			return err
+		}
+	}
+
+	// Create new file
+	w.fileIndex++
+	path := w.getCurrentPath()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// With SplitResume, parts are written aside and only replace the existing
+	// part once they're known to differ
+	createPath := path
+	if w.config.SplitResume {
+		createPath = path + resumeSuffix
+	}
+
+	file, err := createOutputFile(createPath, w.config.OutputMode)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+
+	if _, err := file.Write(utils.OutputBOM(w.config.OutputEncoding)); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write BOM: %w", err)
+	}
+
+	w.currentFile = file
+	w.writer = bufio.NewWriterSize(utils.NewEncodingWriter(file, w.config.OutputEncoding), w.config.ChunkSize)
+	w.sinceFlush = 0
+
+	w.stats.mu.Lock()
+	w.stats.NumberOfFiles++
+	w.stats.mu.Unlock()
+
+	w.logger.Log("Created new file: %s", utils.IconFile, path)
+	return nil
+}
+
+// resumeSuffix is appended to split parts while they're written with SplitResume
+const resumeSuffix = ".partial"
+
+// closeCurrentFile closes the active part and, with SplitResume, either
+// discards it as unchanged or moves it over the previous part
+func (w *multiFileWriter) closeCurrentFile() error {
+	if err := w.currentFile.Close(); err != nil {
+		return fmt.Errorf("failed to close file: %w", err)
+	}
+	if !w.config.SplitResume {
+		return nil
+	}
+
+	tmpPath := w.currentFile.Name()
+	path := strings.TrimSuffix(tmpPath, resumeSuffix)
+
+	unchanged, err := w.sameContent(tmpPath, path)
+	if err != nil {
+		return err
+	}
+	if unchanged {
+		w.logger.Log("Unchanged: %s", utils.IconFile, path)
+		if err := os.Remove(tmpPath); err != nil {
+			return fmt.Errorf("failed to remove temporary part: %w", err)
+		}
+		return nil
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace part: %w", err)
+	}
+	return nil
+}
+
+// sameContent reports whether an existing part at path has the same content
+// hash as the newly written one
+func (w *multiFileWriter) sameContent(newPath, path string) (bool, error) {
+	oldInfo, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to stat part: %w", err)
+	}
+	newInfo, err := os.Stat(newPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat part: %w", err)
+	}
+	if oldInfo.Size() != newInfo.Size() {
+		return false, nil
+	}
+
+	oldHash, err := w.hasher.HashFile(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to hash part: %w", err)
+	}
+	newHash, err := w.hasher.HashFile(newPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to hash part: %w", err)
+	}
+
+	return oldHash == newHash, nil
+}
+
+func (w *multiFileWriter) getCurrentPath() string {
+	dir := filepath.Dir(w.config.OutputFile)
+	base := filepath.Base(w.config.OutputFile)
+	ext := filepath.Ext(base)
+	nameWithoutExt := strings.TrimSuffix(base, ext)
+
+	if w.config.OutputFilePattern != "" {
+		return filepath.Join(dir, fmt.Sprintf(w.config.OutputFilePattern, w.fileIndex))
+	}
+
+	return filepath.Join(dir, fmt.Sprintf("%s_part%d%s", nameWithoutExt, w.fileIndex, ext))
+}
+
+func (p *Processor) collectFiles(ctx context.Context) ([]string, error) {
+	var files []string
+
+	if p.config.UseFileList {
+		return p.collectListedFiles(ctx)
+	}
+
+	p.logger.Log("Collecting files from %s", utils.IconSearch, p.config.InputDir)
+
+	root := p.config.InputDir
+	if p.singleFile != "" {
+		root = filepath.Join(root, p.singleFile)
+	}
+
+	var explainer *utils.IgnoreExplainer
+	if p.config.ShowIgnored {
+		explainer = utils.NewIgnoreExplainer(p.ignoreSources)
+	}
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(p.config.InputDir, path)
+		if err != nil {
+			return err
+		}
+
+		// Skip ignored directories entirely. Files inside them are not
+		// visited, so they are not counted in IgnoredCount.
+		if info.IsDir() {
+			if relPath != "." && p.matcher.SkipsDir(relPath) {
+				p.stats.mu.Lock()
+				p.stats.SkippedDirCount++
+				p.stats.mu.Unlock()
+				if explainer != nil {
+					p.stats.IgnoredFiles = append(p.stats.IgnoredFiles, exclusion(relPath, true, explainer.Explain(relPath+"/")))
+				}
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if p.matcher.ShouldIgnore(relPath) || p.isStateFile(path) ||
+			(p.include != nil && !p.include.Matches(relPath)) ||
+			(p.tracked != nil && !p.tracked[filepath.ToSlash(relPath)]) ||
+			(p.changed != nil && !p.changed[filepath.ToSlash(relPath)]) {
+			p.stats.mu.Lock()
+			p.stats.IgnoredCount++
+			p.stats.mu.Unlock()
+			if explainer != nil {
+				p.stats.IgnoredFiles = append(p.stats.IgnoredFiles, p.explainIgnored(explainer, relPath, path))
+			}
+			return nil
+		}
+
+		// With WithTestedSource, other files are kept until keepTestedSources
+		// knows which ones have tests
+		if p.tests != nil && p.tests.Matches(relPath) != p.config.OnlyTests && !(p.config.OnlyTests && p.config.WithTestedSource) {
+			p.stats.mu.Lock()
+			p.stats.TestFileCount++
+			p.stats.mu.Unlock()
+			return nil
+		}
+
+		if p.config.MaxAge > 0 && p.now.Sub(info.ModTime()) > p.config.MaxAge {
+			p.stats.mu.Lock()
+			p.stats.AgeFilteredCount++
+			p.stats.mu.Unlock()
+			return nil
+		}
+
+		if p.exceedsTypeLimit(relPath, info.Size()) {
+			p.stats.mu.Lock()
+			p.stats.TypeLimitedCount++
+			p.stats.mu.Unlock()
+			return nil
+		}
+
+		if p.prevState != nil {
+			key := filepath.ToSlash(relPath)
+			modTime := info.ModTime().UnixNano()
+			p.nextState.Files[key] = modTime
+
+			if !p.prevState.Changed(key, modTime) {
+				p.stats.mu.Lock()
+				p.stats.UnchangedCount++
+				p.stats.mu.Unlock()
+				return nil
+			}
+		}
+
+		if p.config.GroupByAge {
+			p.modTimes[relPath] = info.ModTime()
+		}
+
+		files = append(files, relPath)
 		return nil
 	})
 
 	if err != nil {
-		return nil, err
+		return nil, err
+	}
+
+	if p.config.OnlyTests && p.config.WithTestedSource {
+		files = p.keepTestedSources(files)
+	}
+
+	sort.SliceStable(files, func(i, j int) bool { return utils.NaturalLess(files[i], files[j]) })
+	files = prioritizeFiles(files, p.config.Prioritize)
+	files = p.orderFiles(files)
+	if p.config.GroupByAge {
+		files = p.groupFilesByAge(files)
+	}
+
+	p.stats.TotalFiles = len(files)
+	p.logger.Log("Found %d files to process", utils.IconFound, len(files))
+	return files, nil
+}
+
+// keepTestedSources keeps test files and the source files next to them that
+// they test, dropping everything else
+func (p *Processor) keepTestedSources(files []string) []string {
+	tested := make(map[string]bool)
+	for _, relPath := range files {
+		if p.tests.Matches(relPath) {
+			for _, source := range utils.TestedSources(relPath) {
+				tested[source] = true
+			}
+		}
+	}
+
+	kept := files[:0]
+	for _, relPath := range files {
+		if tested[relPath] || p.tests.Matches(relPath) {
+			kept = append(kept, relPath)
+		} else {
+			p.stats.TestFileCount++
+		}
+	}
+	return kept
+}
+
+// collectListedFiles returns the regular files in FileList, in list order,
+// warning about paths that don't exist
+func (p *Processor) collectListedFiles(ctx context.Context) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+
+	p.logger.Log("Reading %d listed paths", utils.IconSearch, len(p.config.FileList))
+
+	for _, listed := range p.config.FileList {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		fullPath := listed
+		if !filepath.IsAbs(fullPath) {
+			fullPath = filepath.Join(p.config.InputDir, listed)
+		}
+
+		info, err := os.Stat(fullPath)
+		if os.IsNotExist(err) {
+			p.logger.LogWarning("Skipping %s: file does not exist", listed)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !info.Mode().IsRegular() {
+			p.logger.LogWarning("Skipping %s: not a regular file", listed)
+			continue
+		}
+
+		relPath, err := filepath.Rel(p.config.InputDir, fullPath)
+		if err != nil {
+			return nil, err
+		}
+		if seen[relPath] {
+			continue
+		}
+		seen[relPath] = true
+
+		if p.config.GroupByAge {
+			p.modTimes[relPath] = info.ModTime()
+		}
+		files = append(files, relPath)
+	}
+
+	p.stats.TotalFiles = len(files)
+	p.logger.Log("Found %d files to process", utils.IconFound, len(files))
+	return files, nil
+}
+
+// estimateOutputSize approximates the digest size of files from their input
+// sizes plus a per-file allowance for headers and fences
+func (p *Processor) estimateOutputSize(files []string) (int64, error) {
+	const blockOverhead = 32
+
+	var total int64
+	for _, relPath := range files {
+		info, err := os.Stat(filepath.Join(p.config.InputDir, relPath))
+		if err != nil {
+			return 0, err
+		}
+		total += info.Size() + int64(len(relPath)) + blockOverhead
+	}
+	return total, nil
+}
+
+// exceedsTypeLimit checks a file against the limits configured for its extension
+func (p *Processor) exceedsTypeLimit(relPath string, size int64) bool {
+	limit, ok := p.config.TypeLimits[strings.ToLower(filepath.Ext(relPath))]
+	if !ok {
+		return false
+	}
+
+	if limit.MaxSize > 0 && size > limit.MaxSize {
+		return true
+	}
+
+	depth := len(strings.Split(filepath.ToSlash(relPath), "/"))
+	return limit.MaxDepth > 0 && depth > limit.MaxDepth
+}
+
+// prioritizeFiles moves files matching the given globs to the front, grouped
+// in pattern order, leaving the remaining files in their existing order
+func prioritizeFiles(files []string, patterns []string) []string {
+	if len(patterns) == 0 {
+		return files
+	}
+
+	ordered := make([]string, 0, len(files))
+	taken := make([]bool, len(files))
+
+	for _, pattern := range patterns {
+		for i, file := range files {
+			if taken[i] {
+				continue
+			}
+			if matched, _ := path.Match(pattern, filepath.ToSlash(file)); matched {
+				ordered = append(ordered, file)
+				taken[i] = true
+			}
+		}
+	}
+
+	for i, file := range files {
+		if !taken[i] {
+			ordered = append(ordered, file)
+		}
+	}
+
+	return ordered
+}
+
+// orderFiles moves the files listed in the order file to the front, in list
+// order, leaving the remaining files in their existing order. Listed paths
+// that aren't among the files are reported as warnings.
+func (p *Processor) orderFiles(files []string) []string {
+	if len(p.order) == 0 {
+		return files
+	}
+
+	index := make(map[string]int, len(files))
+	for i, file := range files {
+		index[filepath.ToSlash(file)] = i
+	}
+
+	ordered := make([]string, 0, len(files))
+	taken := make([]bool, len(files))
+
+	for _, listed := range p.order {
+		i, ok := index[path.Clean(filepath.ToSlash(listed))]
+		if !ok {
+			p.logger.LogWarning("Order file lists %s, which is not an included file", listed)
+			continue
+		}
+		if !taken[i] {
+			ordered = append(ordered, files[i])
+			taken[i] = true
+		}
+	}
+
+	for i, file := range files {
+		if !taken[i] {
+			ordered = append(ordered, file)
+		}
+	}
+
+	return ordered
+}
+
+// isStateFile reports whether path is the run state file or the lockfile
+func (p *Processor) isStateFile(path string) bool {
+	return samePath(path, p.config.StateFile) || samePath(path, p.config.LockFile)
+}
+
+// samePath reports whether path refers to target, which may be empty
+func samePath(path, target string) bool {
+	if target == "" {
+		return false
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return false
+	}
+
+	return absPath == absTarget
+}
+
+// partIndexRegex matches the %d verb, with optional flags and width, that
+// numbers split parts in an output pattern
+var partIndexRegex = regexp.MustCompile(`%[-+ 0#]*\d*d`)
+
+// staleParts returns parts numbered above the last one written by this run.
+// Only names that the part naming scheme reproduces exactly are returned.
+func (w *multiFileWriter) staleParts() ([]string, error) {
+	dir := filepath.Dir(w.getCurrentPathForIndex(1))
+
+	var prefix, suffix string
+	if w.config.OutputFilePattern != "" {
+		pattern := filepath.Base(w.config.OutputFilePattern)
+		loc := partIndexRegex.FindStringIndex(pattern)
+		if loc == nil {
+			return nil, nil
+		}
+		prefix, suffix = pattern[:loc[0]], pattern[loc[1]:]
+	} else {
+		base := filepath.Base(w.config.OutputFile)
+		ext := filepath.Ext(base)
+		prefix, suffix = strings.TrimSuffix(base, ext)+"_part", ext
+	}
+	nameRegex := regexp.MustCompile("^" + regexp.QuoteMeta(prefix) + `(\d+)` + regexp.QuoteMeta(suffix) + "$")
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list output directory: %w", err)
+	}
+
+	var stale []string
+	for _, entry := range entries {
+		m := nameRegex.FindStringSubmatch(entry.Name())
+		if m == nil || entry.IsDir() {
+			continue
+		}
+		index, err := strconv.Atoi(m[1])
+		if err != nil || index <= w.fileIndex {
+			continue
+		}
+		if path := w.getCurrentPathForIndex(index); filepath.Base(path) == entry.Name() {
+			stale = append(stale, path)
+		}
+	}
+
+	sort.Slice(stale, func(i, j int) bool { return utils.NaturalLess(stale[i], stale[j]) })
+	return stale, nil
+}
+
+// handleStaleParts removes leftover parts with CleanStale, or warns about them
+func (w *multiFileWriter) handleStaleParts() error {
+	stale, err := w.staleParts()
+	if err != nil {
+		return err
+	}
+	if len(stale) == 0 {
+		return nil
 	}
 
-	p.stats.TotalFiles = len(files)
-	p.logger.Log("Found %d files to process", "📚", len(files))
-	return files, nil
+	if !w.config.CleanStale {
+		w.logger.LogWarning("Found %d stale parts from a previous run (use --clean-stale to remove them)", len(stale))
+		return nil
+	}
+
+	for _, path := range stale {
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove stale part: %w", err)
+		}
+		w.logger.Log("Removed stale part: %s", utils.IconFile, path)
+	}
+	return nil
 }
 
 func (w *multiFileWriter) calculateFinalStats() error {
@@ -412,35 +1890,53 @@ func (w *multiFileWriter) getCurrentPathForIndex(index int) string {
 	return filepath.Join(dir, fmt.Sprintf("%s_part%d%s", nameWithoutExt, index, ext))
 }
 
-func (p *Processor) processFiles(files []string) chan FileResult {
+// processFiles processes files concurrently and delivers results in input
+// order. Delivery stops early once ctx is done.
+func (p *Processor) processFiles(ctx context.Context, files []string) chan FileResult {
 	resultChan := make(chan FileResult, len(files))
-	var wg sync.WaitGroup
-	semaphore := make(chan struct{}, maxConcurrency)
-
-	for _, file := range files {
-		wg.Add(1)
-		go func(relPath string) {
-			defer wg.Done()
-			semaphore <- struct{}{}
+	slots := make([]chan FileResult, len(files))
+	semaphore := make(chan struct{}, p.workers)
+
+	for i, file := range files {
+		slots[i] = make(chan FileResult, 1)
+		go func(relPath string, index int, slot chan FileResult) {
+			select {
+			case semaphore <- struct{}{}:
+			case <-ctx.Done():
+				slot <- FileResult{RelativePath: relPath, Index: index, Error: ctx.Err()}
+				return
+			}
 			defer func() { <-semaphore }()
 
-			result := p.processFile(relPath)
-			resultChan <- result
-		}(file)
+			slot <- p.processFile(relPath, index)
+		}(file, i+1, slots[i])
 	}
 
 	go func() {
-		wg.Wait()
-		close(resultChan)
+		defer close(resultChan)
+		for _, slot := range slots {
+			select {
+			case result := <-slot:
+				resultChan <- result
+			case <-ctx.Done():
+				return
+			}
+		}
 	}()
 
 	return resultChan
 }
 
-func (p *Processor) processFile(relPath string) FileResult {
-	result := FileResult{RelativePath: relPath}
+func (p *Processor) processFile(relPath string, index int) FileResult {
+	result := FileResult{RelativePath: relPath, Index: index}
 	fullPath := filepath.Join(p.config.InputDir, relPath)
 
+	if p.config.NoteSymlinks {
+		if info, err := os.Lstat(fullPath); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return p.processSymlink(fullPath, result)
+		}
+	}
+
 	// Get file info
 	info, err := os.Stat(fullPath)
 	if err != nil {
@@ -449,45 +1945,220 @@ func (p *Processor) processFile(relPath string) FileResult {
 	}
 	result.Size = info.Size()
 
+	if p.config.MaxInputFileSize > 0 && result.Size > p.config.MaxInputFileSize {
+		result.Pruned = true
+		return result
+	}
+
+	// Charge the whole file up front; the head reads below hit the page cache
+	if p.limiter != nil {
+		p.limiter.WaitN(result.Size)
+	}
+
+	if p.config.ReportDuplicates || p.config.HashHeaders {
+		if result.Hash, err = p.hasher.HashFile(fullPath); err != nil {
+			result.Error = err
+			return result
+		}
+	}
+
 	// Check if file is text
-	isText, err := utils.IsTextFile(fullPath)
+	mimeType, isText, err := p.fileTypes.DetectFileType(fullPath)
 	if err != nil {
 		result.Error = err
 		return result
 	}
+	result.MIMEType = mimeType
 
-	if isText && !utils.ShouldTreatAsBinary(fullPath) {
+	if isText && !p.fileTypes.ShouldTreatAsBinary(fullPath) {
 		result.FileType = "text"
-		content, err := p.processTextFile(fullPath)
+
+		filtered, err := p.isFilteredByContent(fullPath)
+		if err != nil {
+			result.Error = err
+			return result
+		}
+		if filtered {
+			result.Filtered = true
+			return result
+		}
+
+		noisy, err := p.isNoise(fullPath)
+		if err != nil {
+			result.Error = err
+			return result
+		}
+		if noisy {
+			result.Pruned = true
+			return result
+		}
+
+		if p.config.SkipGeneratedHeader {
+			head, err := utils.ReadHead(fullPath, contentFilterScanSize)
+			if err != nil {
+				result.Error = err
+				return result
+			}
+			if utils.HasGeneratedHeader(head) {
+				result.Generated = true
+				return result
+			}
+		}
+
+		content, err := p.processTextFile(fullPath, &result)
 		if err != nil {
 			result.Error = err
 			return result
 		}
 		result.Content = content
 	} else {
+		// Binary content can't be matched, so content filters drop it
+		if p.hasContentFilter() {
+			result.Filtered = true
+			return result
+		}
+
 		result.FileType = utils.GetFileType(fullPath)
-		result.Content = p.formatBinaryFileContent(relPath, result.FileType)
+
+		var data []byte
+		if p.config.MaxBinarySize > 0 && result.Size <= p.config.MaxBinarySize {
+			if data, err = os.ReadFile(fullPath); err != nil {
+				result.Error = err
+				return result
+			}
+			result.Embedded = true
+		}
+		result.Content = p.formatBinaryFileContent(&result, data)
 	}
 
 	return result
 }
 
-func (p *Processor) processTextFile(path string) (string, error) {
+// isNoise checks a text file's head for minified or generated content
+func (p *Processor) isNoise(path string) (bool, error) {
+	if !p.config.SkipMinified && !p.config.SkipGenerated {
+		return false, nil
+	}
+
+	head, err := utils.ReadHead(path, contentFilterScanSize)
+	if err != nil {
+		return false, err
+	}
+
+	if p.config.SkipMinified && utils.IsMinified(path, head) {
+		return true, nil
+	}
+	if p.config.SkipGenerated && utils.IsGenerated(head) {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// hasContentFilter reports whether any content regex is configured
+func (p *Processor) hasContentFilter() bool {
+	return p.excludeContent != nil || p.includeContent != nil
+}
+
+// isFilteredByContent scans the head of a text file against the content filters.
+// A file survives only if it passes both the exclude and include filters.
+func (p *Processor) isFilteredByContent(path string) (bool, error) {
+	if !p.hasContentFilter() {
+		return false, nil
+	}
+
+	head, err := utils.ReadHead(path, contentFilterScanSize)
+	if err != nil {
+		return false, err
+	}
+
+	if p.excludeContent != nil && p.excludeContent.Match(head) {
+		return true, nil
+	}
+	if p.includeContent != nil && !p.includeContent.Match(head) {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (p *Processor) processTextFile(path string, result *FileResult) (string, error) {
 	content, err := os.ReadFile(path)
 	if err != nil {
 		return "", err
 	}
 
-	content = bytes.TrimPrefix(content, utf8BOM)
-	if !utf8.Valid(content) {
+	result.EmbeddedBOM = hasEmbeddedUTF8BOM(content)
+
+	contentStr, encoding, err := utils.DecodeText(content)
+	if err != nil {
 		return "", fmt.Errorf("file %s contains invalid UTF-8 characters", path)
 	}
 
 	ext := filepath.Ext(path)
-	contentStr := string(content)
 
-	if p.config.RemoveWhitespace && !utils.IsWhitespaceSensitive(ext) {
-		contentStr = utils.RemoveWhitespace(contentStr)
+	if result.EmbeddedBOM && p.config.EmbeddedBOMMode == EmbeddedBOMStrip {
+		contentStr = stripUTF8BOMs(contentStr)
+	}
+
+	var title string
+	if p.config.PromoteFrontMatter {
+		if fields, body, ok := utils.ParseFrontMatter(contentStr); ok {
+			title = fields["title"]
+			if p.config.StripFrontMatter {
+				contentStr = body
+			}
+		}
+	}
+
+	if p.includes != nil {
+		var problems []string
+		contentStr, problems = p.includes.Expand(contentStr, path)
+		for _, problem := range problems {
+			p.logger.LogWarning("%s", problem)
+		}
+	}
+
+	if p.editorConfig != nil {
+		properties, err := p.editorConfig.Properties(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read .editorconfig: %w", err)
+		}
+		contentStr = utils.ApplyEditorConfig(contentStr, properties)
+	}
+
+	// Transforms see the detected language, so a Makefile or shebang script
+	// keeps its whitespace handling without the canonical extension
+	transformExt := ext
+	if p.config.DetectLanguage {
+		if detected := utils.DetectLanguageExt(path, contentStr); detected != "" {
+			transformExt = detected
+		}
+	}
+
+	original := contentStr
+	for _, transform := range p.transforms {
+		contentStr = transform(contentStr, transformExt)
+	}
+
+	if p.config.SummarizeOver > 0 && utils.EstimateTokenCount(contentStr) > p.config.SummarizeOver {
+		if summary := utils.Summarize(contentStr); summary != contentStr {
+			contentStr = summary
+			result.Summarized = true
+		}
+	}
+
+	if p.config.MaxTokensPerFile > 0 {
+		kept, removed := utils.TruncateToTokens(contentStr, p.config.MaxTokensPerFile)
+		if removed > 0 {
+			contentStr = fmt.Sprintf("%s\n[... truncated, %d more tokens ...]", kept, removed)
+			result.Truncated = true
+		}
+	}
+
+	if p.config.CompareBaseline {
+		result.BaselineTokens = utils.EstimateTokenCount(original)
+		result.Tokens = utils.EstimateTokenCount(contentStr)
 	}
 
 	relPath, err := filepath.Rel(p.config.InputDir, path)
@@ -495,24 +2166,55 @@ func (p *Processor) processTextFile(path string) (string, error) {
 		return "", fmt.Errorf("failed to get relative path: %w", err)
 	}
 
+	result.Header = p.formatHeader(relPath, result) + formatTitle(title)
+
 	var buf strings.Builder
-	fmt.Fprintf(&buf, "# %s\n\n", relPath)
+	fmt.Fprintf(&buf, "%s%s%s%s\n\n", result.Header,
+		p.formatHeaderMetadata(result.Size, utils.EstimateTokenCount(contentStr)),
+		p.formatHeaderHash(result.Hash), utils.FormatEncodingNote(encoding))
+	buf.WriteString(p.formatAuthors(relPath))
 
-	// For markdown files, use four backticks to wrap content
 	if ext == ".md" || ext == ".markdown" {
-		buf.WriteString("````md\n")
-		buf.WriteString(contentStr)
-		buf.WriteString("\n````\n\n")
+		p.writeMarkdownBlock(&buf, contentStr)
 	} else {
-		fmt.Fprintf(&buf, "```%s\n%s\n```\n\n",
-			strings.TrimPrefix(ext, "."),
-			contentStr)
+		writeCodeBlock(&buf, p.languages.Language(relPath), contentStr)
 	}
 
+	buf.WriteString(p.blockSeparator())
+
 	return buf.String(), nil
 }
 
-func (p *Processor) formatBinaryFileContent(path, fileType string) string {
+// writeCodeBlock wraps source content in a fence longer than any backtick run
+// inside it, so files that contain fences of their own can't end the block early
+func writeCodeBlock(buf *strings.Builder, lang, content string) {
+	fence := utils.FenceFor(content)
+	fmt.Fprintf(buf, "%s%s\n%s\n%s\n", fence, lang, content, fence)
+}
+
+// writeMarkdownBlock wraps markdown content according to the markdown mode
+func (p *Processor) writeMarkdownBlock(buf *strings.Builder, content string) {
+	switch p.config.MarkdownMode {
+	case MarkdownModeFenced:
+		fence := utils.FenceFor(content)
+		fmt.Fprintf(buf, "%smd\n%s\n%s\n", fence, content, fence)
+	case MarkdownModeEscape:
+		fmt.Fprintf(buf, "```md\n%s\n```\n", utils.EscapeTripleBackticks(content))
+	default:
+		// Use four backticks so inner triple-backtick fences survive
+		fmt.Fprintf(buf, "````md\n%s\n````\n", content)
+	}
+}
+
+// formatBinaryFileContent describes a binary file, embedding data as base64
+// when given and emitting only a placeholder otherwise
+func (p *Processor) formatBinaryFileContent(result *FileResult, data []byte) string {
+	path := result.RelativePath
+	fileType := result.FileType
+	if result.MIMEType != "" {
+		fileType = fmt.Sprintf("%s (%s)", fileType, result.MIMEType)
+	}
+
 	var description string
 	if strings.HasSuffix(strings.ToLower(path), ".svg") {
 		description = fmt.Sprintf("This is a file of type: %s", fileType)
@@ -520,144 +2222,372 @@ func (p *Processor) formatBinaryFileContent(path, fileType string) string {
 		description = fmt.Sprintf("This is a binary file of type: %s", fileType)
 	}
 
-	return fmt.Sprintf("# %s\n\n%s\n\n", path, description)
+	if data != nil {
+		description = fmt.Sprintf("%s, embedded as base64:\n\n```base64\n%s```\n", description, wrapBase64(data))
+	}
+
+	result.Header = p.formatHeader(path, result)
+	return fmt.Sprintf("%s%s%s\n\n%s%s\n%s", result.Header, p.formatHeaderMetadata(result.Size, -1),
+		p.formatHeaderHash(result.Hash), p.formatAuthors(path), description, p.blockSeparator())
 }
 
-func (p *Processor) updateStats(result FileResult) {
-	p.stats.mu.Lock()
-	defer p.stats.mu.Unlock()
+// wrapBase64 encodes data as base64 in lines of 76 characters, each ending
+// in a newline
+func wrapBase64(data []byte) string {
+	const lineLength = 76
 
-	p.stats.IncludedCount++
-	p.stats.IncludedFiles = append(p.stats.IncludedFiles, result.RelativePath)
-	if result.FileType != "text" {
-		p.stats.BinaryCount++
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var buf strings.Builder
+	for len(encoded) > lineLength {
+		buf.WriteString(encoded[:lineLength] + "\n")
+		encoded = encoded[lineLength:]
 	}
-	p.stats.TotalSize += result.Size
+	if encoded != "" {
+		buf.WriteString(encoded + "\n")
+	}
+	return buf.String()
 }
 
-func (p *Processor) printStats() {
-	if p.config.Split {
-		p.printSplitStats()
-	} else {
-		p.printSingleStats()
+// processSymlink records where a symlink points without following it
+func (p *Processor) processSymlink(fullPath string, result FileResult) FileResult {
+	target, err := os.Readlink(fullPath)
+	if err != nil {
+		result.Error = err
+		return result
 	}
+
+	result.FileType = "symlink"
+	result.SymlinkTarget = p.relativeSymlinkTarget(fullPath, target)
+	result.Header = p.formatHeader(result.RelativePath, &result)
+	result.Content = fmt.Sprintf("%s\n\n%sThis is a symbolic link to: %s\n%s", result.Header,
+		p.formatAuthors(result.RelativePath), result.SymlinkTarget, p.blockSeparator())
+	return result
 }
 
-func (p *Processor) printSingleStats() {
-	fmt.Println("\n📊 Processing Summary")
-	fmt.Println("═══════════════════")
+// relativeSymlinkTarget rewrites an absolute target inside the input
+// directory relative to the link, so the note doesn't expose local paths
+func (p *Processor) relativeSymlinkTarget(fullPath, target string) string {
+	if !filepath.IsAbs(target) {
+		return filepath.ToSlash(target)
+	}
 
-	// File counts section
-	fmt.Println("\n📁 File Statistics")
-	fmt.Printf("   • Total Files Scanned:     %5d\n", p.stats.TotalFiles)
-	fmt.Printf("   • Files in Output:         %5d\n", p.stats.IncludedCount)
-	fmt.Printf("   • Files Ignored:           %5d\n", p.stats.IgnoredCount)
-	fmt.Printf("   • Binary/SVG Files:        %5d\n", p.stats.BinaryCount)
+	root, err := filepath.Abs(p.config.InputDir)
+	if err != nil {
+		return target
+	}
+	if rel, err := filepath.Rel(root, target); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return target
+	}
 
-	// Size metrics
-	fmt.Println("\n💾 Size Analysis")
-	sizeInMB := float64(p.stats.TotalSize) / (1024 * 1024)
-	fmt.Printf("   • Total Size:              %.2f MB\n", sizeInMB)
+	linkDir, err := filepath.Abs(filepath.Dir(fullPath))
+	if err != nil {
+		return target
+	}
+	if rel, err := filepath.Rel(linkDir, target); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return target
+}
 
-	// Process effectiveness
-	fmt.Println("\n🎯 Processing Effectiveness")
-	if p.stats.TotalFiles > 0 {
-		inclusionRate := float64(p.stats.IncludedCount) / float64(p.stats.TotalFiles) * 100
-		fmt.Printf("   • Inclusion Rate:          %5.1f%%\n", inclusionRate)
+// formatHeader renders the header line for a file block from the header format
+func (p *Processor) formatHeader(relPath string, result *FileResult) string {
+	path := relPath
+	if short, ok := p.shortPaths[relPath]; ok {
+		path = short
+	} else if p.trimPrefix != "" {
+		path = strings.TrimPrefix(filepath.ToSlash(relPath), p.trimPrefix)
 	}
 
-	// Token estimation
-	fmt.Println("\n🔤 Token Estimation")
-	if p.stats.TotalSize > maxFileSize {
-		fmt.Println("   ⚠️  Output exceeds recommended size (10 MB)")
-		fmt.Println("   ⚠️  Token estimation skipped")
-		fmt.Printf("   💡 Tip: Add more patterns to %s to reduce size\n", p.config.IgnoreFile)
-	} else {
-		tokenCount := utils.EstimateTokenCount(fmt.Sprintf("%d", p.stats.TotalSize))
-		fmt.Printf("   • Estimated Tokens:        %5d\n", tokenCount)
-		fmt.Println("   📝 Note: Token count may vary ±20% across AI models")
-	}
-
-	// File listing (if enabled)
-	if p.config.ShowOutputFiles && len(p.stats.IncludedFiles) > 0 {
-		fmt.Println("\n📋 Included Files")
-		fmt.Println("   Files processed and included in output:")
-		for i, file := range p.stats.IncludedFiles {
-			if i < 10 { // Show first 10 files only
-				fmt.Printf("   %2d. %s\n", i+1, file)
+	return utils.ExpandHeaderFormat(p.config.HeaderFormat, utils.HeaderFields{
+		Path:  path,
+		Lang:  p.languages.Language(relPath),
+		Size:  result.Size,
+		Index: result.Index,
+	})
+}
+
+// formatInstructions renders the instruction paragraphs as one blockquote,
+// or returns "" when there are none
+func formatInstructions(paragraphs []string) string {
+	var buf strings.Builder
+	for _, paragraph := range paragraphs {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+		if buf.Len() > 0 {
+			buf.WriteString(">\n")
+		}
+		for _, line := range strings.Split(paragraph, "\n") {
+			if line = strings.TrimRight(line, " \t\r"); line == "" {
+				buf.WriteString(">\n")
 			} else {
-				remaining := len(p.stats.IncludedFiles) - 10
-				fmt.Printf("   ... and %d more files\n", remaining)
-				break
+				fmt.Fprintf(&buf, "> %s\n", line)
 			}
 		}
 	}
+	if buf.Len() == 0 {
+		return ""
+	}
+	buf.WriteString("\n")
+	return buf.String()
+}
 
-	// Final status
-	fmt.Println("\n✨ Process Complete")
-	if p.stats.TotalSize > maxFileSize {
-		fmt.Println("   ⚠️  Warning: Large output file size")
+// formatGitHeader renders the repository version the digest was taken from
+func formatGitHeader(info utils.GitInfo) string {
+	var buf strings.Builder
+	buf.WriteString("# Git\n\n")
+	if info.Branch != "" {
+		fmt.Fprintf(&buf, "- Branch: %s\n", info.Branch)
 	} else {
-		fmt.Println("   ✅ Output generated successfully")
-	}
-}
-
-func (p *Processor) printSplitStats() {
-	fmt.Println("\n📊 Split Processing Summary")
-	fmt.Println("═══════════════════════════")
-
-	// Output files information
-	fmt.Println("\n📁 Output Files")
-	fmt.Printf("   • Number of Files:         %d\n", p.stats.NumberOfFiles)
-	fmt.Printf("   • Average File Size:       %.2f MB\n", float64(p.stats.AverageFileSize)/(1024*1024))
-
-	// Size distribution
-	fmt.Println("\n📏 Size Distribution")
-	fmt.Printf("   • Smallest File:           %s (%.2f MB)\n",
-		filepath.Base(p.stats.SmallestFile),
-		float64(p.stats.SmallestFileSize)/(1024*1024))
-	fmt.Printf("   • Largest File:            %s (%.2f MB)\n",
-		filepath.Base(p.stats.LargestFile),
-		float64(p.stats.LargestFileSize)/(1024*1024))
-
-	// Processing statistics
-	fmt.Println("\n🔍 Processing Details")
-	fmt.Printf("   • Total Files Processed:   %d\n", p.stats.TotalFiles)
-	fmt.Printf("   • Files Included:          %d\n", p.stats.IncludedCount)
-	fmt.Printf("   • Files Ignored:           %d\n", p.stats.IgnoredCount)
-	fmt.Printf("   • Binary/SVG Files:        %d\n", p.stats.BinaryCount)
-
-	// Total size
-	fmt.Println("\n💾 Total Size")
-	fmt.Printf("   • Combined Size:           %.2f MB\n", float64(p.stats.TotalSize)/(1024*1024))
-
-	// Process effectiveness
-	fmt.Println("\n🎯 Processing Effectiveness")
-	if p.stats.TotalFiles > 0 {
-		inclusionRate := float64(p.stats.IncludedCount) / float64(p.stats.TotalFiles) * 100
-		fmt.Printf("   • Inclusion Rate:          %5.1f%%\n", inclusionRate)
-	}
-
-	// File listing (if enabled)
-	if p.config.ShowOutputFiles && len(p.stats.IncludedFiles) > 0 {
-		fmt.Println("\n📋 Included Files")
-		fmt.Println("   Files processed and included in output:")
-		for i, file := range p.stats.IncludedFiles {
-			if i < 10 {
-				fmt.Printf("   %2d. %s\n", i+1, file)
-			} else {
-				remaining := len(p.stats.IncludedFiles) - 10
-				fmt.Printf("   ... and %d more files\n", remaining)
-				break
-			}
+		buf.WriteString("- Branch: (detached HEAD)\n")
+	}
+	fmt.Fprintf(&buf, "- Commit: %s\n", info.Commit)
+	if info.Remote != "" {
+		fmt.Fprintf(&buf, "- Remote: %s\n", info.Remote)
+	}
+	buf.WriteString("\n")
+	return buf.String()
+}
+
+// formatTitle returns the header annotation for a front matter title
+func formatTitle(title string) string {
+	if title == "" {
+		return ""
+	}
+	return utils.TitleSeparator + title
+}
+
+// formatHeaderMetadata returns the size and token annotation for a file
+// header, omitting tokens when negative
+func (p *Processor) formatHeaderMetadata(size int64, tokens int) string {
+	if !p.config.HeaderMetadata {
+		return ""
+	}
+	if tokens < 0 {
+		return fmt.Sprintf(" (%s)", utils.FormatSize(size))
+	}
+	return fmt.Sprintf(" (%s, ~%d tokens)", utils.FormatSize(size), tokens)
+}
+
+// headerHashLength is how many hex digits of the content hash file headers show
+const headerHashLength = 12
+
+// formatHeaderHash returns the short content hash annotation for a file header
+func (p *Processor) formatHeaderHash(hash string) string {
+	if !p.config.HashHeaders || hash == "" {
+		return ""
+	}
+	return fmt.Sprintf(" [%s:%s]", p.hasher.Name(), hash[:min(len(hash), headerHashLength)])
+}
+
+// formatTodoSummary scans text files for TODO/FIXME/HACK markers and renders
+// them as a single block listing each marker with its location
+func (p *Processor) formatTodoSummary(ctx context.Context, files []string) (string, error) {
+	var buf strings.Builder
+	buf.WriteString("# TODO Summary\n\n")
+
+	count := 0
+	for _, relPath := range files {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+
+		fullPath := filepath.Join(p.config.InputDir, relPath)
+		if isText, err := p.fileTypes.IsTextFile(fullPath); err != nil || !isText || p.fileTypes.ShouldTreatAsBinary(fullPath) {
+			continue
+		}
+
+		f, err := os.Open(fullPath)
+		if err != nil {
+			return "", err
+		}
+		todos, err := utils.ScanTodos(f)
+		f.Close()
+		if err != nil {
+			return "", fmt.Errorf("failed to scan %s: %w", relPath, err)
+		}
+
+		for _, todo := range todos {
+			fmt.Fprintf(&buf, "- `%s:%d` %s: %s\n", filepath.ToSlash(relPath), todo.Line, todo.Marker, todo.Text)
+			count++
+		}
+	}
+
+	if count == 0 {
+		buf.WriteString("No TODO, FIXME or HACK markers found.\n")
+	}
+
+	buf.WriteString(p.blockSeparator())
+	return buf.String(), nil
+}
+
+// findClones scans the included text files for duplicated blocks of code
+func (p *Processor) findClones(ctx context.Context) error {
+	finder := utils.NewCloneFinder(utils.DefaultCloneMinLines)
+	for _, relPath := range p.stats.IncludedFiles {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		fullPath := filepath.Join(p.config.InputDir, relPath)
+		if isText, err := p.fileTypes.IsTextFile(fullPath); err != nil || !isText || p.fileTypes.ShouldTreatAsBinary(fullPath) {
+			continue
 		}
+
+		f, err := os.Open(fullPath)
+		if err != nil {
+			return err
+		}
+		err = finder.Add(filepath.ToSlash(relPath), f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to scan %s: %w", relPath, err)
+		}
+	}
+
+	p.stats.Clones = finder.Clusters()
+	return nil
+}
+
+// visibleDeletedFiles returns the files deleted in the commit range that the
+// ignore patterns don't exclude
+func (p *Processor) visibleDeletedFiles() []string {
+	var visible []string
+	for _, path := range p.deleted {
+		if !p.matcher.ShouldIgnore(path) {
+			visible = append(visible, path)
+		}
+	}
+	return visible
+}
+
+// formatDeletedList renders files deleted in the commit range as a list block
+func (p *Processor) formatDeletedList(deleted []string) string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "# Deleted in %s\n\n", p.config.CommitRange)
+	for _, path := range deleted {
+		fmt.Fprintf(&buf, "- %s\n", path)
+	}
+	buf.WriteString(p.blockSeparator())
+	return buf.String()
+}
+
+// formatBinaryList renders binary files as a single table block
+func (p *Processor) formatBinaryList(binaries []FileResult) string {
+	var buf strings.Builder
+	buf.WriteString("# Binary files\n\n")
+	buf.WriteString("| Path | Type |\n")
+	buf.WriteString("| --- | --- |\n")
+
+	for _, b := range binaries {
+		fileType := b.FileType
+		if b.MIMEType != "" {
+			fileType = fmt.Sprintf("%s (%s)", fileType, b.MIMEType)
+		}
+		fmt.Fprintf(&buf, "| %s | %s |\n", strings.ReplaceAll(b.RelativePath, "|", "\\|"), fileType)
+	}
+
+	buf.WriteString(p.blockSeparator())
+	return buf.String()
+}
+
+// blockSeparator returns the text emitted after each file block
+func (p *Processor) blockSeparator() string {
+	if p.config.BlockSeparator != "" {
+		return "\n" + p.config.BlockSeparator + "\n\n"
+	}
+	return strings.Repeat("\n", max(p.config.NewlinesBetweenFiles, 0))
+}
+
+// formatAuthors returns the primary authors line for a file, or an empty
+// string when disabled or the file has no git history
+func (p *Processor) formatAuthors(relPath string) string {
+	if !p.config.GitAuthors {
+		return ""
+	}
+
+	authors, err := utils.GitPrimaryAuthors(p.config.InputDir, relPath, maxGitAuthors)
+	if err != nil {
+		p.logger.LogDebug("Skipping authors for %s: %v", relPath, err)
+		return ""
+	}
+	if len(authors) == 0 {
+		return ""
 	}
 
-	// Final status
-	fmt.Println("\n✨ Process Complete")
-	fmt.Println("   ✅ Output files generated successfully")
+	return fmt.Sprintf("Primary authors: %s\n\n", strings.Join(authors, ", "))
+}
+
+func (p *Processor) updateStats(result FileResult) {
+	p.stats.mu.Lock()
+	defer p.stats.mu.Unlock()
+
+	p.stats.IncludedCount++
+	p.stats.IncludedFiles = append(p.stats.IncludedFiles, result.RelativePath)
+	if result.SymlinkTarget != "" {
+		p.stats.SymlinkCount++
+	} else if result.FileType != "text" {
+		p.stats.BinaryCount++
+	}
+	if result.EmbeddedBOM {
+		p.stats.EmbeddedBOMCount++
+	}
+	if result.Truncated {
+		p.stats.TruncatedCount++
+	}
+	if result.Summarized {
+		p.stats.SummarizedCount++
+	}
+	p.stats.BaselineTokens += result.BaselineTokens
+	p.stats.OutputTokens += result.Tokens
+	if result.Hash != "" {
+		if p.stats.FilesByHash == nil {
+			p.stats.FilesByHash = make(map[string][]string)
+		}
+		p.stats.FilesByHash[result.Hash] = append(p.stats.FilesByHash[result.Hash], result.RelativePath)
+	}
+	p.stats.TotalSize += result.Size
+}
+
+// printStats renders the run's stats report to stdout in the configured theme
+func (p *Processor) printStats() {
+	report := p.singleStatsReport()
+	if p.config.Split {
+		report = p.splitStatsReport()
+	}
+	renderStats(os.Stdout, report, p.config.Theme)
+}
+
+// duplicateGroups returns sorted groups of files sharing identical content
+func (p *Processor) duplicateGroups() [][]string {
+	var groups [][]string
+	for _, files := range p.stats.FilesByHash {
+		if len(files) < 2 {
+			continue
+		}
+		group := append([]string(nil), files...)
+		sort.Slice(group, func(i, j int) bool { return utils.NaturalLess(group[i], group[j]) })
+		groups = append(groups, group)
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return utils.NaturalLess(groups[i][0], groups[j][0]) })
+	return groups
 }
 
 func hasUTF8BOM(data []byte) bool {
 	return bytes.HasPrefix(data, utf8BOM)
 }
+
+// hasEmbeddedUTF8BOM reports whether data contains a BOM anywhere after its start
+func hasEmbeddedUTF8BOM(data []byte) bool {
+	if hasUTF8BOM(data) {
+		data = data[len(utf8BOM):]
+	}
+	return bytes.Contains(data, utf8BOM)
+}
+
+// stripUTF8BOMs removes every BOM from already decoded content
+func stripUTF8BOMs(content string) string {
+	return strings.ReplaceAll(content, string(utf8BOM), "")
+}
@@ -0,0 +1,1538 @@
+package processor
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/richardamare/ai-digest/internal/utils"
+)
+
+// digestFixture creates an input directory from files and returns the
+// config for a single-file digest into a separate output directory
+func digestFixture(t *testing.T, files map[string]string) ProcessorConfig {
+	t.Helper()
+	h := utils.NewTestHelper(t)
+	t.Cleanup(h.Cleanup)
+
+	input := h.CreateTempDir("src")
+	for name, content := range files {
+		h.CreateTempFile(filepath.Join("src", name), content)
+	}
+
+	return ProcessorConfig{
+		InputDir:   input,
+		OutputFile: filepath.Join(h.CreateTempDir("out"), "digest.md"),
+		IgnoreFile: ".aidigestignore",
+		OutputMode: 0644,
+	}
+}
+
+// runDigest digests cfg and returns the output file's contents
+func runDigest(t *testing.T, cfg ProcessorConfig) string {
+	t.Helper()
+	runProcessor(t, cfg)
+	data, err := os.ReadFile(cfg.OutputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// processDigest runs a processor for cfg and returns it with the Process
+// error, for tests that inspect stats or expect the run to fail
+func processDigest(t *testing.T, cfg ProcessorConfig) (*Processor, error) {
+	t.Helper()
+	p, err := NewProcessor(cfg)
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	return p, p.Process(context.Background())
+}
+
+// commitFixture turns the input directory of cfg into a git repository
+// with everything committed
+func commitFixture(t *testing.T, cfg ProcessorConfig) *utils.TestHelper {
+	t.Helper()
+	h := utils.NewTestHelper(t)
+	t.Cleanup(h.Cleanup)
+	h.Git(cfg.InputDir, "init", "--quiet", "--initial-branch=main")
+	h.Git(cfg.InputDir, "add", ".")
+	h.Git(cfg.InputDir, "commit", "--quiet", "-m", "initial")
+	return h
+}
+
+// stdinFrom replaces os.Stdin with a pipe holding content for the rest of the test
+func stdinFrom(t *testing.T, content string) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	stdin := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() {
+		os.Stdin = stdin
+		r.Close()
+	})
+}
+
+// fileHeaders returns the file paths of the "# path" headers in a digest
+func fileHeaders(digest string) []string {
+	var paths []string
+	for _, line := range strings.Split(digest, "\n") {
+		if path, ok := strings.CutPrefix(line, "# "); ok && strings.Contains(path, ".") {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+func TestResultsDocumentRoundTrip(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{"a.go": "package a\n", "b.txt": "hello\n"})
+	cfg.ResultsFile = filepath.Join(filepath.Dir(cfg.OutputFile), "results.json")
+	cfg.ResultsContent = true
+
+	runDigest(t, cfg)
+
+	f, err := os.Open(cfg.ResultsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	doc, err := ReadResultsDocument(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Results) != 2 {
+		t.Fatalf("got %d results, want 2", len(doc.Results))
+	}
+	for i, result := range doc.Results {
+		if result.Index != i+1 || result.Content == "" || result.Header == "" {
+			t.Errorf("result %d = %+v, want index %d with content and header", i, result, i+1)
+		}
+	}
+}
+
+func TestTestFileSelection(t *testing.T) {
+	files := map[string]string{
+		"parser.go":          "package p\n",
+		"parser_test.go":     "package p\n",
+		"lexer.go":           "package p\n",
+		"web/app.ts":         "export {}\n",
+		"web/app.spec.ts":    "export {}\n",
+		"tests/test_cli.py":  "pass\n",
+		"docs/guide.md":      "guide\n",
+		"fixtures/sample.go": "package fixtures\n",
+	}
+
+	tests := []struct {
+		name  string
+		setup func(*ProcessorConfig)
+		want  []string
+	}{
+		{
+			name:  "exclude tests",
+			setup: func(c *ProcessorConfig) { c.ExcludeTests = true },
+			want:  []string{"docs/guide.md", "fixtures/sample.go", "lexer.go", "parser.go", "web/app.ts"},
+		},
+		{
+			name:  "only tests",
+			setup: func(c *ProcessorConfig) { c.OnlyTests = true },
+			want:  []string{"parser_test.go", "tests/test_cli.py", "web/app.spec.ts"},
+		},
+		{
+			name:  "only tests with tested source",
+			setup: func(c *ProcessorConfig) { c.OnlyTests, c.WithTestedSource = true, true },
+			want:  []string{"parser.go", "parser_test.go", "tests/test_cli.py", "web/app.spec.ts", "web/app.ts"},
+		},
+		{
+			name: "extra test pattern",
+			setup: func(c *ProcessorConfig) {
+				c.ExcludeTests = true
+				c.TestPatterns = []string{"fixtures/"}
+			},
+			want: []string{"docs/guide.md", "lexer.go", "parser.go", "web/app.ts"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := digestFixture(t, files)
+			tt.setup(&cfg)
+
+			got := fileHeaders(runDigest(t, cfg))
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got files %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIgnoreFileOverridesDefaults(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{
+		".aidigestignore":   "!package-lock.json\n",
+		"main.go":           "package main\n",
+		"package-lock.json": "{}\n",
+		"yarn.lock":         "# yarn\n",
+	})
+	cfg.UseDefaultIgnores = true
+
+	got := fileHeaders(runDigest(t, cfg))
+	if want := []string{".aidigestignore", "main.go", "package-lock.json"}; !slices.Equal(got, want) {
+		t.Errorf("digested files = %q, want %q", got, want)
+	}
+}
+
+func TestHashAlgo(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{"hello.txt": "hello\n"})
+	cfg.HashHeaders = true
+	cfg.HashAlgo = "fnv64a"
+
+	if digest := runDigest(t, cfg); !strings.Contains(digest, "# hello.txt [fnv64a:a9bc80cca21f]") {
+		t.Errorf("digest lacks the fnv64a header hash:\n%s", digest)
+	}
+
+	cfg.HashAlgo = "crc32"
+	if _, err := NewProcessor(cfg); err == nil {
+		t.Error("NewProcessor with an unknown hash algorithm succeeded")
+	}
+}
+
+func TestSinceLastRun(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{"a.go": "package a\n", "b.go": "package b\n"})
+	cfg.SinceLastRun = true
+	cfg.StateFile = filepath.Join(filepath.Dir(cfg.OutputFile), "state.json")
+
+	if got, want := fileHeaders(runDigest(t, cfg)), []string{"a.go", "b.go"}; !slices.Equal(got, want) {
+		t.Fatalf("first run digested %q, want %q", got, want)
+	}
+
+	modified := filepath.Join(cfg.InputDir, "b.go")
+	if err := os.WriteFile(modified, []byte("package b\n\nvar B = 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(modified, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	digest := runDigest(t, cfg)
+	if got, want := fileHeaders(digest), []string{"b.go"}; !slices.Equal(got, want) {
+		t.Fatalf("second run digested %q, want %q", got, want)
+	}
+	if !strings.Contains(digest, "var B = 1") {
+		t.Errorf("second run lacks the modified content:\n%s", digest)
+	}
+
+	if digest := runDigest(t, cfg); digest != "" {
+		t.Errorf("run without changes wrote %q, want an empty digest", digest)
+	}
+}
+
+func TestTypeLimits(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{
+		"small.log":     "ok\n",
+		"big.log":       strings.Repeat("x", 64) + "\n",
+		"main.go":       "package main\n",
+		"pkg/util.go":   "package pkg\n",
+		"pkg/notes.txt": "notes\n",
+	})
+	cfg.TypeLimits = map[string]TypeLimit{
+		".log": {MaxSize: 16},
+		".go":  {MaxDepth: 1},
+	}
+
+	p, err := NewProcessor(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Process(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(cfg.OutputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := fileHeaders(string(data)), []string{"main.go", "pkg/notes.txt", "small.log"}; !slices.Equal(got, want) {
+		t.Errorf("digested files = %q, want %q", got, want)
+	}
+	if p.stats.TypeLimitedCount != 2 {
+		t.Errorf("TypeLimitedCount = %d, want 2", p.stats.TypeLimitedCount)
+	}
+}
+
+func TestEmbeddedBOMModes(t *testing.T) {
+	files := map[string]string{"joined.txt": "first\n\ufeffsecond\n", "clean.txt": "\ufeffleading only\n"}
+
+	for _, mode := range []string{EmbeddedBOMWarn, EmbeddedBOMStrip, EmbeddedBOMFail} {
+		t.Run(mode, func(t *testing.T) {
+			cfg := digestFixture(t, files)
+			cfg.EmbeddedBOMMode = mode
+
+			p, err := processDigest(t, cfg)
+			if mode == EmbeddedBOMFail {
+				if err == nil || !strings.Contains(err.Error(), "joined.txt") {
+					t.Errorf("Process error = %v, want joined.txt reported", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.stats.EmbeddedBOMCount != 1 {
+				t.Errorf("EmbeddedBOMCount = %d, want 1", p.stats.EmbeddedBOMCount)
+			}
+
+			data, err := os.ReadFile(cfg.OutputFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kept := strings.Contains(string(data), "\ufeffsecond"); kept != (mode == EmbeddedBOMWarn) {
+				t.Errorf("embedded BOM kept = %v in %s mode", kept, mode)
+			}
+		})
+	}
+}
+
+func TestGitAuthors(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{"main.go": "package main\n"})
+	commitFixture(t, cfg)
+	cfg.GitAuthors = true
+
+	if digest := runDigest(t, cfg); !strings.Contains(digest, "# main.go\n\nPrimary authors: Test\n") {
+		t.Errorf("digest lacks the authors line:\n%s", digest)
+	}
+
+	cfg.GitAuthors = false
+	if digest := runDigest(t, cfg); strings.Contains(digest, "Primary authors") {
+		t.Errorf("digest has an authors line without GitAuthors:\n%s", digest)
+	}
+}
+
+func TestExcludeContent(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{
+		"gen.go":  "// Code generated by stringer. DO NOT EDIT.\npackage gen\n",
+		"main.go": "package main\n",
+		// Content filters only scan the head of each file
+		"late.go": "package late\n" + strings.Repeat("//\n", contentFilterScanSize/3+1) + "// DO NOT EDIT\n",
+	})
+	cfg.ExcludeContent = `DO NOT EDIT`
+
+	p, err := processDigest(t, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(cfg.OutputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := fileHeaders(string(data)), []string{"late.go", "main.go"}; !slices.Equal(got, want) {
+		t.Errorf("digested files = %q, want %q", got, want)
+	}
+	if p.stats.FilteredCount != 1 {
+		t.Errorf("FilteredCount = %d, want 1", p.stats.FilteredCount)
+	}
+
+	cfg.ExcludeContent = `(unclosed`
+	if _, err := NewProcessor(cfg); err == nil {
+		t.Error("NewProcessor with an invalid content regex succeeded")
+	}
+}
+
+func TestReportDuplicates(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{
+		"a.txt":   "same\n",
+		"b/a.txt": "same\n",
+		"c.txt":   "unique\n",
+		"d.txt":   "pair\n",
+		"e.txt":   "pair\n",
+	})
+	cfg.ReportDuplicates = true
+
+	p, err := processDigest(t, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := fmt.Sprintf("%q", p.duplicateGroups())
+	if want := `[["a.txt" "b/a.txt"] ["d.txt" "e.txt"]]`; got != want {
+		t.Errorf("duplicateGroups() = %s, want %s", got, want)
+	}
+
+	section := fmt.Sprint(p.duplicatesSection().Lines)
+	if !strings.Contains(section, "a.txt, b/a.txt") || !strings.Contains(section, "d.txt, e.txt") {
+		t.Errorf("duplicates section lacks the groups: %s", section)
+	}
+}
+
+func TestOutputMode(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{"main.go": "package main\n"})
+	// An existing output file is rewritten with the configured mode
+	if err := os.WriteFile(cfg.OutputFile, []byte("old"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	cfg.OutputMode = 0600
+
+	runDigest(t, cfg)
+
+	info, err := os.Stat(cfg.OutputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := info.Mode().Perm(); got != 0600 {
+		t.Errorf("output mode = %o, want 600", got)
+	}
+}
+
+func TestFileOrder(t *testing.T) {
+	files := map[string]string{
+		"file10.go":    "package a\n",
+		"file2.go":     "package a\n",
+		"README.md":    "# readme\n",
+		"docs/b.md":    "b\n",
+		"cmd/main.go":  "package main\n",
+		"Makefile.txt": "all:\n",
+	}
+
+	tests := []struct {
+		name       string
+		prioritize []string
+		want       []string
+	}{
+		{"natural order", nil, []string{"Makefile.txt", "README.md", "cmd/main.go", "docs/b.md", "file2.go", "file10.go"}},
+		{"prioritized in pattern order", []string{"README.md", "cmd/*"}, []string{"README.md", "cmd/main.go", "Makefile.txt", "docs/b.md", "file2.go", "file10.go"}},
+		{"unmatched pattern", []string{"*.rs"}, []string{"Makefile.txt", "README.md", "cmd/main.go", "docs/b.md", "file2.go", "file10.go"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := digestFixture(t, files)
+			cfg.Prioritize = tt.prioritize
+			if got := fileHeaders(runDigest(t, cfg)); !slices.Equal(got, tt.want) {
+				t.Errorf("digested files = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBinaryDescriptionHasMIMEType(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{"logo.png": "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"})
+
+	if digest := runDigest(t, cfg); !strings.Contains(digest, "(image/png)") {
+		t.Errorf("binary description lacks the MIME type:\n%s", digest)
+	}
+}
+
+func TestParentIgnores(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{
+		"main.go":           "package main\n",
+		"app.log":           "log\n",
+		"secret.txt":        "secret\n",
+		"nested/secret.txt": "not anchored here\n",
+	})
+	parent := filepath.Dir(cfg.InputDir)
+	if err := os.WriteFile(filepath.Join(parent, ".gitignore"), []byte("*.log\n/src/secret.txt\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg.IgnoreCeiling = parent
+
+	if got, want := fileHeaders(runDigest(t, cfg)), []string{"app.log", "main.go", "nested/secret.txt", "secret.txt"}; !slices.Equal(got, want) {
+		t.Errorf("without ParentIgnores digested %q, want %q", got, want)
+	}
+
+	cfg.ParentIgnores = true
+	if got, want := fileHeaders(runDigest(t, cfg)), []string{"main.go", "nested/secret.txt"}; !slices.Equal(got, want) {
+		t.Errorf("with ParentIgnores digested %q, want %q", got, want)
+	}
+}
+
+func TestHeaderMetadata(t *testing.T) {
+	const text = "hello metadata, these are a few words\n"
+	cfg := digestFixture(t, map[string]string{
+		"hello.txt": text,
+		"logo.png":  "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR",
+	})
+	cfg.HeaderMetadata = true
+
+	digest := runDigest(t, cfg)
+	for _, want := range []string{
+		fmt.Sprintf("# hello.txt (%d B, ~%d tokens)\n", len(text), utils.EstimateTokenCount(text)),
+		"# logo.png (16 B)\n",
+	} {
+		if !strings.Contains(digest, want) {
+			t.Errorf("digest lacks header %q:\n%s", want, digest)
+		}
+	}
+
+	cfg.HeaderMetadata = false
+	if digest := runDigest(t, cfg); !strings.Contains(digest, "# hello.txt\n") {
+		t.Errorf("header annotated without HeaderMetadata:\n%s", digest)
+	}
+}
+
+func TestPatternsFromStdin(t *testing.T) {
+	files := map[string]string{
+		"main.go":      "package main\n",
+		"main_test.go": "package main\n",
+		"app.log":      "log\n",
+		"notes.txt":    "notes\n",
+	}
+
+	t.Run("ignore file", func(t *testing.T) {
+		cfg := digestFixture(t, files)
+		cfg.IgnoreFile = utils.StdinPatternSource
+		stdinFrom(t, "*.log\n*_test.go\n")
+
+		if got, want := fileHeaders(runDigest(t, cfg)), []string{"main.go", "notes.txt"}; !slices.Equal(got, want) {
+			t.Errorf("digested files = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("include file", func(t *testing.T) {
+		cfg := digestFixture(t, files)
+		cfg.IncludeFile = utils.StdinPatternSource
+		stdinFrom(t, "*.go\n")
+
+		if got, want := fileHeaders(runDigest(t, cfg)), []string{"main.go", "main_test.go"}; !slices.Equal(got, want) {
+			t.Errorf("digested files = %q, want %q", got, want)
+		}
+	})
+}
+
+func TestMarkdownMode(t *testing.T) {
+	// Raw mode keeps its fixed four-backtick fence even though the nested
+	// fence closes it early; fenced mode picks a longer one
+	const readme = "# Title\n\n````go\nfmt.Println()\n````\n"
+
+	tests := []struct {
+		mode string
+		want string
+	}{
+		{MarkdownModeRaw, "````md\n# Title\n\n````go\nfmt.Println()\n````\n\n````\n"},
+		{MarkdownModeFenced, "`````md\n# Title\n\n````go\nfmt.Println()\n````\n\n`````\n"},
+		{MarkdownModeEscape, "```md\n# Title\n\n\\`\\`\\``go\nfmt.Println()\n\\`\\`\\``\n\n```\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			cfg := digestFixture(t, map[string]string{"README.md": readme})
+			cfg.MarkdownMode = tt.mode
+
+			if digest := runDigest(t, cfg); !strings.Contains(digest, "# README.md\n\n"+tt.want) {
+				t.Errorf("digest does not wrap README.md as %q:\n%s", tt.want, digest)
+			}
+		})
+	}
+}
+
+func TestCompactBinaries(t *testing.T) {
+	const png = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+	cfg := digestFixture(t, map[string]string{
+		"b.png":   png,
+		"a|b.png": png,
+		"main.go": "package main\n",
+	})
+	cfg.CompactBinaries = true
+
+	digest := runDigest(t, cfg)
+	want := "# main.go\n\n```go\npackage main\n\n```\n\n" +
+		"# Binary files\n\n| Path | Type |\n| --- | --- |\n" +
+		"| a\\|b.png | Image (image/png) |\n| b.png | Image (image/png) |\n"
+	if !strings.HasPrefix(digest, want) {
+		t.Errorf("digest = %q, want binaries listed in one table after the text files", digest)
+	}
+}
+
+func TestOnlyTracked(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{"main.go": "package main\n", "pkg/lib.go": "package pkg\n"})
+	commitFixture(t, cfg)
+	for name, content := range map[string]string{"scratch.go": "package main\n", "pkg/wip.go": "package pkg\n"} {
+		if err := os.WriteFile(filepath.Join(cfg.InputDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg.OnlyTracked = true
+
+	if got, want := fileHeaders(runDigest(t, cfg)), []string{"main.go", "pkg/lib.go"}; !slices.Equal(got, want) {
+		t.Errorf("digested files = %q, want %q", got, want)
+	}
+
+	cfg.InputDir = t.TempDir()
+	if _, err := NewProcessor(cfg); err == nil {
+		t.Error("NewProcessor with OnlyTracked outside a repository succeeded")
+	}
+}
+
+func TestOutputEOL(t *testing.T) {
+	files := map[string]string{"unix.txt": "one\ntwo\n", "windows.txt": "three\r\nfour\r\n"}
+
+	tests := []struct {
+		eol  string
+		want string
+	}{
+		{"", "# unix.txt\n\n```txt\none\ntwo\n\n```\n\n# windows.txt\n\n```txt\nthree\r\nfour\r\n\n```\n\n"},
+		{utils.LineEndingLF, "# unix.txt\n\n```txt\none\ntwo\n\n```\n\n# windows.txt\n\n```txt\nthree\nfour\n\n```\n\n"},
+		{utils.LineEndingCRLF, "# unix.txt\r\n\r\n```txt\r\none\r\ntwo\r\n\r\n```\r\n\r\n# windows.txt\r\n\r\n```txt\r\nthree\r\nfour\r\n\r\n```\r\n\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("eol=%q", tt.eol), func(t *testing.T) {
+			cfg := digestFixture(t, files)
+			cfg.OutputEOL = tt.eol
+			if got := runDigest(t, cfg); got != tt.want {
+				t.Errorf("digest = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIgnoredDirectoriesAreSkipped(t *testing.T) {
+	files := map[string]string{
+		"main.go":                   "package main\n",
+		"node_modules/react/a.js":   "a\n",
+		"build/out.js":              "out\n",
+		"dist/app.js":               "app\n",
+		"dist/README.md":            "readme\n",
+		"src/node_modules_helper.c": "int x;\n",
+	}
+
+	tests := []struct {
+		name        string
+		ignore      string
+		want        []string
+		wantSkipped int
+	}{
+		{"no negations", "build/\ndist\n", []string{".aidigestignore", "main.go", "src/node_modules_helper.c"}, 3},
+		// The negation may re-include files anywhere, so every directory is visited
+		{"negation", "build/\ndist\n!*.md\n", []string{".aidigestignore", "dist/README.md", "main.go", "src/node_modules_helper.c"}, 0},
+		{"anchored negation", "build/\ndist\n!/dist/README.md\n", []string{".aidigestignore", "dist/README.md", "main.go", "src/node_modules_helper.c"}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := digestFixture(t, files)
+			if err := os.WriteFile(filepath.Join(cfg.InputDir, ".aidigestignore"), []byte(tt.ignore), 0644); err != nil {
+				t.Fatal(err)
+			}
+			cfg.UseDefaultIgnores = true
+
+			p, err := processDigest(t, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(cfg.OutputFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := fileHeaders(string(data)); !slices.Equal(got, tt.want) {
+				t.Errorf("digested files = %q, want %q", got, tt.want)
+			}
+			if p.stats.SkippedDirCount != tt.wantSkipped {
+				t.Errorf("SkippedDirCount = %d, want %d", p.stats.SkippedDirCount, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestCollectTodos(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{
+		"main.go":   "package main\n\n// TODO: handle errors\n",
+		"b/util.py": "# FIXME slow\n",
+		"logo.png":  "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR TODO",
+	})
+	cfg.CollectTodos = true
+
+	want := "# TODO Summary\n\n- `b/util.py:1` FIXME: slow\n- `main.go:3` TODO: handle errors\n\n# b/util.py\n"
+	if digest := runDigest(t, cfg); !strings.HasPrefix(digest, want) {
+		t.Errorf("digest does not start with the TODO summary:\n%s", digest)
+	}
+
+	cfg = digestFixture(t, map[string]string{"main.go": "package main\n"})
+	cfg.CollectTodos = true
+	if digest := runDigest(t, cfg); !strings.HasPrefix(digest, "# TODO Summary\n\nNo TODO, FIXME or HACK markers found.\n") {
+		t.Errorf("digest lacks the empty summary:\n%s", digest)
+	}
+}
+
+func TestEnvExampleIncludes(t *testing.T) {
+	files := map[string]string{
+		".env":                    "SECRET=1\n",
+		".env.local":              "SECRET=2\n",
+		".env.example":            "SECRET=\n",
+		"config/.env.sample":      "SECRET=\n",
+		"deploy/prod.env.example": "SECRET=\n",
+		"main.go":                 "package main\n",
+	}
+
+	cfg := digestFixture(t, files)
+	cfg.UseDefaultIgnores = true
+	if got, want := fileHeaders(runDigest(t, cfg)), []string{"main.go"}; !slices.Equal(got, want) {
+		t.Errorf("without the includes digested %q, want %q", got, want)
+	}
+
+	cfg.ExtraIgnores = utils.EnvExampleIncludes
+	want := []string{".env.example", "config/.env.sample", "deploy/prod.env.example", "main.go"}
+	if got := fileHeaders(runDigest(t, cfg)); !slices.Equal(got, want) {
+		t.Errorf("with the includes digested %q, want %q", got, want)
+	}
+
+	// The ignore file still wins over the includes
+	if err := os.WriteFile(filepath.Join(cfg.InputDir, ".aidigestignore"), []byte("deploy/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{".aidigestignore", ".env.example", "config/.env.sample", "main.go"}
+	if got := fileHeaders(runDigest(t, cfg)); !slices.Equal(got, want) {
+		t.Errorf("with an ignore file digested %q, want %q", got, want)
+	}
+}
+
+func TestRunDeadline(t *testing.T) {
+	expired, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		wantErr error
+	}{
+		{"deadline", expired, ErrTimeout},
+		{"canceled", canceled, context.Canceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := digestFixture(t, map[string]string{"main.go": "package main\n"})
+			p, err := NewProcessor(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := p.Process(tt.ctx); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Process() error = %v, want %v", err, tt.wantErr)
+			}
+			// Partial output is kept
+			if _, err := os.Stat(cfg.OutputFile); err != nil {
+				t.Errorf("output removed after the run stopped: %v", err)
+			}
+		})
+	}
+}
+
+func TestSkipGeneratedHeader(t *testing.T) {
+	files := map[string]string{
+		"main.go":   "package main\n",
+		"api.pb.go": "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage api\n",
+		"late.go":   "package late\n\n\n\n\n// Code generated by hand. DO NOT EDIT.\n",
+	}
+
+	for _, skip := range []bool{false, true} {
+		t.Run(fmt.Sprintf("skip=%v", skip), func(t *testing.T) {
+			cfg := digestFixture(t, files)
+			cfg.SkipGeneratedHeader = skip
+
+			p, err := processDigest(t, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(cfg.OutputFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Markers past the first few lines don't count
+			included := slices.Contains(fileHeaders(string(data)), "api.pb.go")
+			if included == skip || !strings.Contains(string(data), "# late.go\n") {
+				t.Errorf("generated file included = %v with SkipGeneratedHeader %v:\n%s", included, skip, data)
+			}
+			if want := map[bool]int{false: 0, true: 1}[skip]; p.stats.GeneratedCount != want {
+				t.Errorf("GeneratedCount = %d, want %d", p.stats.GeneratedCount, want)
+			}
+		})
+	}
+}
+
+func TestCompareBaseline(t *testing.T) {
+	files := map[string]string{"main.go": "package main" + strings.Repeat(" ", 40) + "\n\nfunc main() {}" + strings.Repeat(" ", 40) + "\n"}
+
+	cfg := digestFixture(t, files)
+	cfg.CompareBaseline = true
+	p, err := processDigest(t, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.stats.BaselineTokens == 0 || p.stats.OutputTokens != p.stats.BaselineTokens {
+		t.Errorf("without transforms baseline %d tokens, output %d, want equal", p.stats.BaselineTokens, p.stats.OutputTokens)
+	}
+
+	cfg = digestFixture(t, files)
+	cfg.CompareBaseline = true
+	cfg.StripTrailingWS = true
+	if p, err = processDigest(t, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if p.stats.OutputTokens >= p.stats.BaselineTokens {
+		t.Errorf("baseline %d tokens, output %d, want transforms to save tokens", p.stats.BaselineTokens, p.stats.OutputTokens)
+	}
+}
+
+func TestPromoteFrontMatter(t *testing.T) {
+	const guide = "---\ntitle: Getting Started\n---\n# Install\n"
+
+	tests := []struct {
+		name    string
+		promote bool
+		strip   bool
+		want    string
+	}{
+		{"off", false, false, "# guide.md\n\n````md\n" + guide},
+		{"promoted", true, false, "# guide.md" + utils.TitleSeparator + "Getting Started\n\n````md\n" + guide},
+		{"stripped", true, true, "# guide.md" + utils.TitleSeparator + "Getting Started\n\n````md\n# Install\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := digestFixture(t, map[string]string{"guide.md": guide})
+			cfg.PromoteFrontMatter = tt.promote
+			cfg.StripFrontMatter = tt.strip
+
+			if digest := runDigest(t, cfg); !strings.HasPrefix(digest, tt.want) {
+				t.Errorf("digest = %q, want prefix %q", digest, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommitRange(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{
+		"a.go":    "package a\n",
+		"b.go":    "package b\n",
+		"c.go":    "package c\n",
+		"old.log": "log\n",
+	})
+	h := commitFixture(t, cfg)
+	if err := os.WriteFile(filepath.Join(cfg.InputDir, "b.go"), []byte("package b\n\nvar B = 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cfg.InputDir, "d.go"), []byte("package d\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	h.Git(cfg.InputDir, "rm", "--quiet", "c.go", "old.log")
+	h.Git(cfg.InputDir, "add", ".")
+	h.Git(cfg.InputDir, "commit", "--quiet", "-m", "change")
+
+	cfg.CommitRange = "HEAD~1..HEAD"
+	cfg.ExtraIgnores = []string{"*.log"}
+
+	digest := runDigest(t, cfg)
+	if got, want := fileHeaders(digest), []string{"b.go", "d.go", "Deleted in HEAD~1..HEAD"}; !slices.Equal(got, want) {
+		t.Errorf("digest headers = %q, want %q", got, want)
+	}
+	// Deleted files are listed after the others unless ignored
+	if !strings.Contains(digest, "# Deleted in HEAD~1..HEAD\n\n- c.go\n\n") {
+		t.Errorf("digest lacks the deleted files list:\n%s", digest)
+	}
+
+	cfg.CommitRange = "no-such-ref..HEAD"
+	if _, err := NewProcessor(cfg); err == nil {
+		t.Error("NewProcessor with an unknown commit range succeeded")
+	}
+}
+
+func TestMaxReadBytesPerSec(t *testing.T) {
+	files := make(map[string]string)
+	for i := range 4 {
+		files[fmt.Sprintf("file%d.txt", i)] = strings.Repeat("x", 999) + "\n"
+	}
+	cfg := digestFixture(t, files)
+	cfg.MaxReadBytesPerSec = 10000
+
+	// 4000 bytes fit the initial one-second bucket, so the run isn't slowed
+	start := time.Now()
+	runDigest(t, cfg)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("run within the bucket took %v", elapsed)
+	}
+
+	// At 2000 B/s, reading 4000 bytes has to wait about a second past the bucket
+	cfg.MaxReadBytesPerSec = 2000
+	start = time.Now()
+	runDigest(t, cfg)
+	if elapsed := time.Since(start); elapsed < 800*time.Millisecond {
+		t.Errorf("throttled run took %v, want about 1s", elapsed)
+	}
+}
+
+func TestFormatTitle(t *testing.T) {
+	if got := formatTitle(""); got != "" {
+		t.Errorf("formatTitle(\"\") = %q, want empty", got)
+	}
+	if got, want := formatTitle("Setup"), " — Setup"; got != want {
+		t.Errorf("formatTitle() = %q, want %q", got, want)
+	}
+}
+
+// ageFixture backdates each file in the input directory by its age in days
+func ageFixture(t *testing.T, cfg ProcessorConfig, ages map[string]int) {
+	t.Helper()
+	for name, days := range ages {
+		mtime := time.Now().Add(-time.Duration(days) * 24 * time.Hour)
+		if err := os.Chtimes(filepath.Join(cfg.InputDir, name), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMaxAge(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{"new.txt": "new\n", "old.txt": "old\n"})
+	ageFixture(t, cfg, map[string]int{"new.txt": 1, "old.txt": 60})
+	cfg.MaxAge = 30 * 24 * time.Hour
+
+	p, err := processDigest(t, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest, err := os.ReadFile(cfg.OutputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fileHeaders(string(digest)); !slices.Equal(got, []string{"new.txt"}) {
+		t.Errorf("headers = %q, want only new.txt", got)
+	}
+	if p.stats.AgeFilteredCount != 1 {
+		t.Errorf("AgeFilteredCount = %d, want 1", p.stats.AgeFilteredCount)
+	}
+}
+
+func TestGroupByAge(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{"a.txt": "a\n", "b.txt": "b\n", "c.txt": "c\n", "d.txt": "d\n"})
+	ageFixture(t, cfg, map[string]int{"a.txt": 200, "b.txt": 5, "c.txt": 60, "d.txt": 10})
+	cfg.GroupByAge = true
+	digest := runDigest(t, cfg)
+
+	var got []string
+	for _, line := range strings.Split(digest, "\n") {
+		if strings.HasPrefix(line, "# ") {
+			got = append(got, line)
+		}
+	}
+	want := []string{
+		"# Modified in the last 30 days", "# b.txt", "# d.txt",
+		"# Modified in the last 90 days", "# c.txt",
+		"# Older", "# a.txt",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("headings = %q, want %q", got, want)
+	}
+}
+
+func TestSingleFileInput(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{
+		"pkg/main.go":  "package main\n",
+		"pkg/other.go": "package main\n",
+	})
+	cfg.InputDir = filepath.Join(cfg.InputDir, "pkg", "main.go")
+	digest := runDigest(t, cfg)
+
+	if got := fileHeaders(digest); !slices.Equal(got, []string{"main.go"}) {
+		t.Errorf("headers = %q, want only main.go", got)
+	}
+}
+
+func TestLanguageOverrides(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{"include/util.h": "int f();\n"})
+	cfg.LanguageOverrides = []utils.LanguageOverride{{Pattern: ".h", Language: "cpp"}}
+	digest := runDigest(t, cfg)
+
+	if !strings.Contains(digest, "```cpp\nint f();\n") {
+		t.Errorf("override language not used for the fence:\n%s", digest)
+	}
+}
+
+func TestHardLimitTokens(t *testing.T) {
+	body := strings.Repeat("word ", 80) + "\n"
+	cfg := digestFixture(t, map[string]string{"a.txt": body, "b.txt": body})
+	cfg.HardLimitTokens = utils.EstimateTokenCount(body) + 50
+
+	if _, err := processDigest(t, cfg); !errors.Is(err, ErrTokenLimit) {
+		t.Fatalf("Process() error = %v, want ErrTokenLimit", err)
+	}
+
+	// Blocks written before the limit are kept
+	digest, err := os.ReadFile(cfg.OutputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fileHeaders(string(digest)); !slices.Equal(got, []string{"a.txt"}) {
+		t.Errorf("headers = %q, want only a.txt", got)
+	}
+}
+
+func TestFileList(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{"a.txt": "a\n", "b.txt": "b\n", "sub/c.txt": "c\n"})
+	cfg.UseFileList = true
+	cfg.FileList = []string{"sub/c.txt", "missing.txt", "sub", filepath.Join(cfg.InputDir, "a.txt"), "a.txt"}
+	digest := runDigest(t, cfg)
+
+	// Listed order is kept; missing paths, directories and repeats are skipped
+	if got := fileHeaders(digest); !slices.Equal(got, []string{"sub/c.txt", "a.txt"}) {
+		t.Errorf("headers = %q, want sub/c.txt then a.txt", got)
+	}
+}
+
+func TestSummarizeOver(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{
+		"big.go":   strings.Repeat("x := 1\n", 100),
+		"small.go": "package small\n",
+	})
+	cfg.SummarizeOver = 50
+
+	p, err := processDigest(t, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest, err := os.ReadFile(cfg.OutputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(digest), "# big.go\n\n```go\n[summarized: 101 lines") {
+		t.Errorf("big.go was not summarized:\n%s", digest)
+	}
+	if !strings.Contains(string(digest), "```go\npackage small\n") {
+		t.Errorf("small.go should be kept whole:\n%s", digest)
+	}
+	if p.stats.SummarizedCount != 1 {
+		t.Errorf("SummarizedCount = %d, want 1", p.stats.SummarizedCount)
+	}
+}
+
+func TestRespectEditorConfig(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{
+		".editorconfig": "root = true\n\n[*.go]\ntrim_trailing_whitespace = true\n",
+		"main.go":       "package main   \n",
+		"notes.txt":     "keep   \n",
+	})
+	cfg.RespectEditorConfig = true
+	digest := runDigest(t, cfg)
+
+	if !strings.Contains(digest, "```go\npackage main\n") {
+		t.Errorf("main.go was not trimmed:\n%s", digest)
+	}
+	if !strings.Contains(digest, "keep   \n") {
+		t.Errorf("notes.txt should be untouched:\n%s", digest)
+	}
+}
+
+func TestOrderFile(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{"a.txt": "a\n", "b.txt": "b\n", "c.txt": "c\n", "sub/d.txt": "d\n"})
+	cfg.OrderFile = filepath.Join(filepath.Dir(cfg.OutputFile), "order.txt")
+	if err := os.WriteFile(cfg.OrderFile, []byte("./sub/d.txt\nmissing.txt\nc.txt\nsub/d.txt\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	digest := runDigest(t, cfg)
+
+	// Listed files come first; the rest keep their usual order
+	want := []string{"sub/d.txt", "c.txt", "a.txt", "b.txt"}
+	if got := fileHeaders(digest); !slices.Equal(got, want) {
+		t.Errorf("headers = %q, want %q", got, want)
+	}
+}
+
+func TestDuplicateHeaders(t *testing.T) {
+	files := map[string]string{"a.go": "package a\n", "b.go": "package b\n"}
+
+	cfg := digestFixture(t, files)
+	cfg.HeaderFormat = "# {lang}"
+	cfg.DuplicateHeaders = DuplicateHeadersDisambiguate
+	digest := runDigest(t, cfg)
+	if !strings.Contains(digest, "# go\n\n```go\npackage a\n") || !strings.Contains(digest, "# go [b.go]\n\n```go\npackage b\n") {
+		t.Errorf("repeated header was not disambiguated:\n%s", digest)
+	}
+
+	cfg = digestFixture(t, files)
+	cfg.HeaderFormat = "# {lang}"
+	cfg.DuplicateHeaders = DuplicateHeadersFail
+	if _, err := processDigest(t, cfg); err == nil || !strings.Contains(err.Error(), "same header") {
+		t.Errorf("Process() error = %v, want a duplicate header error", err)
+	}
+}
+
+func TestConcurrencyOverride(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{"a.txt": "a\n"})
+	cfg.Concurrency = 3
+	p, err := NewProcessor(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.workers != 3 {
+		t.Errorf("workers = %d, want the --concurrency override", p.workers)
+	}
+
+	cfg.Concurrency = 0
+	if p, err = NewProcessor(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if p.workers <= 0 {
+		t.Errorf("workers = %d, want a level picked from the filesystem", p.workers)
+	}
+}
+
+func TestGitHeader(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{"a.txt": "a\n"})
+	cfg.GitHeader = true
+	cfg.ExtraIgnores = []string{".git/"}
+	h := commitFixture(t, cfg)
+	sha := strings.TrimSpace(h.Git(cfg.InputDir, "rev-parse", "--short", "HEAD"))
+	branch := strings.TrimSpace(h.Git(cfg.InputDir, "symbolic-ref", "--short", "HEAD"))
+	digest := runDigest(t, cfg)
+
+	want := fmt.Sprintf("# Git\n\n- Branch: %s\n- Commit: %s\n\n# a.txt", branch, sha)
+	if !strings.HasPrefix(digest, want) {
+		t.Errorf("digest does not start with the git header:\n%s", digest)
+	}
+
+	if got := formatGitHeader(utils.GitInfo{Commit: "abc1234", Remote: "https://example.com/r.git"}); got != "# Git\n\n- Branch: (detached HEAD)\n- Commit: abc1234\n- Remote: https://example.com/r.git\n\n" {
+		t.Errorf("formatGitHeader() = %q", got)
+	}
+}
+
+func TestWrapBase64(t *testing.T) {
+	data := []byte(strings.Repeat("binary", 20))
+	got := wrapBase64(data)
+
+	lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	if len(lines) != 3 || len(lines[0]) != 76 || len(lines[1]) != 76 {
+		t.Errorf("wrapBase64() lines = %q, want 76-character lines", lines)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(got, "\n", ""))
+	if err != nil || string(decoded) != string(data) {
+		t.Errorf("wrapBase64() does not round-trip: %v", err)
+	}
+	if wrapBase64(nil) != "" {
+		t.Error("wrapBase64(nil) should be empty")
+	}
+}
+
+func TestMaxBinarySize(t *testing.T) {
+	small := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+	cfg := digestFixture(t, map[string]string{
+		"small.png": small,
+		"large.png": small + strings.Repeat("\x00", 100),
+	})
+	cfg.MaxBinarySize = int64(len(small))
+	digest := runDigest(t, cfg)
+
+	embedded := "```base64\n" + base64.StdEncoding.EncodeToString([]byte(small)) + "\n```"
+	if !strings.Contains(digest, embedded) {
+		t.Errorf("small.png was not embedded:\n%s", digest)
+	}
+	if strings.Count(digest, "```base64") != 1 {
+		t.Errorf("large.png should not be embedded:\n%s", digest)
+	}
+}
+
+func TestNoteSymlinks(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{"a.txt": "a\n", "sub/keep.txt": "k\n"})
+	outside := filepath.Join(filepath.Dir(cfg.OutputFile), "outside.txt")
+	links := map[string]string{
+		"rel.txt":     "a.txt",
+		"sub/abs.txt": filepath.Join(cfg.InputDir, "a.txt"),
+		"out.txt":     outside,
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, filepath.Join(cfg.InputDir, link)); err != nil {
+			t.Skipf("symlinks unsupported: %v", err)
+		}
+	}
+	cfg.NoteSymlinks = true
+
+	p, err := processDigest(t, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest, err := os.ReadFile(cfg.OutputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Absolute targets inside the input directory are made relative to the link
+	for _, want := range []string{
+		"# rel.txt\n\nThis is a symbolic link to: a.txt\n",
+		"# sub/abs.txt\n\nThis is a symbolic link to: ../a.txt\n",
+		"# out.txt\n\nThis is a symbolic link to: " + outside + "\n",
+	} {
+		if !strings.Contains(string(digest), want) {
+			t.Errorf("digest missing %q:\n%s", want, digest)
+		}
+	}
+	if p.stats.SymlinkCount != 3 {
+		t.Errorf("SymlinkCount = %d, want 3", p.stats.SymlinkCount)
+	}
+}
+
+func TestResultsDocumentCompact(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{"a.go": "package a\n"})
+	cfg.ResultsFile = filepath.Join(filepath.Dir(cfg.OutputFile), "results.json")
+	cfg.JSONCompact = true
+	runDigest(t, cfg)
+
+	data, err := os.ReadFile(cfg.ResultsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(strings.TrimSuffix(string(data), "\n"), "\n"); lines != 0 {
+		t.Errorf("compact results span %d extra lines:\n%s", lines, data)
+	}
+	doc, err := ReadResultsDocument(strings.NewReader(string(data)))
+	if err != nil || len(doc.Results) != 1 {
+		t.Errorf("compact results do not round-trip: %v", err)
+	}
+}
+
+func TestHashHeaders(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{"a.txt": "hello\n", "b.txt": "hello   \n"})
+	cfg.HashAlgo = "sha256"
+	cfg.HashHeaders = true
+	cfg.StripTrailingWS = true
+	digest := runDigest(t, cfg)
+
+	// The hash covers the raw content, so b.txt differs from a.txt after trimming
+	if !strings.Contains(digest, "# a.txt [sha256:5891b5b522d5]\n") {
+		t.Errorf("a.txt header lacks its content hash:\n%s", digest)
+	}
+	if !strings.Contains(digest, "# b.txt [sha256:") || strings.Contains(digest, "# b.txt [sha256:5891b5b522d5]") {
+		t.Errorf("b.txt header should hash the untrimmed content:\n%s", digest)
+	}
+}
+
+func TestStrict(t *testing.T) {
+	files := map[string]string{"a.txt": "a\n", "bad.txt": "bad \xff byte\n"}
+
+	cfg := digestFixture(t, files)
+	digest := runDigest(t, cfg)
+	if got := fileHeaders(digest); !slices.Equal(got, []string{"a.txt"}) {
+		t.Errorf("headers = %q, want the invalid file skipped", got)
+	}
+
+	cfg = digestFixture(t, files)
+	cfg.Strict = true
+	if _, err := processDigest(t, cfg); err == nil || !strings.Contains(err.Error(), "failed to process bad.txt") {
+		t.Errorf("Process() error = %v, want bad.txt to fail the run", err)
+	}
+}
+
+func TestInstructionsPrecedeFiles(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{"main.go": "package main\n"})
+	cfg.Instructions = []string{"You are reviewing the following codebase.\nBe brief.", "  ", "Point out bugs first."}
+
+	digest := runDigest(t, cfg)
+
+	want := "> You are reviewing the following codebase.\n> Be brief.\n>\n> Point out bugs first.\n\n# main.go\n"
+	if !strings.HasPrefix(digest, want) {
+		t.Errorf("digest does not start with the preamble then the first file:\n%s", digest)
+	}
+}
+
+func TestJSONStreamWritesEveryResult(t *testing.T) {
+	files := make(map[string]string)
+	for i := range 200 {
+		files[fmt.Sprintf("pkg%d/file%03d.go", i%7, i)] = fmt.Sprintf("package pkg\n\nconst V = %d\n", i)
+	}
+	cfg := digestFixture(t, files)
+	cfg.JSONStream = true
+	cfg.Concurrency = 4
+
+	runProcessor(t, cfg)
+
+	f, err := os.Open(cfg.OutputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var fields map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &fields); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", scanner.Text(), err)
+		}
+		for _, key := range []string{"path", "index", "content", "header", "size"} {
+			if _, ok := fields[key]; !ok {
+				t.Errorf("line %q has no %q field", scanner.Text(), key)
+			}
+		}
+
+		var result FileResult
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		if result.Index < 1 || result.Index > len(files) {
+			t.Errorf("%s has index %d, want 1 to %d", result.RelativePath, result.Index, len(files))
+		}
+		if seen[result.RelativePath] {
+			t.Errorf("%s streamed twice", result.RelativePath)
+		}
+		seen[result.RelativePath] = true
+		if !strings.Contains(result.Content, "const V = ") {
+			t.Errorf("%s streamed without its content", result.RelativePath)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name := range files {
+		if !seen[filepath.ToSlash(name)] {
+			t.Errorf("%s missing from the stream", name)
+		}
+	}
+}
+
+func BenchmarkJSONStream(b *testing.B) {
+	h := utils.NewTestHelper(b)
+	defer h.Cleanup()
+
+	input := h.CreateTempDir("src")
+	for i := range 500 {
+		h.CreateTempFile(fmt.Sprintf("src/pkg%d/file%03d.go", i%10, i), strings.Repeat(fmt.Sprintf("const V%d = %d\n", i, i), 40))
+	}
+	cfg := ProcessorConfig{
+		InputDir:   input,
+		OutputFile: filepath.Join(h.CreateTempDir("out"), "digest.jsonl"),
+		IgnoreFile: ".aidigestignore",
+		OutputMode: 0644,
+		JSONStream: true,
+	}
+
+	b.ResetTimer()
+	for range b.N {
+		p, err := NewProcessor(cfg)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := p.Process(context.Background()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestEncodingNote(t *testing.T) {
+	utf16 := []byte{0xFF, 0xFE, 'h', 0, 'i', 0, '\n', 0}
+	cfg := digestFixture(t, map[string]string{"plain.txt": "hi\n", "bom.txt": "\ufeffhi\n", "wide.txt": string(utf16)})
+
+	digest := runDigest(t, cfg)
+
+	note := utils.FormatEncodingNote(utils.EncodingUTF16LE)
+	if !strings.Contains(digest, "# wide.txt"+note+"\n\n```txt\nhi\n") {
+		t.Errorf("transcoded file has no %q note or was not decoded:\n%s", note, digest)
+	}
+	for _, name := range []string{"plain.txt", "bom.txt"} {
+		if !strings.Contains(digest, "# "+name+"\n\n```txt\nhi\n") {
+			t.Errorf("UTF-8 file %s is annotated or keeps its BOM:\n%s", name, digest)
+		}
+	}
+}
+
+func TestBlockSeparator(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(*ProcessorConfig)
+		want  string
+	}{
+		{name: "zero value", setup: func(c *ProcessorConfig) {}, want: "```\n\n# b.go\n"},
+		{name: "three lines", setup: func(c *ProcessorConfig) { c.NewlinesBetweenFiles = 3 }, want: "```\n\n\n\n# b.go\n"},
+		{name: "none", setup: func(c *ProcessorConfig) { c.NewlinesBetweenFiles = NoNewlinesBetweenFiles }, want: "```\n# b.go\n"},
+		{name: "custom", setup: func(c *ProcessorConfig) { c.BlockSeparator = "---" }, want: "```\n\n---\n\n# b.go\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := digestFixture(t, map[string]string{"a.go": "package a\n", "b.go": "package b\n"})
+			tt.setup(&cfg)
+
+			if digest := runDigest(t, cfg); !strings.Contains(digest, tt.want) {
+				t.Errorf("digest has no %q between blocks:\n%s", tt.want, digest)
+			}
+		})
+	}
+}
+
+func TestFileTypeOverridesArePerProcessor(t *testing.T) {
+	files := map[string]string{"app.log": "started\n", "blob.dat": "raw\x00data\n"}
+	binaryLog := digestFixture(t, files)
+	binaryLog.TreatAsBinary = []string{".log"}
+	textDat := digestFixture(t, files)
+	textDat.TreatAsText = []string{"dat"}
+
+	// Both processors exist before either runs, so overrides set up by one
+	// must not leak into the other
+	first, err := NewProcessor(binaryLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := NewProcessor(textDat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []*Processor{first, second} {
+		if err := p.Process(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		cfg      ProcessorConfig
+		included []string
+		excluded []string
+	}{
+		{"log as binary", binaryLog, nil, []string{"started", "raw\x00data"}},
+		{"dat as text", textDat, []string{"started", "raw\x00data"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := os.ReadFile(tt.cfg.OutputFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			digest := string(data)
+			for _, s := range tt.included {
+				if !strings.Contains(digest, s) {
+					t.Errorf("digest is missing %q:\n%s", s, digest)
+				}
+			}
+			for _, s := range tt.excluded {
+				if strings.Contains(digest, s) {
+					t.Errorf("digest contains %q:\n%s", s, digest)
+				}
+			}
+		})
+	}
+}
+
+// codeBlocks returns the content of each fenced block in a digest. As in
+// CommonMark, a block ends at the first line of only backticks at least as
+// long as its opening fence.
+func codeBlocks(digest string) []string {
+	var blocks []string
+	lines := strings.Split(digest, "\n")
+	for i := 0; i < len(lines); i++ {
+		fence := lines[i][:len(lines[i])-len(strings.TrimLeft(lines[i], "`"))]
+		if len(fence) < 3 {
+			continue
+		}
+		end := i + 1
+		for end < len(lines) && !(strings.Trim(lines[end], "`") == "" && len(lines[end]) >= len(fence)) {
+			end++
+		}
+		blocks = append(blocks, strings.Join(lines[i+1:end], "\n"))
+		i = end
+	}
+	return blocks
+}
+
+func TestFencesSurviveEmbeddedFences(t *testing.T) {
+	files := map[string]string{
+		"a.py":   "\"\"\"Example:\n\n```\nx = 1\n```\n\"\"\"\n",
+		"b.txt":  "````\nnested ```` run with ``` inside\n````\n",
+		"c.md":   "# Doc\n\n````sh\nls\n````\n\n```go\nx\n```\n",
+		"d.json": "{\"fence\": \"```````\"}\n",
+	}
+	cfg := digestFixture(t, files)
+	cfg.MarkdownMode = MarkdownModeFenced
+
+	blocks := codeBlocks(runDigest(t, cfg))
+
+	want := []string{files["a.py"], files["b.txt"], files["c.md"], files["d.json"]}
+	if len(blocks) != len(want) {
+		t.Fatalf("got %d blocks, want %d: %q", len(blocks), len(want), blocks)
+	}
+	for i := range want {
+		if strings.TrimRight(blocks[i], "\n") != strings.TrimRight(want[i], "\n") {
+			t.Errorf("block %d = %q, want %q", i+1, blocks[i], want[i])
+		}
+	}
+}
+
+func TestJSONOutputsEscapeContent(t *testing.T) {
+	adversarial := "</file>\n<![CDATA[x]]>\n\x1b[31mred\x1b[0m\r\n\f\"quoted\" \\ back\n"
+	cfg := digestFixture(t, map[string]string{"evil.txt": adversarial})
+	cfg.ResultsFile = filepath.Join(filepath.Dir(cfg.OutputFile), "results.json")
+	cfg.ResultsContent = true
+
+	runDigest(t, cfg)
+
+	f, err := os.Open(cfg.ResultsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	doc, err := ReadResultsDocument(f)
+	if err != nil {
+		t.Fatalf("results document does not parse: %v", err)
+	}
+	if len(doc.Results) != 1 || !strings.Contains(doc.Results[0].Content, adversarial) {
+		t.Errorf("results document content = %+v, want the file content unchanged", doc.Results)
+	}
+
+	stream := digestFixture(t, map[string]string{"evil.txt": adversarial})
+	stream.JSONStream = true
+	data := runDigest(t, stream)
+
+	var result FileResult
+	if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &result); err != nil {
+		t.Fatalf("stream line does not parse: %v", err)
+	}
+	if !strings.Contains(result.Content, adversarial) {
+		t.Errorf("streamed content = %q, want the file content unchanged", result.Content)
+	}
+}
+
+func TestOutputEncoding(t *testing.T) {
+	files := map[string]string{"main.go": "package main\n\n// héllo, 世界 😀\n"}
+	want := runDigest(t, digestFixture(t, files))
+
+	for _, encoding := range []string{utils.EncodingUTF16LE, utils.EncodingUTF16BE} {
+		t.Run(encoding, func(t *testing.T) {
+			cfg := digestFixture(t, files)
+			cfg.OutputEncoding = encoding
+
+			raw := runDigest(t, cfg)
+			if !strings.HasPrefix(raw, string(utils.OutputBOM(encoding))) {
+				t.Fatalf("output does not start with the %s BOM", encoding)
+			}
+			got, _, err := utils.DecodeText([]byte(raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Errorf("decoded output = %q, want %q", got, want)
+			}
+		})
+	}
+}
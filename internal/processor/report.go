@@ -0,0 +1,376 @@
+package processor
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/richardamare/ai-digest/internal/utils"
+)
+
+// Stats output themes
+const (
+	ThemeFancy   = "fancy"   // Icons, sections and aligned columns
+	ThemePlain   = "plain"   // Sections and aligned columns without icons
+	ThemeMinimal = "minimal" // One "Label: value" line per metric
+)
+
+// statsLabelWidth is the column metric values are aligned to
+const statsLabelWidth = 24
+
+// statsLine is one entry of a stats section: a labelled metric, or free text
+// such as a note or list item when Label is empty
+type statsLine struct {
+	Marker utils.Symbol // Drawn before the line, zero for list items
+	Label  string
+	Value  string
+}
+
+// statsSection is a titled group of stats lines
+type statsSection struct {
+	Icon  utils.Symbol
+	Title string
+	Lines []statsLine
+}
+
+// statsReport is the stats printed after a run, independent of how a theme
+// renders it
+type statsReport struct {
+	Icon     utils.Symbol
+	Title    string
+	Rule     int // Width of the title underline in the fancy theme
+	Sections []statsSection
+}
+
+// metric returns a bulleted labelled value
+func metric(label, value string) statsLine {
+	return statsLine{Marker: utils.Bullet, Label: label, Value: value}
+}
+
+// note returns free text drawn after marker
+func note(marker utils.Symbol, text string) statsLine {
+	return statsLine{Marker: marker, Value: text}
+}
+
+// item returns free text without a marker
+func item(text string) statsLine {
+	return statsLine{Value: text}
+}
+
+// add appends section to the report unless it has no lines
+func (r *statsReport) add(section statsSection) {
+	if len(section.Lines) > 0 {
+		r.Sections = append(r.Sections, section)
+	}
+}
+
+// renderStats writes report to w in the given theme
+func renderStats(w io.Writer, report statsReport, theme string) {
+	switch theme {
+	case ThemePlain:
+		fmt.Fprintf(w, "\n%s\n%s\n", report.Title, strings.Repeat("-", len(report.Title)))
+		for _, section := range report.Sections {
+			fmt.Fprintf(w, "\n%s\n", section.Title)
+			for _, line := range section.Lines {
+				fmt.Fprintf(w, "  %s\n", plainStatsLine(line, true))
+			}
+		}
+	case ThemeMinimal:
+		for _, section := range report.Sections {
+			for _, line := range section.Lines {
+				fmt.Fprintln(w, plainStatsLine(line, false))
+			}
+		}
+	default:
+		fmt.Fprintf(w, "\n%s %s\n", report.Icon, report.Title)
+		fmt.Fprintln(w, utils.Rule(report.Rule))
+		for _, section := range report.Sections {
+			fmt.Fprintf(w, "\n%s %s\n", section.Icon, section.Title)
+			for _, line := range section.Lines {
+				text := line.Value
+				if line.Label != "" {
+					text = fmt.Sprintf("%-*s %s", statsLabelWidth, line.Label+":", line.Value)
+				}
+				if line.Marker == (utils.Symbol{}) {
+					fmt.Fprintf(w, "   %s\n", text)
+				} else {
+					fmt.Fprintf(w, "   %s %s\n", line.Marker, text)
+				}
+			}
+		}
+	}
+}
+
+// plainStatsLine renders a line without icons, keeping the ASCII tag of
+// markers other than bullets so warnings stay recognizable
+func plainStatsLine(line statsLine, aligned bool) string {
+	value := strings.TrimSpace(line.Value)
+	if line.Label != "" {
+		if aligned {
+			return fmt.Sprintf("%-*s %s", statsLabelWidth, line.Label+":", value)
+		}
+		return line.Label + ": " + value
+	}
+	if line.Marker != (utils.Symbol{}) && line.Marker != utils.Bullet {
+		return line.Marker.ASCII + " " + value
+	}
+	return value
+}
+
+// formatMB formats a byte count in megabytes
+func formatMB(size int64) string {
+	return fmt.Sprintf("%.2f MB", float64(size)/(1024*1024))
+}
+
+// fileCountLines lists the file counters shared by both summaries, with
+// counts formatted by countFormat
+func (p *Processor) fileCountLines(countFormat string) []statsLine {
+	count := func(label string, n int) statsLine {
+		return metric(label, fmt.Sprintf(countFormat, n))
+	}
+
+	lines := []statsLine{
+		count("Files Ignored", p.stats.IgnoredCount),
+		count("Directories Skipped", p.stats.SkippedDirCount),
+	}
+	if p.config.SinceLastRun {
+		lines = append(lines, count("Files Unchanged", p.stats.UnchangedCount))
+	}
+	lines = append(lines, count("Binary/SVG Files", p.stats.BinaryCount))
+	if p.stats.EmbeddedBOMCount > 0 {
+		lines = append(lines, count("Files with Embedded BOM", p.stats.EmbeddedBOMCount))
+	}
+	if p.config.MaxTokensPerFile > 0 {
+		lines = append(lines, count("Files Truncated", p.stats.TruncatedCount))
+	}
+	if p.config.SummarizeOver > 0 {
+		lines = append(lines, count("Files Summarized", p.stats.SummarizedCount))
+	}
+	if p.config.NoteSymlinks {
+		lines = append(lines, count("Symlinks Noted", p.stats.SymlinkCount))
+	}
+	if p.hasContentFilter() {
+		lines = append(lines, count("Files Content-Filtered", p.stats.FilteredCount))
+	}
+	if p.config.PruneNoise {
+		lines = append(lines, count("Files Pruned as Noise", p.stats.PrunedCount))
+	}
+	if p.config.SkipGeneratedHeader {
+		lines = append(lines, count("Generated Files Skipped", p.stats.GeneratedCount))
+	}
+	if p.config.ExcludeTests {
+		lines = append(lines, count("Test Files Skipped", p.stats.TestFileCount))
+	}
+	if p.config.OnlyTests {
+		lines = append(lines, count("Non-Test Files Skipped", p.stats.TestFileCount))
+	}
+	if len(p.config.TypeLimits) > 0 {
+		lines = append(lines, count("Files Over Type Limits", p.stats.TypeLimitedCount))
+	}
+	if p.config.MaxAge > 0 {
+		lines = append(lines, count("Files Older Than Max Age", p.stats.AgeFilteredCount))
+	}
+	return lines
+}
+
+// effectivenessSection reports the share of scanned files that were included
+func (p *Processor) effectivenessSection() statsSection {
+	section := statsSection{Icon: utils.IconRate, Title: "Processing Effectiveness"}
+	if p.stats.TotalFiles > 0 {
+		inclusionRate := float64(p.stats.IncludedCount) / float64(p.stats.TotalFiles) * 100
+		section.Lines = append(section.Lines, metric("Inclusion Rate", fmt.Sprintf("%5.1f%%", inclusionRate)))
+	}
+	return section
+}
+
+// duplicatesSection lists groups of files with identical content, if
+// ReportDuplicates is set
+func (p *Processor) duplicatesSection() statsSection {
+	section := statsSection{Icon: utils.IconDuplicates, Title: "Duplicate Files"}
+	if !p.config.ReportDuplicates {
+		return section
+	}
+
+	groups := p.duplicateGroups()
+	if len(groups) == 0 {
+		section.Lines = append(section.Lines, note(utils.Bullet, "No files with identical content"))
+	}
+	for i, group := range groups {
+		section.Lines = append(section.Lines, item(fmt.Sprintf("%2d. %s", i+1, strings.Join(group, ", "))))
+	}
+	return section
+}
+
+// clonesSection lists the largest duplicated code blocks, if ReportClones is set
+func (p *Processor) clonesSection() statsSection {
+	section := statsSection{Icon: utils.IconDuplicates, Title: "Duplicated Code"}
+	if !p.config.ReportClones {
+		return section
+	}
+
+	if len(p.stats.Clones) == 0 {
+		section.Lines = append(section.Lines, note(utils.Bullet, "No duplicated blocks found"))
+	}
+	for i, clone := range p.stats.Clones {
+		if i == 10 { // Show first 10 clusters only
+			section.Lines = append(section.Lines, item(fmt.Sprintf("... and %d more", len(p.stats.Clones)-10)))
+			break
+		}
+		locations := make([]string, len(clone.Locations))
+		for j, loc := range clone.Locations {
+			locations[j] = fmt.Sprintf("%s:%d-%d", loc.Path, loc.StartLine, loc.EndLine)
+		}
+		section.Lines = append(section.Lines, item(fmt.Sprintf("%2d. %d lines in %s", i+1, clone.Lines, strings.Join(locations, ", "))))
+	}
+	return section
+}
+
+// tokenSavingsSection reports how many tokens content transforms saved, if
+// CompareBaseline is set
+func (p *Processor) tokenSavingsSection() statsSection {
+	section := statsSection{Icon: utils.IconSavings, Title: "Token Savings"}
+	if !p.config.CompareBaseline {
+		return section
+	}
+
+	saved := p.stats.BaselineTokens - p.stats.OutputTokens
+	percent := 0.0
+	if p.stats.BaselineTokens > 0 {
+		percent = float64(saved) / float64(p.stats.BaselineTokens) * 100
+	}
+
+	section.Lines = []statsLine{
+		metric("Baseline Tokens", fmt.Sprintf("%d", p.stats.BaselineTokens)),
+		metric("Output Tokens", fmt.Sprintf("%d", p.stats.OutputTokens)),
+		metric("Saved", fmt.Sprintf("%d (%.1f%%)", saved, percent)),
+	}
+	return section
+}
+
+// includedFilesSection lists the first included files, if ShowOutputFiles is set
+func (p *Processor) includedFilesSection() statsSection {
+	section := statsSection{Icon: utils.IconList, Title: "Included Files"}
+	if !p.config.ShowOutputFiles || len(p.stats.IncludedFiles) == 0 {
+		return section
+	}
+
+	section.Lines = append(section.Lines, item("Files processed and included in output:"))
+	for i, file := range p.stats.IncludedFiles {
+		if i == 10 { // Show first 10 files only
+			section.Lines = append(section.Lines, item(fmt.Sprintf("... and %d more files", len(p.stats.IncludedFiles)-10)))
+			break
+		}
+		section.Lines = append(section.Lines, item(fmt.Sprintf("%2d. %s", i+1, file)))
+	}
+	return section
+}
+
+// ignoredFilesSection lists the first ignored paths and why each was left
+// out, if ShowIgnored is set
+func (p *Processor) ignoredFilesSection() statsSection {
+	section := statsSection{Icon: utils.IconList, Title: "Ignored Files"}
+	if !p.config.ShowIgnored || len(p.stats.IgnoredFiles) == 0 {
+		return section
+	}
+
+	section.Lines = append(section.Lines, item("Files and directories left out, with the pattern responsible:"))
+	for i, e := range p.stats.IgnoredFiles {
+		if i == 10 { // Show first 10 paths only
+			section.Lines = append(section.Lines, item(fmt.Sprintf("... and %d more", len(p.stats.IgnoredFiles)-10)))
+			break
+		}
+		section.Lines = append(section.Lines, item(fmt.Sprintf("%2d. %s", i+1, e)))
+	}
+	return section
+}
+
+// singleStatsReport summarizes a run that wrote a single output file
+func (p *Processor) singleStatsReport() statsReport {
+	report := statsReport{Icon: utils.IconSummary, Title: "Processing Summary", Rule: 19}
+
+	report.add(statsSection{Icon: utils.IconFiles, Title: "File Statistics", Lines: append([]statsLine{
+		metric("Total Files Scanned", fmt.Sprintf("%5d", p.stats.TotalFiles)),
+		metric("Files in Output", fmt.Sprintf("%5d", p.stats.IncludedCount)),
+	}, p.fileCountLines("%5d")...)})
+
+	report.add(statsSection{Icon: utils.IconSize, Title: "Size Analysis", Lines: []statsLine{
+		metric("Total Size", formatMB(p.stats.TotalSize)),
+	}})
+	report.add(p.effectivenessSection())
+
+	tokens := statsSection{Icon: utils.IconTokens, Title: "Token Estimation"}
+	if p.stats.TotalSize > maxFileSize {
+		tokens.Lines = []statsLine{
+			note(utils.IconWarning, "Output exceeds recommended size (10 MB)"),
+			note(utils.IconWarning, "Token estimation skipped"),
+			note(utils.IconTip, fmt.Sprintf("Tip: Add more patterns to %s to reduce size", p.config.IgnoreFile)),
+		}
+	} else {
+		tokenCount := utils.EstimateTokenCount(fmt.Sprintf("%d", p.stats.TotalSize))
+		tokens.Lines = []statsLine{
+			metric("Estimated Tokens", fmt.Sprintf("%5d", tokenCount)),
+			note(utils.IconNote, fmt.Sprintf("Note: Token count may vary %s20%% across AI models", utils.PlusMinus)),
+		}
+	}
+	report.add(tokens)
+
+	report.add(p.duplicatesSection())
+	report.add(p.clonesSection())
+	report.add(p.tokenSavingsSection())
+	report.add(p.languageOutputsSection())
+	report.add(p.includedFilesSection())
+	report.add(p.ignoredFilesSection())
+
+	done := statsSection{Icon: utils.IconDone, Title: "Process Complete"}
+	if p.stats.TotalSize > maxFileSize {
+		done.Lines = []statsLine{note(utils.IconWarning, "Warning: Large output file size")}
+	} else {
+		done.Lines = []statsLine{note(utils.IconSuccess, "Output generated successfully")}
+	}
+	report.add(done)
+
+	return report
+}
+
+// splitStatsReport summarizes a run that wrote split output files
+func (p *Processor) splitStatsReport() statsReport {
+	report := statsReport{Icon: utils.IconSummary, Title: "Split Processing Summary", Rule: 27}
+
+	outputs := statsSection{Icon: utils.IconFiles, Title: "Output Files", Lines: []statsLine{
+		metric("Number of Files", fmt.Sprintf("%d", p.stats.NumberOfFiles)),
+		metric("Average File Size", formatMB(p.stats.AverageFileSize)),
+	}}
+	if p.combined != nil {
+		outputs.Lines = append(outputs.Lines, metric("Combined File",
+			fmt.Sprintf("%s (%s)", p.config.AlsoCombined, formatMB(p.stats.CombinedSize))))
+	}
+	report.add(outputs)
+
+	report.add(statsSection{Icon: utils.IconSizes, Title: "Size Distribution", Lines: []statsLine{
+		metric("Smallest File", fmt.Sprintf("%s (%s)", filepath.Base(p.stats.SmallestFile), formatMB(p.stats.SmallestFileSize))),
+		metric("Largest File", fmt.Sprintf("%s (%s)", filepath.Base(p.stats.LargestFile), formatMB(p.stats.LargestFileSize))),
+	}})
+
+	report.add(statsSection{Icon: utils.IconDetails, Title: "Processing Details", Lines: append([]statsLine{
+		metric("Total Files Processed", fmt.Sprintf("%d", p.stats.TotalFiles)),
+		metric("Files Included", fmt.Sprintf("%d", p.stats.IncludedCount)),
+	}, p.fileCountLines("%d")...)})
+
+	report.add(statsSection{Icon: utils.IconSize, Title: "Total Size", Lines: []statsLine{
+		metric("Combined Size", formatMB(p.stats.TotalSize)),
+	}})
+	report.add(p.effectivenessSection())
+
+	report.add(p.duplicatesSection())
+	report.add(p.clonesSection())
+	report.add(p.tokenSavingsSection())
+	report.add(p.includedFilesSection())
+	report.add(p.ignoredFilesSection())
+
+	report.add(statsSection{Icon: utils.IconDone, Title: "Process Complete", Lines: []statsLine{
+		note(utils.IconSuccess, "Output files generated successfully"),
+	}})
+
+	return report
+}
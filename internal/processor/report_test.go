@@ -0,0 +1,58 @@
+package processor
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/richardamare/ai-digest/internal/utils"
+)
+
+func TestRenderStats(t *testing.T) {
+	t.Cleanup(func() { utils.SetEmoji(true) })
+	utils.SetEmoji(false)
+
+	report := statsReport{
+		Icon:  utils.IconSummary,
+		Title: "Summary",
+		Rule:  7,
+		Sections: []statsSection{{
+			Icon:  utils.IconFiles,
+			Title: "Files",
+			Lines: []statsLine{
+				metric("Included", "  3"),
+				note(utils.IconWarning, "Large output"),
+				item("1. a.go"),
+			},
+		}},
+	}
+
+	tests := []struct {
+		theme string
+		want  string
+	}{
+		{ThemeFancy, "\n" + utils.IconSummary.ASCII + " Summary\n=======\n\n" + utils.IconFiles.ASCII + " Files\n" +
+			"   - Included:" + strings.Repeat(" ", 18) + "3\n   [WARN] Large output\n   1. a.go\n"},
+		{ThemePlain, "\nSummary\n-------\n\nFiles\n  Included:" + strings.Repeat(" ", 16) + "3\n  [WARN] Large output\n  1. a.go\n"},
+		{ThemeMinimal, "Included: 3\n[WARN] Large output\n1. a.go\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.theme, func(t *testing.T) {
+			var buf bytes.Buffer
+			renderStats(&buf, report, tt.theme)
+			if got := buf.String(); got != tt.want {
+				t.Errorf("renderStats() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatsReportAddSkipsEmptySections(t *testing.T) {
+	var report statsReport
+	report.add(statsSection{Title: "Empty"})
+	report.add(statsSection{Title: "Full", Lines: []statsLine{item("x")}})
+	if len(report.Sections) != 1 || report.Sections[0].Title != "Full" {
+		t.Errorf("Sections = %+v, want only the non-empty one", report.Sections)
+	}
+}
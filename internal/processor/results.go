@@ -0,0 +1,83 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/richardamare/ai-digest/internal/utils"
+)
+
+// ResultsSchemaVersion is the version of the results document layout. It
+// changes only when fields are removed or change meaning; new optional
+// fields may appear within a version.
+const ResultsSchemaVersion = 1
+
+// ResultsDocument is the batch form of a run's file results, in output
+// order. Files that failed to process are left out, and content is only
+// present when requested.
+type ResultsDocument struct {
+	Version int          `json:"version"`
+	Results []FileResult `json:"results"`
+}
+
+// ReadResultsDocument decodes a results document, rejecting versions this
+// build doesn't understand
+func ReadResultsDocument(r io.Reader) (*ResultsDocument, error) {
+	var doc ResultsDocument
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse results document: %w", err)
+	}
+	if doc.Version != ResultsSchemaVersion {
+		return nil, fmt.Errorf("unsupported results document version %d (expected %d)", doc.Version, ResultsSchemaVersion)
+	}
+	return &doc, nil
+}
+
+// recordResult keeps a copy of result for the results document, dropping
+// its content unless ResultsContent is set
+func (p *Processor) recordResult(result FileResult) {
+	if p.config.ResultsFile == "" {
+		return
+	}
+	if !p.config.ResultsContent {
+		result.Content = ""
+	}
+	p.results = append(p.results, result)
+}
+
+// marshalOutput encodes v for a JSON output, indented unless JSONCompact is set
+func (p *Processor) marshalOutput(v any) ([]byte, error) {
+	if p.config.JSONCompact {
+		return json.Marshal(v)
+	}
+	return json.MarshalIndent(v, "", "  ")
+}
+
+// writeResults writes the recorded results to ResultsFile
+func (p *Processor) writeResults() error {
+	results := p.results
+	if results == nil {
+		results = []FileResult{}
+	}
+
+	data, err := p.marshalOutput(ResultsDocument{Version: ResultsSchemaVersion, Results: results})
+	if err != nil {
+		return fmt.Errorf("failed to marshal results: %w", err)
+	}
+
+	file, err := createOutputFile(p.config.ResultsFile, p.config.OutputMode)
+	if err != nil {
+		return fmt.Errorf("failed to create results file: %w", err)
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write results file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write results file: %w", err)
+	}
+
+	p.logger.Log("Created results file: %s", utils.IconFile, p.config.ResultsFile)
+	return nil
+}
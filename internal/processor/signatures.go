@@ -0,0 +1,158 @@
+package processor
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"path"
+	"sort"
+	"strings"
+)
+
+// goPrinter formats declarations the way gofmt does
+var goPrinter = printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+
+// signatureExtractors map extensions to functions that reduce a file to its
+// top-level declarations, reporting false when the content can't be parsed
+var signatureExtractors = map[string]func(content string) (string, bool){
+	".go": goSignatures,
+}
+
+// extractSignatures is a ContentTransformer that replaces supported source
+// files with their top-level declarations. Other files are left whole.
+var extractSignatures ContentTransformer = func(content string, ext string) string {
+	extract, ok := signatureExtractors[ext]
+	if !ok {
+		return content
+	}
+
+	if signatures, ok := extract(content); ok {
+		return signatures
+	}
+	return content
+}
+
+// goSignatures renders a Go file's package clause, type declarations,
+// function signatures without bodies and exported constants and variables
+func goSignatures(content string) (string, bool) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", content, parser.SkipObjectResolution)
+	if err != nil {
+		return "", false
+	}
+
+	var decls []ast.Decl
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			d.Body = nil
+			decls = append(decls, d)
+		case *ast.GenDecl:
+			if filtered := goSignatureSpecs(d); filtered != nil {
+				decls = append(decls, filtered)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("package " + file.Name.Name + "\n")
+	for _, decl := range decls {
+		buf.WriteString("\n")
+		if err := goPrinter.Fprint(&buf, fset, decl); err != nil {
+			return "", false
+		}
+		buf.WriteString("\n")
+	}
+
+	return strings.TrimRight(buf.String(), "\n"), true
+}
+
+// goSignatureSpecs keeps type declarations whole and reduces constant and
+// variable declarations to their exported names, dropping imports
+func goSignatureSpecs(d *ast.GenDecl) *ast.GenDecl {
+	switch d.Tok {
+	case token.TYPE:
+		return d
+	case token.CONST, token.VAR:
+	default:
+		return nil
+	}
+
+	var specs []ast.Spec
+	for _, spec := range d.Specs {
+		vs, ok := spec.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+
+		// Variable initializers can be arbitrarily large; keep only the type
+		// when there is one to keep
+		keepValues := len(vs.Values) == len(vs.Names) && (d.Tok == token.CONST || vs.Type == nil)
+
+		var names []*ast.Ident
+		var values []ast.Expr
+		for i, name := range vs.Names {
+			if !name.IsExported() {
+				continue
+			}
+			names = append(names, name)
+			if keepValues {
+				values = append(values, vs.Values[i])
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+		specs = append(specs, &ast.ValueSpec{Names: names, Type: vs.Type, Values: values})
+	}
+
+	if len(specs) == 0 {
+		return nil
+	}
+	return &ast.GenDecl{Tok: d.Tok, Lparen: d.Lparen, Specs: specs, Rparen: d.Rparen}
+}
+
+// formatTree renders the collected files as an indented directory tree block
+func (p *Processor) formatTree(files []string) string {
+	paths := make([][]string, len(files))
+	for i, file := range files {
+		paths[i] = strings.Split(path.Clean(strings.ReplaceAll(file, "\\", "/")), "/")
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		a, b := paths[i], paths[j]
+		for k := 0; k < len(a) && k < len(b); k++ {
+			// Directories sort before files at the same level
+			aDir, bDir := k < len(a)-1, k < len(b)-1
+			if aDir != bDir {
+				return aDir
+			}
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+
+	var buf strings.Builder
+	buf.WriteString("# Structure\n\n```\n")
+
+	var prev []string
+	for _, parts := range paths {
+		dirs := parts[:len(parts)-1]
+		common := 0
+		for common < len(dirs) && common < len(prev) && dirs[common] == prev[common] {
+			common++
+		}
+		for depth := common; depth < len(dirs); depth++ {
+			buf.WriteString(strings.Repeat("  ", depth) + dirs[depth] + "/\n")
+		}
+		buf.WriteString(strings.Repeat("  ", len(dirs)) + parts[len(parts)-1] + "\n")
+		prev = dirs
+	}
+
+	buf.WriteString("```\n")
+	buf.WriteString(p.blockSeparator())
+	return buf.String()
+}
@@ -0,0 +1,88 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGoSignatures(t *testing.T) {
+	src := `package shapes
+
+import "math"
+
+const Pi = 3.14
+const internal, Tau = 1, 6.28
+
+var Registry = map[string]Shape{"unit": Circle{1}}
+
+var Default Shape = Circle{1}
+
+// Shape is anything with an area
+type Shape interface {
+	Area() float64
+}
+
+type Circle struct{ R float64 }
+
+func (c Circle) Area() float64 {
+	return math.Pi * c.R * c.R
+}
+`
+	want := `package shapes
+
+const Pi = 3.14
+
+const Tau = 6.28
+
+var Registry = map[string]Shape{"unit": Circle{1}}
+
+var Default Shape
+
+type Shape interface {
+	Area() float64
+}
+
+type Circle struct{ R float64 }
+
+func (c Circle) Area() float64`
+
+	got, ok := goSignatures(src)
+	if !ok {
+		t.Fatal("goSignatures() failed to parse valid source")
+	}
+	if got != want {
+		t.Errorf("goSignatures() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestExtractSignaturesFallsBack(t *testing.T) {
+	broken := "package main\n\nfunc {\n"
+	if got := extractSignatures(broken, ".go"); got != broken {
+		t.Errorf("unparseable Go = %q, want content unchanged", got)
+	}
+	text := "plain text\n"
+	if got := extractSignatures(text, ".txt"); got != text {
+		t.Errorf("unsupported extension = %q, want content unchanged", got)
+	}
+}
+
+func TestSignaturesOnlyDigest(t *testing.T) {
+	cfg := digestFixture(t, map[string]string{
+		"main.go":        "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n",
+		"pkg/util/a.go":  "package util\n\nfunc A() int { return 1 }\n",
+		"pkg/README.txt": "notes\n",
+	})
+	cfg.SignaturesOnly = true
+	digest := runDigest(t, cfg)
+
+	tree := "# Structure\n\n```\npkg/\n  util/\n    a.go\n  README.txt\nmain.go\n```\n"
+	if !strings.HasPrefix(digest, tree) {
+		t.Errorf("digest does not start with the directory tree:\n%s", digest)
+	}
+	if strings.Contains(digest, "println") || !strings.Contains(digest, "func main()") {
+		t.Errorf("function bodies were not stripped:\n%s", digest)
+	}
+	if !strings.Contains(digest, "notes\n") {
+		t.Errorf("unsupported files should be kept whole:\n%s", digest)
+	}
+}
@@ -0,0 +1,547 @@
+package processor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/richardamare/ai-digest/internal/utils"
+)
+
+// splitFixture creates an input directory with n small Go files and
+// returns the config for a per-file split into a separate output directory
+func splitFixture(t *testing.T, n int) ProcessorConfig {
+	t.Helper()
+	h := utils.NewTestHelper(t)
+	t.Cleanup(h.Cleanup)
+
+	var input string
+	for i := 1; i <= n; i++ {
+		path := h.CreateTempFile(fmt.Sprintf("src/file%02d.go", i), fmt.Sprintf("package src\n\nconst V%d = %d\n", i, i))
+		input = filepath.Dir(path)
+	}
+
+	return ProcessorConfig{
+		InputDir:     input,
+		OutputFile:   filepath.Join(h.CreateTempDir("out"), "digest.md"),
+		IgnoreFile:   ".aidigestignore",
+		Split:        true,
+		SplitPerFile: true,
+		OutputMode:   0644,
+	}
+}
+
+// runProcessor digests cfg and returns the contents of each split part
+func runProcessor(t *testing.T, cfg ProcessorConfig) []string {
+	t.Helper()
+	p, err := NewProcessor(cfg)
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := p.Process(context.Background()); err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+
+	paths, err := filepath.Glob(strings.TrimSuffix(cfg.OutputFile, ".md") + "_part*.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := make([]string, len(paths))
+	for i := range paths {
+		// Parts are read by index since the glob sorts part10 before part2
+		data, err := os.ReadFile(strings.TrimSuffix(cfg.OutputFile, ".md") + fmt.Sprintf("_part%d.md", i+1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[i] = string(data)
+	}
+	return parts
+}
+
+func TestSplitWriters(t *testing.T) {
+	tests := []struct {
+		name      string
+		parallel  bool
+		maxParts  int
+		wantParts int
+	}{
+		{name: "per file", wantParts: 12},
+		{name: "per file parallel", parallel: true, wantParts: 12},
+		{name: "max parts", maxParts: 5, wantParts: 5},
+		{name: "max parts parallel", parallel: true, maxParts: 5, wantParts: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := splitFixture(t, 12)
+			cfg.ParallelWrite = tt.parallel
+			cfg.MaxParts = tt.maxParts
+
+			parts := runProcessor(t, cfg)
+			if len(parts) != tt.wantParts {
+				t.Fatalf("got %d parts, want %d", len(parts), tt.wantParts)
+			}
+
+			all := strings.Join(parts, "")
+			for i := 1; i <= 12; i++ {
+				if n := strings.Count(all, fmt.Sprintf("# file%02d.go\n", i)); n != 1 {
+					t.Errorf("file%02d.go appears %d times, want 1", i, n)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitPerFileAttachesBlocks(t *testing.T) {
+	for _, parallel := range []bool{false, true} {
+		t.Run(fmt.Sprintf("parallel=%v", parallel), func(t *testing.T) {
+			cfg := splitFixture(t, 4)
+			if err := os.WriteFile(filepath.Join(cfg.InputDir, "logo.bin"), []byte{0, 1, 2, 3}, 0644); err != nil {
+				t.Fatal(err)
+			}
+			cfg.ParallelWrite = parallel
+			cfg.Instructions = []string{"Review this code."}
+			cfg.CollectTodos = true
+			cfg.CompactBinaries = true
+
+			parts := runProcessor(t, cfg)
+			if len(parts) != 4 {
+				t.Fatalf("got %d parts, want 4", len(parts))
+			}
+			if !strings.HasPrefix(strings.TrimPrefix(parts[0], "\ufeff"), "> Review this code.\n") {
+				t.Errorf("part 1 does not start with the instructions:\n%s", parts[0])
+			}
+			if !strings.Contains(parts[0], "# TODO Summary") || !strings.Contains(parts[0], "# file01.go\n") {
+				t.Errorf("part 1 is missing the TODO summary or the first file:\n%s", parts[0])
+			}
+			if !strings.Contains(parts[3], "logo.bin") || !strings.Contains(parts[3], "# file04.go\n") {
+				t.Errorf("last part is missing the binary table or the last file:\n%s", parts[3])
+			}
+		})
+	}
+}
+
+// sizedSplitFixture creates an input directory of n files of about 64 KB
+// each and returns the config for a 1 MB size-based split
+func sizedSplitFixture(tb testing.TB, n int) ProcessorConfig {
+	tb.Helper()
+	h := utils.NewTestHelper(tb)
+	tb.Cleanup(h.Cleanup)
+
+	input := h.CreateTempDir("src")
+	for i := 1; i <= n; i++ {
+		line := fmt.Sprintf("const V%d = %q\n", i, strings.Repeat("x", i%50))
+		h.CreateTempFile(fmt.Sprintf("src/pkg%d/file%02d.go", i%3, i), strings.Repeat(line, 64*1024/len(line)))
+	}
+
+	return ProcessorConfig{
+		InputDir:      input,
+		OutputFile:    filepath.Join(h.CreateTempDir("out"), "digest.md"),
+		IgnoreFile:    ".aidigestignore",
+		Split:         true,
+		MaxFileSizeMB: 1,
+		ChunkSize:     16 * 1024,
+		OutputMode:    0644,
+	}
+}
+
+func TestParallelWriteMatchesSerial(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(*ProcessorConfig)
+	}{
+		{name: "size split", setup: func(c *ProcessorConfig) {}},
+		{name: "overlap", setup: func(c *ProcessorConfig) { c.SplitOverlap = 2 }},
+		{name: "per file", setup: func(c *ProcessorConfig) { c.SplitPerFile = true }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serial := sizedSplitFixture(t, 40)
+			tt.setup(&serial)
+			parallel := serial
+			parallel.OutputFile = filepath.Join(t.TempDir(), "digest.md")
+			parallel.ParallelWrite = true
+
+			want := runProcessor(t, serial)
+			got := runProcessor(t, parallel)
+			if len(want) < 2 {
+				t.Fatalf("serial run wrote %d parts, want a split", len(want))
+			}
+			if len(got) != len(want) {
+				t.Fatalf("parallel run wrote %d parts, serial run wrote %d", len(got), len(want))
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Errorf("part %d differs between serial and parallel writes", i+1)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSplitWrite(b *testing.B) {
+	for _, parallel := range []bool{false, true} {
+		b.Run(fmt.Sprintf("parallel=%v", parallel), func(b *testing.B) {
+			cfg := sizedSplitFixture(b, 80)
+			cfg.ParallelWrite = parallel
+
+			b.ResetTimer()
+			for range b.N {
+				p, err := NewProcessor(cfg)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if err := p.Process(context.Background()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestPartHeader(t *testing.T) {
+	tests := []struct {
+		name     string
+		first    int
+		files    []string
+		repeated []string
+		want     string
+	}{
+		{"empty", 1, nil, nil, "Part 2 of 3\n\n"},
+		{"one file", 4, []string{"a.go"}, nil, "Part 2 of 3, file 4 (a.go)\n\n"},
+		{"range", 4, []string{"a.go", "b.go", "c.go"}, nil, "Part 2 of 3, files 4–6 (a.go to c.go)\n\n"},
+		{"one repeated", 4, []string{"a.go", "b.go"}, []string{"z.go"}, "Part 2 of 3, files 4–5 (a.go to b.go), after 1 repeated file (z.go)\n\n"},
+		{"repeated range", 4, []string{"a.go"}, []string{"x.go", "z.go"}, "Part 2 of 3, file 4 (a.go), after 2 repeated files (x.go to z.go)\n\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := partHeader(2, 3, tt.first, tt.files, tt.repeated); got != tt.want {
+				t.Errorf("partHeader() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitOverlap(t *testing.T) {
+	cfg := sizedSplitFixture(t, 20)
+	cfg.SplitOverlap = 2
+
+	parts := runProcessor(t, cfg)
+	if len(parts) < 2 {
+		t.Fatalf("got %d parts, want a split", len(parts))
+	}
+
+	// Each part starts with the last two file blocks of the part before it
+	blocks := make([][]string, len(parts))
+	for i, part := range parts {
+		for _, block := range strings.Split("\n"+strings.TrimPrefix(part, "\ufeff"), "\n# ")[1:] {
+			blocks[i] = append(blocks[i], "# "+strings.TrimRight(block, "\n"))
+		}
+		if i == 0 {
+			continue
+		}
+		prev := blocks[i-1]
+		if len(prev) < 2 || len(blocks[i]) < 3 {
+			t.Fatalf("parts %d and %d have %d and %d files", i, i+1, len(prev), len(blocks[i]))
+		}
+		if !slices.Equal(blocks[i][:2], prev[len(prev)-2:]) {
+			t.Errorf("part %d does not repeat the last 2 files of part %d", i+1, i)
+		}
+	}
+}
+
+func TestFlushPeriodically(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval int64
+		writes   []int
+		want     []int // Bytes reaching the file after each write
+	}{
+		{"disabled", 0, []int{60, 50, 200}, []int{0, 0, 0}},
+		{"below interval", 100, []int{60, 30}, []int{0, 0}},
+		{"crossing interval", 100, []int{60, 50, 40, 70}, []int{0, 110, 110, 220}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var file bytes.Buffer
+			w := &multiFileWriter{
+				config: ProcessorConfig{FlushInterval: tt.interval},
+				writer: bufio.NewWriterSize(&file, 4096),
+			}
+
+			for i, n := range tt.writes {
+				w.writer.WriteString(strings.Repeat("x", n))
+				if err := w.flushPeriodically(int64(n)); err != nil {
+					t.Fatal(err)
+				}
+				if file.Len() != tt.want[i] {
+					t.Errorf("after write %d the file holds %d bytes, want %d", i+1, file.Len(), tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFlushIntervalKeepsOutput(t *testing.T) {
+	want := runProcessor(t, sizedSplitFixture(t, 12))
+
+	cfg := sizedSplitFixture(t, 12)
+	cfg.FlushInterval = 4096
+	if got := runProcessor(t, cfg); !slices.Equal(got, want) {
+		t.Error("parts written with a flush interval differ from the default")
+	}
+}
+
+func TestPartHeadersWithOverlap(t *testing.T) {
+	for _, parallel := range []bool{false, true} {
+		t.Run(fmt.Sprintf("parallel=%v", parallel), func(t *testing.T) {
+			cfg := sizedSplitFixture(t, 40)
+			cfg.PartHeaders = true
+			cfg.SplitOverlap = 2
+			cfg.ParallelWrite = parallel
+
+			parts := runProcessor(t, cfg)
+			if len(parts) < 2 {
+				t.Fatalf("got %d parts, want a split", len(parts))
+			}
+
+			next := 1
+			for i, part := range parts {
+				header, body, _ := strings.Cut(strings.TrimPrefix(part, "\ufeff"), "\n")
+				var files []string
+				for _, line := range strings.Split(body, "\n") {
+					if path, ok := strings.CutPrefix(line, "# "); ok {
+						files = append(files, path)
+					}
+				}
+
+				own := files
+				if i > 0 {
+					if len(files) < 3 {
+						t.Fatalf("part %d has %d files, want 2 repeated and at least 1 new", i+1, len(files))
+					}
+					own = files[2:]
+				}
+				want := fmt.Sprintf("Part %d of %d, files %d–%d (%s to %s)",
+					i+1, len(parts), next, next+len(own)-1, own[0], own[len(own)-1])
+				if i > 0 {
+					want += fmt.Sprintf(", after 2 repeated files (%s to %s)", files[0], files[1])
+				}
+				if header != want {
+					t.Errorf("part %d header = %q, want %q", i+1, header, want)
+				}
+				next += len(own)
+			}
+			if next != 41 {
+				t.Errorf("headers cover %d files, want 40", next-1)
+			}
+		})
+	}
+}
+
+func TestSplitOutputMode(t *testing.T) {
+	cfg := splitFixture(t, 2)
+	cfg.OutputMode = 0640
+
+	runProcessor(t, cfg)
+
+	paths, err := filepath.Glob(strings.TrimSuffix(cfg.OutputFile, ".md") + "_part*.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.Mode().Perm(); got != 0640 {
+			t.Errorf("%s mode = %o, want 640", filepath.Base(path), got)
+		}
+	}
+}
+
+func TestSplitResume(t *testing.T) {
+	cfg := splitFixture(t, 3)
+	cfg.SplitResume = true
+	runProcessor(t, cfg)
+
+	base := strings.TrimSuffix(cfg.OutputFile, ".md")
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for i := 1; i <= 3; i++ {
+		if err := os.Chtimes(fmt.Sprintf("%s_part%d.md", base, i), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(cfg.InputDir, "file02.go"), []byte("package src\n\nconst V2 = 20\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	parts := runProcessor(t, cfg)
+
+	for i := 1; i <= 3; i++ {
+		info, err := os.Stat(fmt.Sprintf("%s_part%d.md", base, i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kept := info.ModTime().Equal(old); kept != (i != 2) {
+			t.Errorf("part %d kept = %v, want only the changed part rewritten", i, kept)
+		}
+	}
+	if !strings.Contains(parts[1], "V2 = 20") {
+		t.Errorf("part 2 was not updated:\n%s", parts[1])
+	}
+
+	leftovers, err := filepath.Glob(base + "*" + resumeSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leftovers) != 0 {
+		t.Errorf("temporary parts left behind: %q", leftovers)
+	}
+}
+
+func TestSplitSizeStatsMatchDisk(t *testing.T) {
+	cfg := splitFixture(t, 3)
+	if err := os.WriteFile(filepath.Join(cfg.InputDir, "file02.go"), []byte(strings.Repeat("// padding\n", 50)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := NewProcessor(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Process(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	base := strings.TrimSuffix(cfg.OutputFile, ".md")
+	var total int64
+	sizes := make(map[string]int64)
+	for i := 1; i <= 3; i++ {
+		path := fmt.Sprintf("%s_part%d.md", base, i)
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sizes[path] = info.Size()
+		total += info.Size()
+	}
+
+	if want := sizes[base+"_part2.md"]; p.stats.LargestFile != base+"_part2.md" || p.stats.LargestFileSize != want {
+		t.Errorf("largest = %s (%d), want part 2 (%d)", p.stats.LargestFile, p.stats.LargestFileSize, want)
+	}
+	if want := sizes[p.stats.SmallestFile]; p.stats.SmallestFileSize != want || want >= sizes[base+"_part2.md"] {
+		t.Errorf("smallest = %s (%d), want an on-disk size below part 2", p.stats.SmallestFile, p.stats.SmallestFileSize)
+	}
+	if p.stats.AverageFileSize != total/3 {
+		t.Errorf("AverageFileSize = %d, want %d", p.stats.AverageFileSize, total/3)
+	}
+}
+
+func TestCleanStale(t *testing.T) {
+	tests := []struct {
+		name       string
+		cleanStale bool
+	}{
+		{"warn only", false},
+		{"clean", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := splitFixture(t, 2)
+			cfg.OutputFilePattern = "part-%03d.md"
+			cfg.CleanStale = tt.cleanStale
+			dir := filepath.Dir(cfg.OutputFile)
+
+			// part-1.md is not a name the pattern produces, so it is never touched
+			for _, name := range []string{"part-003.md", "part-010.md", "part-1.md", "notes.md"} {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte("old\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			p, err := NewProcessor(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := p.Process(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, entry := range entries {
+				got = append(got, entry.Name())
+			}
+
+			want := []string{"notes.md", "part-001.md", "part-002.md", "part-003.md", "part-010.md", "part-1.md"}
+			if tt.cleanStale {
+				want = []string{"notes.md", "part-001.md", "part-002.md", "part-1.md"}
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("output directory = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestAlsoCombined(t *testing.T) {
+	cfg := splitFixture(t, 3)
+	cfg.AlsoCombined = filepath.Join(filepath.Dir(cfg.OutputFile), "all", "combined.md")
+	parts := runProcessor(t, cfg)
+
+	combined, err := os.ReadFile(cfg.AlsoCombined)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each split part starts with a BOM; the combined file is a single UTF-8 file without one
+	var want strings.Builder
+	for _, part := range parts {
+		want.WriteString(strings.TrimPrefix(part, "\ufeff"))
+	}
+	if string(combined) != want.String() {
+		t.Errorf("combined output =\n%s\nwant the parts concatenated:\n%s", combined, want.String())
+	}
+	if len(parts) != 3 {
+		t.Errorf("got %d parts, want 3", len(parts))
+	}
+}
+
+func TestSplitIndex(t *testing.T) {
+	cfg := splitFixture(t, 2)
+	cfg.SplitIndex = filepath.Join(filepath.Dir(cfg.OutputFile), "docs", "index.md")
+	parts := runProcessor(t, cfg)
+
+	data, err := os.ReadFile(cfg.SplitIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := string(data)
+
+	// Links are relative to the index file
+	for i, want := range []string{
+		"# Digest Index\n\n2 parts, 2 files\n",
+		"\n## [Part 1](../digest_part1.md)\n\n" + utils.FormatSize(int64(len(parts[0]))) + ", 1 files\n\n- `file01.go`\n",
+		"\n## [Part 2](../digest_part2.md)\n\n" + utils.FormatSize(int64(len(parts[1]))) + ", 1 files\n\n- `file02.go`\n",
+	} {
+		if !strings.Contains(index, want) {
+			t.Errorf("index missing entry %d %q:\n%s", i, want, index)
+		}
+	}
+}
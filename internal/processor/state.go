@@ -0,0 +1,63 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// RunState records file modification times from a previous run
+type RunState struct {
+	Files map[string]int64 `json:"files"`
+}
+
+// NewRunState creates an empty run state
+func NewRunState() *RunState {
+	return &RunState{Files: make(map[string]int64)}
+}
+
+// LoadRunState reads a run state file, returning an empty state if it doesn't exist
+func LoadRunState(path string) (*RunState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return NewRunState(), nil
+		}
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	state := NewRunState()
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse state file: %w", err)
+	}
+	if state.Files == nil {
+		state.Files = make(map[string]int64)
+	}
+
+	return state, nil
+}
+
+// Save writes the run state to file
+func (s *RunState) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+
+	return nil
+}
+
+// Changed reports whether a file's modification time differs from the recorded one
+func (s *RunState) Changed(relPath string, modTime int64) bool {
+	prev, ok := s.Files[relPath]
+	return !ok || prev != modTime
+}
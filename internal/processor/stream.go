@@ -0,0 +1,107 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/richardamare/ai-digest/internal/utils"
+)
+
+// streamResults writes each file result to the output as one JSON line as
+// soon as it completes. Lines follow completion order rather than input
+// order, so at most one result per worker is held in memory at a time.
+func (p *Processor) streamResults(ctx context.Context, files []string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan int)
+	results := make(chan FileResult, p.workers)
+
+	var wg sync.WaitGroup
+	for range p.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results <- p.processFile(files[i], i+1)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for i := range files {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var streamErr error
+	for result := range results {
+		// Keep draining so the workers can finish
+		if streamErr != nil || ctx.Err() != nil {
+			continue
+		}
+
+		if result.Error != nil {
+			if p.config.Strict {
+				streamErr = fmt.Errorf("failed to process %s: %w", result.RelativePath, result.Error)
+				cancel()
+				continue
+			}
+			p.logger.LogError("Error processing %s: %v", result.RelativePath, result.Error)
+			continue
+		}
+
+		switch {
+		case result.Filtered:
+			p.stats.mu.Lock()
+			p.stats.FilteredCount++
+			p.stats.mu.Unlock()
+			continue
+		case result.Pruned:
+			p.stats.mu.Lock()
+			p.stats.PrunedCount++
+			p.stats.mu.Unlock()
+			continue
+		case result.Generated:
+			p.stats.mu.Lock()
+			p.stats.GeneratedCount++
+			p.stats.mu.Unlock()
+			continue
+		}
+
+		line, err := json.Marshal(result)
+		if err != nil {
+			streamErr = fmt.Errorf("failed to marshal result for %s: %w", result.RelativePath, err)
+			cancel()
+			continue
+		}
+		if err := p.writer.Write(string(line) + "\n"); err != nil {
+			streamErr = fmt.Errorf("failed to write result stream: %w", err)
+			cancel()
+			continue
+		}
+		p.updateStats(result)
+	}
+
+	if streamErr != nil {
+		return streamErr
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	p.logger.Log("Streamed %d results to %s", utils.IconFile, p.stats.IncludedCount, p.config.OutputFile)
+	return nil
+}
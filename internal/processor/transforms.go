@@ -0,0 +1,322 @@
+package processor
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/richardamare/ai-digest/internal/utils"
+)
+
+// Content transform names accepted in ProcessorConfig.TransformOrder
+const (
+	TransformSignatures      = "signatures"
+	TransformCollapseImports = "collapse-imports"
+	TransformStripTrailingWS = "strip-trailing-ws"
+	TransformDedent          = "dedent"
+	TransformMarkdownPlain   = "markdown-to-plaintext"
+	TransformWrapMarkdown    = "wrap-markdown"
+	TransformWhitespace      = "whitespace-removal"
+)
+
+// DefaultTransformOrder is the sequence content transforms run in when no
+// explicit order is configured
+var DefaultTransformOrder = []string{
+	TransformSignatures,
+	TransformCollapseImports,
+	TransformStripTrailingWS,
+	TransformDedent,
+	TransformMarkdownPlain,
+	TransformWrapMarkdown,
+	TransformWhitespace,
+}
+
+// buildTransforms returns the enabled content transforms in the configured
+// order. Enabled transforms missing from the order run afterwards in their
+// default position.
+func buildTransforms(cfg ProcessorConfig) ([]ContentTransformer, error) {
+	available := map[string]ContentTransformer{}
+	if cfg.SignaturesOnly {
+		available[TransformSignatures] = extractSignatures
+	}
+	if cfg.CollapseImports {
+		available[TransformCollapseImports] = collapseImports
+	}
+	if cfg.StripTrailingWS {
+		available[TransformStripTrailingWS] = stripTrailingWS
+	}
+	if cfg.Dedent {
+		available[TransformDedent] = dedent
+	}
+	if cfg.MarkdownPlainText {
+		available[TransformMarkdownPlain] = markdownToPlainText
+	}
+	if cfg.WrapMarkdown > 0 {
+		available[TransformWrapMarkdown] = wrapMarkdown(cfg.WrapMarkdown)
+	}
+	if cfg.RemoveWhitespace {
+		available[TransformWhitespace] = removeWhitespace(cfg.PreserveBlankLines)
+	}
+
+	var order []string
+	for _, name := range cfg.TransformOrder {
+		if !slices.Contains(DefaultTransformOrder, name) {
+			return nil, fmt.Errorf("unknown content transform: %s", name)
+		}
+		if slices.Contains(order, name) {
+			return nil, fmt.Errorf("content transform listed twice: %s", name)
+		}
+		order = append(order, name)
+	}
+	for _, name := range DefaultTransformOrder {
+		if !slices.Contains(order, name) {
+			order = append(order, name)
+		}
+	}
+
+	var transforms []ContentTransformer
+	for _, name := range order {
+		if t, ok := available[name]; ok {
+			transforms = append(transforms, t)
+		}
+	}
+
+	return transforms, nil
+}
+
+// stripTrailingWS is a ContentTransformer that trims end-of-line whitespace
+var stripTrailingWS ContentTransformer = func(content string, ext string) string {
+	return utils.StripTrailingWhitespace(content)
+}
+
+// dedent is a ContentTransformer that strips common leading indentation from
+// files whose indentation isn't significant
+var dedent ContentTransformer = func(content string, ext string) string {
+	if utils.IsWhitespaceSensitive(ext) {
+		return content
+	}
+	return utils.Dedent(content)
+}
+
+// markdownToPlainText is a ContentTransformer that strips rendering-only
+// syntax from markdown files, keeping code intact
+var markdownToPlainText ContentTransformer = func(content string, ext string) string {
+	if ext != ".md" && ext != ".markdown" {
+		return content
+	}
+	return utils.MarkdownToPlainText(content)
+}
+
+// wrapMarkdown returns a ContentTransformer that reflows prose paragraphs in
+// markdown files to width columns
+func wrapMarkdown(width int) ContentTransformer {
+	return func(content string, ext string) string {
+		if ext != ".md" && ext != ".markdown" {
+			return content
+		}
+		return utils.WrapMarkdown(content, width)
+	}
+}
+
+// removeWhitespace returns a ContentTransformer that caps blank-line runs at
+// preserveBlank, or collapses all whitespace when preserveBlank is 0.
+// Whitespace-sensitive files are left alone either way.
+func removeWhitespace(preserveBlank int) ContentTransformer {
+	return func(content string, ext string) string {
+		if utils.IsWhitespaceSensitive(ext) {
+			return content
+		}
+		if preserveBlank > 0 {
+			return utils.CapBlankLines(content, preserveBlank)
+		}
+		return utils.RemoveWhitespace(content)
+	}
+}
+
+// importSpan is the extent of a parsed import statement
+type importSpan struct {
+	end   int // Index after the statement's last line
+	count int // Number of imports declared
+}
+
+// importParser reports whether lines[i] starts an import statement
+type importParser func(lines []string, i int) (importSpan, bool)
+
+var (
+	goImportBlockRegex  = regexp.MustCompile(`^import\s*\($`)
+	goImportLineRegex   = regexp.MustCompile(`^import\s+(\w+\s+|\.\s+|_\s+)?"[^"]*"$`)
+	jsImportRegex       = regexp.MustCompile(`^import[\s{*'"]`)
+	jsImportEndRegex    = regexp.MustCompile(`(\bfrom\s*['"][^'"]*['"]|^import\s*['"][^'"]*['"])\s*;?$`)
+	jsRequireRegex      = regexp.MustCompile(`^(const|let|var)\s+[\w{}\s,:]+=\s*require\(\s*['"][^'"]*['"]\s*\)\s*;?$`)
+	pyImportRegex       = regexp.MustCompile(`^(import\s+[\w.]+|from\s+[\w.]+\s+import\s+)`)
+	importLanguageByExt = map[string]importParser{
+		".go":  parseGoImport,
+		".js":  parseJSImport,
+		".jsx": parseJSImport,
+		".mjs": parseJSImport,
+		".cjs": parseJSImport,
+		".ts":  parseJSImport,
+		".tsx": parseJSImport,
+		".py":  parsePythonImport,
+	}
+)
+
+// collapseImports is a ContentTransformer that replaces the leading import
+// section of supported languages with a single marker
+var collapseImports ContentTransformer = func(content string, ext string) string {
+	parse, ok := importLanguageByExt[ext]
+	if !ok {
+		return content
+	}
+
+	lines := strings.Split(content, "\n")
+	start, end, count := -1, -1, 0
+
+	for i := 0; i < len(lines); {
+		if next, ok := parse(lines, i); ok {
+			if start < 0 {
+				start = i
+			}
+			end = next.end
+			count += next.count
+			i = next.end
+			continue
+		}
+
+		if next := skipPreamble(lines, i, ext); next > i {
+			i = next
+			continue
+		}
+
+		break
+	}
+
+	if start < 0 {
+		return content
+	}
+
+	marker := fmt.Sprintf("[%d imports collapsed]", count)
+	collapsed := append(append(append([]string{}, lines[:start]...), marker), lines[end:]...)
+	return strings.Join(collapsed, "\n")
+}
+
+// skipPreamble returns the index after any blank, comment or header lines
+// that may precede or separate imports, or i if lines[i] is code
+func skipPreamble(lines []string, i int, ext string) int {
+	line := strings.TrimSpace(lines[i])
+
+	switch {
+	case line == "":
+		return i + 1
+	case strings.HasPrefix(line, "//"):
+		return i + 1
+	case strings.HasPrefix(line, "/*"):
+		for j := i; j < len(lines); j++ {
+			if strings.Contains(lines[j], "*/") {
+				return j + 1
+			}
+		}
+		return len(lines)
+	}
+
+	switch ext {
+	case ".go":
+		if strings.HasPrefix(line, "package ") {
+			return i + 1
+		}
+	case ".py":
+		if strings.HasPrefix(line, "#") {
+			return i + 1
+		}
+		for _, quote := range []string{`"""`, `'''`} {
+			if strings.HasPrefix(line, quote) {
+				if strings.Count(line, quote) >= 2 {
+					return i + 1
+				}
+				for j := i + 1; j < len(lines); j++ {
+					if strings.Contains(lines[j], quote) {
+						return j + 1
+					}
+				}
+				return len(lines)
+			}
+		}
+	default:
+		if line == `"use strict";` || line == `'use strict';` ||
+			line == `"use strict"` || line == `'use strict'` {
+			return i + 1
+		}
+	}
+
+	return i
+}
+
+// parseGoImport matches single-line and grouped Go imports
+func parseGoImport(lines []string, i int) (importSpan, bool) {
+	line := strings.TrimSpace(lines[i])
+
+	if goImportLineRegex.MatchString(line) {
+		return importSpan{end: i + 1, count: 1}, true
+	}
+
+	if !goImportBlockRegex.MatchString(line) {
+		return importSpan{}, false
+	}
+
+	count := 0
+	for j := i + 1; j < len(lines); j++ {
+		spec := strings.TrimSpace(lines[j])
+		if spec == ")" {
+			return importSpan{end: j + 1, count: count}, true
+		}
+		if spec != "" && !strings.HasPrefix(spec, "//") {
+			count++
+		}
+	}
+
+	return importSpan{}, false
+}
+
+// parseJSImport matches ES module imports, possibly multi-line, and require calls
+func parseJSImport(lines []string, i int) (importSpan, bool) {
+	line := strings.TrimSpace(lines[i])
+
+	if jsRequireRegex.MatchString(line) {
+		return importSpan{end: i + 1, count: 1}, true
+	}
+
+	if !jsImportRegex.MatchString(line) {
+		return importSpan{}, false
+	}
+
+	for j := i; j < len(lines); j++ {
+		if jsImportEndRegex.MatchString(strings.TrimSpace(lines[j])) {
+			return importSpan{end: j + 1, count: 1}, true
+		}
+	}
+
+	return importSpan{}, false
+}
+
+// parsePythonImport matches import and from-import statements, including
+// parenthesized multi-line forms
+func parsePythonImport(lines []string, i int) (importSpan, bool) {
+	line := strings.TrimSpace(lines[i])
+
+	if !pyImportRegex.MatchString(line) {
+		return importSpan{}, false
+	}
+
+	if !strings.HasSuffix(line, "(") {
+		return importSpan{end: i + 1, count: 1}, true
+	}
+
+	for j := i + 1; j < len(lines); j++ {
+		if strings.Contains(lines[j], ")") {
+			return importSpan{end: j + 1, count: 1}, true
+		}
+	}
+
+	return importSpan{}, false
+}
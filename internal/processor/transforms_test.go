@@ -0,0 +1,142 @@
+package processor
+
+import "testing"
+
+func TestRemoveWhitespaceTransform(t *testing.T) {
+	const source = "a  b\n\n\n\nc\n"
+
+	tests := []struct {
+		name     string
+		preserve int
+		ext      string
+		want     string
+	}{
+		{"zero value collapses", 0, ".go", "a b c"},
+		{"cap at one", 1, ".go", "a  b\n\nc\n"},
+		{"cap at two", 2, ".go", "a  b\n\n\nc\n"},
+		{"whitespace sensitive untouched", 0, ".py", source},
+		{"whitespace sensitive not capped", 1, ".yaml", source},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transforms, err := buildTransforms(ProcessorConfig{RemoveWhitespace: true, PreserveBlankLines: tt.preserve})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(transforms) != 1 {
+				t.Fatalf("got %d transforms, want 1", len(transforms))
+			}
+			if got := transforms[0](source, tt.ext); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// applyTransforms runs content through the transforms built from cfg
+func applyTransforms(t *testing.T, cfg ProcessorConfig, content, ext string) string {
+	t.Helper()
+	transforms, err := buildTransforms(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, transform := range transforms {
+		content = transform(content, ext)
+	}
+	return content
+}
+
+func TestDefaultTransformOrder(t *testing.T) {
+	const source = "package main\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\n\tfunc main() {   \n\n\n\n\t\tfmt.Println(os.Args)\n\t}\n"
+	cfg := ProcessorConfig{CollapseImports: true, StripTrailingWS: true, Dedent: true, RemoveWhitespace: true, PreserveBlankLines: 1}
+
+	// The sequence processTextFile hard-coded before the order was configurable
+	want := removeWhitespace(1)(dedent(stripTrailingWS(collapseImports(source, ".go"), ".go"), ".go"), ".go")
+
+	if got := applyTransforms(t, cfg, source, ".go"); got != want {
+		t.Errorf("default order = %q, want %q", got, want)
+	}
+}
+
+func TestTransformOrder(t *testing.T) {
+	const source = "**bold words here** and more words\n"
+	cfg := ProcessorConfig{MarkdownPlainText: true, WrapMarkdown: 20}
+
+	plainFirst := cfg
+	plainFirst.TransformOrder = []string{TransformMarkdownPlain, TransformWrapMarkdown}
+	wrapFirst := cfg
+	wrapFirst.TransformOrder = []string{TransformWrapMarkdown}
+
+	tests := []struct {
+		name string
+		cfg  ProcessorConfig
+		want string
+	}{
+		{"default", cfg, "bold words here and\nmore words\n"},
+		{"plaintext first", plainFirst, "bold words here and\nmore words\n"},
+		{"wrap first", wrapFirst, "bold words here\nand more words\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := applyTransforms(t, tt.cfg, source, ".md"); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	for _, order := range [][]string{{"minify"}, {TransformDedent, TransformDedent}} {
+		if _, err := buildTransforms(ProcessorConfig{TransformOrder: order}); err == nil {
+			t.Errorf("buildTransforms with order %q succeeded, want error", order)
+		}
+	}
+}
+
+func TestCollapseImports(t *testing.T) {
+	tests := []struct {
+		name    string
+		ext     string
+		content string
+		want    string
+	}{
+		{
+			"go single and grouped",
+			".go",
+			"package main\n\nimport \"fmt\"\n\nimport (\n\t\"os\"\n\tstr \"strings\"\n)\n\nfunc main() {}\n",
+			"package main\n\n[3 imports collapsed]\n\nfunc main() {}\n",
+		},
+		{
+			"javascript multi-line",
+			".js",
+			"'use strict';\nimport a from 'a';\nimport {\n  b,\n  c,\n} from 'bc';\n\nconst x = 1;\n",
+			"'use strict';\n[2 imports collapsed]\n\nconst x = 1;\n",
+		},
+		{
+			"python after docstring",
+			".py",
+			"\"\"\"Doc.\"\"\"\nimport os\nfrom sys import argv\n\nprint(argv)\n",
+			"\"\"\"Doc.\"\"\"\n[2 imports collapsed]\n\nprint(argv)\n",
+		},
+		{
+			"no imports",
+			".go",
+			"package main\n\nfunc main() {}\n",
+			"package main\n\nfunc main() {}\n",
+		},
+		{
+			"unsupported language",
+			".rs",
+			"use std::io;\n\nfn main() {}\n",
+			"use std::io;\n\nfn main() {}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := collapseImports(tt.content, tt.ext); got != tt.want {
+				t.Errorf("collapseImports() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
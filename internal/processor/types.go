@@ -27,13 +27,34 @@ type Stats struct {
 	IncludedFiles       []string
 }
 
-// FileResult represents the result of processing a single file
+// TypeLimit restricts files with a given extension during collection
+type TypeLimit struct {
+	MaxSize  int64 // Maximum file size in bytes, 0 for no limit
+	MaxDepth int   // Maximum path depth, 1 for top level only, 0 for no limit
+}
+
+// FileResult represents the result of processing a single file. The JSON
+// form is part of the results document contract, see ResultsDocument.
 type FileResult struct {
-	RelativePath string
-	Content      string
-	FileType     string
-	Size         int64
-	Error        error
+	RelativePath   string `json:"path"`
+	Index          int    `json:"index"` // 1-based position among the collected files
+	Content        string `json:"content,omitempty"`
+	Header         string `json:"header,omitempty"` // File header as emitted, without metadata
+	FileType       string `json:"fileType,omitempty"`
+	MIMEType       string `json:"mimeType,omitempty"` // Content type sniffed from the file head
+	Size           int64  `json:"size"`
+	EmbeddedBOM    bool   `json:"embeddedBOM,omitempty"`    // Content contained non-leading UTF-8 BOMs
+	Truncated      bool   `json:"truncated,omitempty"`      // Content was cut to the per-file token limit
+	Summarized     bool   `json:"summarized,omitempty"`     // Content was replaced by an extractive summary
+	Embedded       bool   `json:"embedded,omitempty"`       // Binary content was embedded as base64
+	SymlinkTarget  string `json:"symlinkTarget,omitempty"`  // Target of a symlink noted instead of followed
+	Filtered       bool   `json:"filtered,omitempty"`       // File was dropped by a content filter
+	Pruned         bool   `json:"pruned,omitempty"`         // File was dropped as noise (minified, generated or too large)
+	Generated      bool   `json:"generated,omitempty"`      // File was dropped for a generated-file header
+	Tokens         int    `json:"tokens,omitempty"`         // Estimated content tokens, set when comparing against a baseline
+	BaselineTokens int    `json:"baselineTokens,omitempty"` // Estimated content tokens before transforms
+	Hash           string `json:"hash,omitempty"`           // Content hash, set when duplicate reporting is enabled
+	Error          error  `json:"-"`
 }
 
 // FileProcessor handles a single file processing operation
@@ -0,0 +1,220 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/binary"
+	"hash/fnv"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DefaultCloneMinLines is the number of consecutive significant lines two
+// blocks must share to be reported as clones
+const DefaultCloneMinLines = 6
+
+// CloneLocation is one copy of a duplicated block, with 1-based inclusive
+// line numbers
+type CloneLocation struct {
+	Path      string
+	StartLine int
+	EndLine   int
+}
+
+// CloneCluster is a block of code found at two or more locations
+type CloneCluster struct {
+	Lines     int // Significant lines in the block, ignoring blank and brace-only lines
+	Locations []CloneLocation
+}
+
+// cloneSource is a scanned file reduced to hashes of its significant lines
+type cloneSource struct {
+	path   string
+	hashes []uint64
+	lines  []int // Original line number of each hash
+}
+
+// cloneWindow identifies the window of lines starting at a significant line
+type cloneWindow struct {
+	source int
+	start  int
+}
+
+// CloneFinder detects duplicated blocks across files by hashing windows of
+// normalized lines. Lines are compared with surrounding whitespace trimmed
+// and inner runs collapsed, and lines without letters or digits are skipped.
+type CloneFinder struct {
+	minLines int
+	sources  []cloneSource
+}
+
+// NewCloneFinder creates a finder reporting blocks of at least minLines
+// significant lines, or DefaultCloneMinLines if minLines is not positive
+func NewCloneFinder(minLines int) *CloneFinder {
+	if minLines <= 0 {
+		minLines = DefaultCloneMinLines
+	}
+	return &CloneFinder{minLines: minLines}
+}
+
+// Add reads the file at path from r. Only line hashes are kept.
+func (f *CloneFinder) Add(path string, r io.Reader) error {
+	source := cloneSource{path: path}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := normalizeCloneLine(scanner.Text())
+		if text == "" {
+			continue
+		}
+		h := fnv.New64a()
+		h.Write([]byte(text))
+		source.hashes = append(source.hashes, h.Sum64())
+		source.lines = append(source.lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if len(source.hashes) >= f.minLines {
+		f.sources = append(f.sources, source)
+	}
+	return nil
+}
+
+// Clusters returns the duplicated blocks found so far, longest first. A
+// block keeps growing for as long as all of its copies continue to match,
+// so two identical files form one cluster even when a third file shares
+// only part of them.
+func (f *CloneFinder) Clusters() []CloneCluster {
+	groups := make(map[uint64][]cloneWindow)
+	lastSeen := make(map[uint64]cloneWindow)
+	for i, source := range f.sources {
+		for start := 0; start+f.minLines <= len(source.hashes); start++ {
+			key := f.windowHash(source.hashes[start : start+f.minLines])
+			w := cloneWindow{source: i, start: start}
+			// A run of repeated lines matches itself at every offset, so
+			// windows overlapping the previous match continue that run
+			prev, seen := lastSeen[key]
+			lastSeen[key] = w
+			if seen && prev.source == i && start-prev.start < f.minLines {
+				continue
+			}
+			groups[key] = append(groups[key], w)
+		}
+	}
+
+	groupOf := make(map[cloneWindow]int)
+	var matches [][]cloneWindow
+	for _, windows := range groups {
+		if len(windows) < 2 {
+			continue
+		}
+		for _, w := range windows {
+			groupOf[w] = len(matches)
+		}
+		matches = append(matches, windows)
+	}
+
+	// Windows are visited in file order, so a block's windows are always
+	// extended before the windows that follow them are reached
+	var blocks []*cloneBlock
+	endingAt := make(map[cloneWindow][]*cloneBlock)
+	for i, source := range f.sources {
+		for start := range source.hashes {
+			w := cloneWindow{source: i, start: start}
+			g, ok := groupOf[w]
+			if !ok {
+				continue
+			}
+
+			covered := false
+			for _, b := range endingAt[cloneWindow{source: i, start: start - 1}] {
+				if b.group != g && b.extend(g, groupOf) {
+					for _, last := range b.last {
+						endingAt[last] = append(endingAt[last], b)
+					}
+				}
+				if b.group == g && len(b.last) == len(matches[g]) {
+					covered = true
+				}
+			}
+
+			if w == matches[g][0] && !covered {
+				b := &cloneBlock{first: matches[g], last: matches[g], group: g, lines: f.minLines}
+				blocks = append(blocks, b)
+				for _, last := range b.last {
+					endingAt[last] = append(endingAt[last], b)
+				}
+			}
+		}
+	}
+
+	clusters := make([]CloneCluster, len(blocks))
+	for i, b := range blocks {
+		clusters[i].Lines = b.lines
+		for _, w := range b.first {
+			source := f.sources[w.source]
+			clusters[i].Locations = append(clusters[i].Locations, CloneLocation{
+				Path:      source.path,
+				StartLine: source.lines[w.start],
+				EndLine:   source.lines[w.start+b.lines-1],
+			})
+		}
+	}
+
+	sort.SliceStable(clusters, func(a, b int) bool {
+		return clusters[a].Lines > clusters[b].Lines
+	})
+	return clusters
+}
+
+// cloneBlock is a duplicated block being grown one line at a time
+type cloneBlock struct {
+	first []cloneWindow // Windows the block starts at
+	last  []cloneWindow // Windows the block currently ends at
+	group int           // Match group of last
+	lines int
+}
+
+// extend grows the block by one line if every copy's next window is in
+// group g
+func (b *cloneBlock) extend(g int, groupOf map[cloneWindow]int) bool {
+	next := make([]cloneWindow, len(b.last))
+	for i, w := range b.last {
+		next[i] = cloneWindow{source: w.source, start: w.start + 1}
+		if group, ok := groupOf[next[i]]; !ok || group != g {
+			return false
+		}
+	}
+	b.last = next
+	b.group = g
+	b.lines++
+	return true
+}
+
+// windowHash combines the line hashes of one window
+func (f *CloneFinder) windowHash(hashes []uint64) uint64 {
+	h := fnv.New64a()
+	var buf [8]byte
+	for _, line := range hashes {
+		binary.LittleEndian.PutUint64(buf[:], line)
+		h.Write(buf[:])
+	}
+	return h.Sum64()
+}
+
+// normalizeCloneLine trims line and collapses inner whitespace, returning ""
+// for lines with no letters or digits
+func normalizeCloneLine(line string) string {
+	if strings.IndexFunc(line, func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	}) < 0 {
+		return ""
+	}
+	return strings.Join(strings.Fields(line), " ")
+}
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const cloneFunc = `func Normalize(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.ToLower(s)
+	if s == "" {
+		return "empty"
+	}
+	parts := strings.Fields(s)
+	return strings.Join(parts, "-")
+}
+`
+
+func TestCloneFinder(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		order []string
+		want  []CloneCluster
+	}{
+		{
+			name: "function copied across files",
+			files: map[string]string{
+				"a.go": "package a\n\n" + cloneFunc,
+				"b.go": "package b\n\n// Normalize was copied from a\n" + strings.ReplaceAll(cloneFunc, "\t", "    "),
+			},
+			order: []string{"a.go", "b.go"},
+			want: []CloneCluster{{Lines: 7, Locations: []CloneLocation{
+				{Path: "a.go", StartLine: 3, EndLine: 10},
+				{Path: "b.go", StartLine: 4, EndLine: 11},
+			}}},
+		},
+		{
+			name: "identical files with a partial third copy",
+			files: map[string]string{
+				"a.go": "package a\n\n" + cloneFunc,
+				"b.go": "package a\n\n" + cloneFunc,
+				"c.go": "package c\n\nimport \"strings\"\n\n" + cloneFunc,
+			},
+			order: []string{"a.go", "b.go", "c.go"},
+			want: []CloneCluster{
+				{Lines: 8, Locations: []CloneLocation{
+					{Path: "a.go", StartLine: 1, EndLine: 10},
+					{Path: "b.go", StartLine: 1, EndLine: 10},
+				}},
+				{Lines: 7, Locations: []CloneLocation{
+					{Path: "a.go", StartLine: 3, EndLine: 10},
+					{Path: "b.go", StartLine: 3, EndLine: 10},
+					{Path: "c.go", StartLine: 5, EndLine: 12},
+				}},
+			},
+		},
+		{
+			name: "repeated lines in one file",
+			files: map[string]string{
+				"r.py": strings.Repeat("x = compute(1)\n", 20),
+			},
+			order: []string{"r.py"},
+			want:  []CloneCluster{},
+		},
+		{
+			name: "function repeated in one file",
+			files: map[string]string{
+				"d.go": "package d\n\n" + cloneFunc + cloneFunc,
+			},
+			order: []string{"d.go"},
+			want: []CloneCluster{{Lines: 7, Locations: []CloneLocation{
+				{Path: "d.go", StartLine: 3, EndLine: 10},
+				{Path: "d.go", StartLine: 12, EndLine: 19},
+			}}},
+		},
+		{
+			name: "short matches ignored",
+			files: map[string]string{
+				"a.go": "package a\n\nfunc A() int { return 1 }\n",
+				"b.go": "package a\n\nfunc A() int { return 1 }\n",
+			},
+			order: []string{"a.go", "b.go"},
+			want:  []CloneCluster{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finder := NewCloneFinder(0)
+			for _, path := range tt.order {
+				if err := finder.Add(path, strings.NewReader(tt.files[path])); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got := finder.Clusters()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Clusters() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
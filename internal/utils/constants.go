@@ -12,6 +12,152 @@ var WhitespaceDependentExtensions = map[string]bool{
 	".pug":    true, // Pug
 	".styl":   true, // Stylus
 	".gd":     true, // Godot
+	".mk":     true, // Makefile recipes must start with a tab
+}
+
+// languageFileNames maps well-known extensionless file names to the
+// extension of their language
+var languageFileNames = map[string]string{
+	"Makefile":    ".mk",
+	"makefile":    ".mk",
+	"GNUmakefile": ".mk",
+	"Snakefile":   ".py",
+	"SConstruct":  ".py",
+	"SConscript":  ".py",
+}
+
+// shebangInterpreters maps script interpreters to the extension of their language
+var shebangInterpreters = map[string]string{
+	"python":  ".py",
+	"python2": ".py",
+	"python3": ".py",
+	"sh":      ".sh",
+	"bash":    ".sh",
+	"zsh":     ".sh",
+	"dash":    ".sh",
+	"ksh":     ".sh",
+	"node":    ".js",
+	"deno":    ".ts",
+	"ruby":    ".rb",
+	"perl":    ".pl",
+	"php":     ".php",
+	"lua":     ".lua",
+	"make":    ".mk",
+}
+
+// TestFilePatterns match test files and directories by common per-language
+// conventions, used to drop tests from production-focused digests
+var TestFilePatterns = []string{
+	// Directories
+	"tests/",
+	"__tests__/",
+	// Go
+	"*_test.go",
+	// JavaScript and TypeScript
+	"*.test.js",
+	"*.test.jsx",
+	"*.test.mjs",
+	"*.test.cjs",
+	"*.test.ts",
+	"*.test.tsx",
+	"*.spec.js",
+	"*.spec.jsx",
+	"*.spec.ts",
+	"*.spec.tsx",
+	// Python
+	"test_*.py",
+	"*_test.py",
+	"conftest.py",
+	// Ruby
+	"*_spec.rb",
+	"*_test.rb",
+	// Java, Kotlin and C#
+	"*Test.java",
+	"*Tests.java",
+	"*Test.kt",
+	"*Tests.cs",
+	// Rust, PHP and Swift
+	"*_test.rs",
+	"*Test.php",
+	"*Tests.swift",
+}
+
+// NoiseIgnores extends DefaultIgnores with lockfiles, build artifacts and
+// data files that rarely help an AI understand a codebase
+var NoiseIgnores = []string{
+	// Lockfiles
+	"*.lock",
+	"npm-shrinkwrap.json",
+	"packages.lock.json",
+	// Build artifacts
+	"*.map",
+	"*.min.js",
+	"*.min.css",
+	"/coverage/",
+	".nyc_output",
+	"/out/",
+	"*.o",
+	"*.a",
+	"*.jar",
+	"*.war",
+	// Data files
+	"*.csv",
+	"*.tsv",
+	"*.parquet",
+	"*.sqlite",
+	"*.sqlite3",
+	"*.db",
+	"*.ndjson",
+}
+
+// VendoredIgnores extends DefaultIgnores with the directories package
+// managers across ecosystems use for vendored or installed dependencies.
+// Generic names that also hold first-party code, such as packages/ in
+// JavaScript monorepos or Elixir's deps/, are left out.
+var VendoredIgnores = []string{
+	// C, C++ and Go
+	"third_party/",
+	"third-party/",
+	"thirdparty/",
+	"Godeps/_workspace/",
+	// JavaScript
+	"bower_components/",
+	"jspm_packages/",
+	"web_modules/",
+	".yarn/cache/",
+	".yarn/unplugged/",
+	// Python
+	".venv*/",
+	"venv*/",
+	".virtualenv/",
+	".tox/",
+	".nox/",
+	".eggs/",
+	"__pypackages__/",
+	"site-packages/",
+	// iOS and Swift
+	"Pods/",
+	"Carthage/Checkouts/",
+	"Carthage/Build/",
+	".build/checkouts/",
+	// Dart
+	".dart_tool/",
+	".pub-cache/",
+	// Lua
+	"lua_modules/",
+	".luarocks/",
+	// R
+	"renv/library/",
+	"packrat/lib*/",
+}
+
+// EnvExampleIncludes re-includes shareable env templates such as
+// .env.example, which the default .env patterns would otherwise ignore
+var EnvExampleIncludes = []string{
+	"!.env.example",
+	"!.env.sample",
+	"!*.env.example",
+	"!*.env.sample",
 }
 
 // DefaultIgnores defines patterns to ignore by default
@@ -0,0 +1,271 @@
+package utils
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// EditorConfigFile is the name of the files read by EditorConfig
+const EditorConfigFile = ".editorconfig"
+
+// editorConfigSection is one [glob] section of an .editorconfig file
+type editorConfigSection struct {
+	pattern    *regexp.Regexp
+	properties map[string]string
+}
+
+// editorConfigFile is a parsed .editorconfig file
+type editorConfigFile struct {
+	root     bool
+	sections []editorConfigSection
+}
+
+// EditorConfig resolves .editorconfig properties for files, caching parsed
+// files by directory. It is safe for concurrent use.
+type EditorConfig struct {
+	mu    sync.Mutex
+	files map[string]*editorConfigFile // Parsed file by directory, nil if absent
+}
+
+// NewEditorConfig creates an empty .editorconfig resolver
+func NewEditorConfig() *EditorConfig {
+	return &EditorConfig{files: make(map[string]*editorConfigFile)}
+}
+
+// Properties returns the properties that apply to path, merging
+// .editorconfig files from the filesystem root down to the file's directory
+// and stopping at the first one declaring root = true
+func (e *EditorConfig) Properties(path string) (map[string]string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Collect applicable files, nearest first
+	var dirs []string
+	var files []*editorConfigFile
+	for dir := filepath.Dir(absPath); ; dir = filepath.Dir(dir) {
+		file, err := e.load(dir)
+		if err != nil {
+			return nil, err
+		}
+		if file != nil {
+			dirs = append(dirs, dir)
+			files = append(files, file)
+			if file.root {
+				break
+			}
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			break
+		}
+	}
+
+	// Apply farthest first so nearer files and later sections win
+	properties := make(map[string]string)
+	for i := len(files) - 1; i >= 0; i-- {
+		rel, err := filepath.Rel(dirs[i], absPath)
+		if err != nil {
+			return nil, err
+		}
+		rel = filepath.ToSlash(rel)
+
+		for _, section := range files[i].sections {
+			if section.pattern.MatchString(rel) {
+				for key, value := range section.properties {
+					properties[key] = value
+				}
+			}
+		}
+	}
+
+	return properties, nil
+}
+
+// load returns the parsed .editorconfig in dir, or nil if there is none
+func (e *EditorConfig) load(dir string) (*editorConfigFile, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if file, ok := e.files[dir]; ok {
+		return file, nil
+	}
+
+	file, err := parseEditorConfig(filepath.Join(dir, EditorConfigFile))
+	if err != nil {
+		return nil, err
+	}
+	e.files[dir] = file
+	return file, nil
+}
+
+// parseEditorConfig reads an .editorconfig file, returning nil if it doesn't exist
+func parseEditorConfig(path string) (*editorConfigFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	file := &editorConfigFile{}
+	var current *editorConfigSection
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			pattern, err := editorConfigGlob(line[1 : len(line)-1])
+			if err != nil {
+				// Sections with unparsable globs never match
+				current = nil
+				continue
+			}
+			file.sections = append(file.sections, editorConfigSection{pattern: pattern, properties: make(map[string]string)})
+			current = &file.sections[len(file.sections)-1]
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.ToLower(strings.TrimSpace(value))
+
+		if current == nil {
+			if key == "root" {
+				file.root = value == "true"
+			}
+			continue
+		}
+		current.properties[key] = value
+	}
+
+	return file, scanner.Err()
+}
+
+var editorConfigRangeRegex = regexp.MustCompile(`^\{(-?\d+)\.\.(-?\d+)\}`)
+
+// editorConfigGlob converts an EditorConfig section glob to a regular
+// expression matched against slash-separated paths relative to the
+// .editorconfig file. Globs without a slash match at any depth.
+func editorConfigGlob(glob string) (*regexp.Regexp, error) {
+	if strings.HasPrefix(glob, "/") {
+		glob = glob[1:]
+	} else if !strings.Contains(glob, "/") {
+		glob = "**/" + glob
+	}
+
+	var buf strings.Builder
+	buf.WriteString("^")
+	braces := 0
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					i++
+					buf.WriteString("(.*/)?")
+				} else {
+					buf.WriteString(".*")
+				}
+			} else {
+				buf.WriteString("[^/]*")
+			}
+		case '?':
+			buf.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				buf.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			buf.WriteString("[" + class + "]")
+			i += end
+		case '{':
+			if m := editorConfigRangeRegex.FindStringSubmatch(glob[i:]); m != nil {
+				lo, _ := strconv.Atoi(m[1])
+				hi, _ := strconv.Atoi(m[2])
+				if lo > hi {
+					lo, hi = hi, lo
+				}
+				var nums []string
+				for n := lo; n <= hi; n++ {
+					nums = append(nums, strconv.Itoa(n))
+				}
+				buf.WriteString("(" + strings.Join(nums, "|") + ")")
+				i += len(m[0]) - 1
+				continue
+			}
+			braces++
+			buf.WriteString("(")
+		case '}':
+			if braces > 0 {
+				braces--
+				buf.WriteString(")")
+			} else {
+				buf.WriteString(`\}`)
+			}
+		case ',':
+			if braces > 0 {
+				buf.WriteString("|")
+			} else {
+				buf.WriteString(",")
+			}
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				buf.WriteString(regexp.QuoteMeta(string(glob[i])))
+			}
+		default:
+			buf.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	buf.WriteString("$")
+
+	return regexp.Compile(buf.String())
+}
+
+// ApplyEditorConfig normalizes content using the trim_trailing_whitespace
+// and insert_final_newline properties
+func ApplyEditorConfig(content string, properties map[string]string) string {
+	if properties["trim_trailing_whitespace"] == "true" {
+		lines := strings.Split(content, "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRight(line, " \t\r")
+			if strings.HasSuffix(line, "\r") {
+				lines[i] += "\r"
+			}
+		}
+		content = strings.Join(lines, "\n")
+	}
+
+	switch properties["insert_final_newline"] {
+	case "true":
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+	case "false":
+		content = strings.TrimRight(content, "\r\n")
+	}
+
+	return content
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestEditorConfigGlob(t *testing.T) {
+	tests := []struct {
+		glob  string
+		path  string
+		match bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "pkg/main.go", true},
+		{"*.go", "pkg/main.py", false},
+		{"/*.go", "pkg/main.go", false},
+		{"lib/**.js", "lib/a/b.js", true},
+		{"*.{js,ts}", "app.ts", true},
+		{"file{1..3}.txt", "file2.txt", true},
+		{"file{1..3}.txt", "file4.txt", false},
+		{"[!a]*.md", "b.md", true},
+		{"[!a]*.md", "a.md", false},
+	}
+
+	for _, tt := range tests {
+		re, err := editorConfigGlob(tt.glob)
+		if err != nil {
+			t.Fatalf("editorConfigGlob(%q): %v", tt.glob, err)
+		}
+		if got := re.MatchString(tt.path); got != tt.match {
+			t.Errorf("%q matches %q = %v, want %v", tt.glob, tt.path, got, tt.match)
+		}
+	}
+}
+
+func TestEditorConfigProperties(t *testing.T) {
+	h := NewTestHelper(t)
+	defer h.Cleanup()
+	h.CreateTempFile("repo/.editorconfig", "root = true\n\n[*]\ninsert_final_newline = true\n\n[*.md]\ntrim_trailing_whitespace = false\n")
+	h.CreateTempFile("repo/docs/.editorconfig", "[*.md]\ninsert_final_newline = False\n")
+	path := h.CreateTempFile("repo/docs/guide.md", "text")
+
+	got, err := NewEditorConfig().Properties(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["insert_final_newline"] != "false" || got["trim_trailing_whitespace"] != "false" {
+		t.Errorf("Properties() = %v, want the nearer file to win", got)
+	}
+
+	got, err = NewEditorConfig().Properties(filepath.Join(filepath.Dir(path), "..", "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["insert_final_newline"] != "true" || len(got) != 1 {
+		t.Errorf("Properties() = %v, want only the root file's [*] section", got)
+	}
+}
+
+func TestApplyEditorConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		properties map[string]string
+		want       string
+	}{
+		{"none", "a  \nb", nil, "a  \nb"},
+		{"trim", "a  \r\nb\t\n", map[string]string{"trim_trailing_whitespace": "true"}, "a\r\nb\n"},
+		{"insert newline", "a", map[string]string{"insert_final_newline": "true"}, "a\n"},
+		{"remove newline", "a\r\n\n", map[string]string{"insert_final_newline": "false"}, "a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ApplyEditorConfig(tt.content, tt.properties); got != tt.want {
+				t.Errorf("ApplyEditorConfig() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
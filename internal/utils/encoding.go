@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encoding names reported for transcoded files
+const (
+	EncodingUTF8    = "utf-8"
+	EncodingUTF16LE = "utf-16le"
+	EncodingUTF16BE = "utf-16be"
+)
+
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+)
+
+// DecodeText converts raw file content to a UTF-8 string based on its BOM.
+// It returns the name of the source encoding, or an empty string when the
+// content was already UTF-8 and needed no transcoding. A UTF-8 BOM is
+// stripped without being reported.
+func DecodeText(data []byte) (string, string, error) {
+	switch {
+	case bytes.HasPrefix(data, utf8BOM):
+		data = data[len(utf8BOM):]
+	case bytes.HasPrefix(data, utf16LEBOM):
+		return decodeUTF16(data[len(utf16LEBOM):], binary.LittleEndian), EncodingUTF16LE, nil
+	case bytes.HasPrefix(data, utf16BEBOM):
+		return decodeUTF16(data[len(utf16BEBOM):], binary.BigEndian), EncodingUTF16BE, nil
+	}
+
+	if !utf8.Valid(data) {
+		return "", "", fmt.Errorf("invalid UTF-8 characters")
+	}
+	return string(data), "", nil
+}
+
+// FormatEncodingNote returns a header annotation describing a transcoding
+func FormatEncodingNote(encoding string) string {
+	if encoding == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (%s%s%s)", encoding, EncodingArrow, EncodingUTF8)
+}
+
+// IsSupportedOutputEncoding reports whether output can be written in encoding
+func IsSupportedOutputEncoding(encoding string) bool {
+	switch encoding {
+	case EncodingUTF8, EncodingUTF16LE, EncodingUTF16BE:
+		return true
+	default:
+		return false
+	}
+}
+
+// OutputBOM returns the byte order mark for an output encoding
+func OutputBOM(encoding string) []byte {
+	switch encoding {
+	case EncodingUTF16LE:
+		return utf16LEBOM
+	case EncodingUTF16BE:
+		return utf16BEBOM
+	default:
+		return utf8BOM
+	}
+}
+
+// EncodedLen returns the number of bytes s occupies in the output encoding
+func EncodedLen(s string, encoding string) int {
+	if encoding != EncodingUTF16LE && encoding != EncodingUTF16BE {
+		return len(s)
+	}
+
+	n := 0
+	for _, r := range s {
+		n += 2 * utf16.RuneLen(r)
+	}
+	return n
+}
+
+// NewEncodingWriter wraps w so UTF-8 input is transcoded to encoding.
+// UTF-8 output returns w unchanged.
+func NewEncodingWriter(w io.Writer, encoding string) io.Writer {
+	switch encoding {
+	case EncodingUTF16LE:
+		return &utf16Writer{w: w, order: binary.LittleEndian}
+	case EncodingUTF16BE:
+		return &utf16Writer{w: w, order: binary.BigEndian}
+	default:
+		return w
+	}
+}
+
+// utf16Writer transcodes a UTF-8 byte stream to UTF-16, carrying incomplete
+// sequences over to the next write
+type utf16Writer struct {
+	w       io.Writer
+	order   binary.AppendByteOrder
+	pending []byte
+}
+
+func (u *utf16Writer) Write(p []byte) (int, error) {
+	data := append(u.pending, p...)
+
+	// Hold back a trailing incomplete UTF-8 sequence
+	cut := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+
+	out := make([]byte, 0, cut*2)
+	for _, r := range string(data[:cut]) {
+		for _, unit := range utf16.AppendRune(nil, r) {
+			out = u.order.AppendUint16(out, unit)
+		}
+	}
+	u.pending = append([]byte(nil), data[cut:]...)
+
+	if _, err := u.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func decodeUTF16(data []byte, order binary.ByteOrder) string {
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		units = append(units, order.Uint16(data[i:]))
+	}
+	return string(utf16.Decode(units))
+}
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecodeText(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         []byte
+		want         string
+		wantEncoding string
+		wantErr      bool
+	}{
+		{"utf-8", []byte("héllo\n"), "héllo\n", "", false},
+		{"utf-8 bom stripped silently", []byte("\xef\xbb\xbfhéllo\n"), "héllo\n", "", false},
+		{"utf-16le", []byte{0xFF, 0xFE, 'h', 0, 0xE9, 0, 0x3D, 0xD8, 0x00, 0xDE}, "hé😀", EncodingUTF16LE, false},
+		{"utf-16be", []byte{0xFE, 0xFF, 0, 'h', 0, 0xE9, 0xD8, 0x3D, 0xDE, 0x00}, "hé😀", EncodingUTF16BE, false},
+		{"invalid utf-8", []byte("bad \xff byte"), "", "", true},
+		{"invalid utf-8 after bom", []byte("\xef\xbb\xbfbad \xff"), "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, encoding, err := DecodeText(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodeText() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want || encoding != tt.wantEncoding {
+				t.Errorf("DecodeText() = %q, %q, want %q, %q", got, encoding, tt.want, tt.wantEncoding)
+			}
+		})
+	}
+}
+
+func TestEncodingWriter(t *testing.T) {
+	text := "# main.go\n\nconst greeting = \"héllo, 世界 😀\"\n"
+
+	for _, encoding := range []string{EncodingUTF8, EncodingUTF16LE, EncodingUTF16BE} {
+		t.Run(encoding, func(t *testing.T) {
+			var buf bytes.Buffer
+			buf.Write(OutputBOM(encoding))
+			w := NewEncodingWriter(&buf, encoding)
+
+			// Write a byte at a time so multi-byte sequences are split
+			for i := range len(text) {
+				if _, err := w.Write([]byte{text[i]}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, _, err := DecodeText(buf.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != text {
+				t.Errorf("decoded output = %q, want %q", got, text)
+			}
+			if n := buf.Len() - len(OutputBOM(encoding)); n != EncodedLen(text, encoding) {
+				t.Errorf("wrote %d bytes, EncodedLen() = %d", n, EncodedLen(text, encoding))
+			}
+		})
+	}
+}
+
+func TestFormatEncodingNote(t *testing.T) {
+	if got := FormatEncodingNote(""); got != "" {
+		t.Errorf("FormatEncodingNote(\"\") = %q, want empty", got)
+	}
+	if got, want := FormatEncodingNote(EncodingUTF16LE), " (utf-16le → utf-8)"; got != want {
+		t.Errorf("FormatEncodingNote() = %q, want %q", got, want)
+	}
+}
@@ -1,10 +1,14 @@
 package utils
 
 import (
+	"bytes"
 	"io"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 )
 
@@ -35,30 +39,206 @@ var BinaryFileTypes = map[string]string{
 	".dylib": "Dynamic Library",
 }
 
+// FileTypeDetector tells text from binary files, consulting extension
+// overrides before BinaryFileTypes and content sniffing
+type FileTypeDetector struct {
+	treatAsText   map[string]bool
+	treatAsBinary map[string]bool
+	sampleSize    int // Leading bytes read to sniff the content
+}
+
+// NewFileTypeDetector creates a detector forcing files with the given
+// extensions to be treated as text or binary, and sampling sampleSize
+// leading bytes of other files, or DefaultBinarySampleSize if sampleSize
+// is not positive
+func NewFileTypeDetector(text, binary []string, sampleSize int) *FileTypeDetector {
+	if sampleSize < 1 {
+		sampleSize = DefaultBinarySampleSize
+	}
+	return &FileTypeDetector{
+		treatAsText:   extensionSet(text),
+		treatAsBinary: extensionSet(binary),
+		sampleSize:    sampleSize,
+	}
+}
+
+// defaultDetector classifies files without overrides
+var defaultDetector = NewFileTypeDetector(nil, nil, DefaultBinarySampleSize)
+
+// extensionSet normalizes extensions to lower case with a leading dot
+func extensionSet(exts []string) map[string]bool {
+	set := make(map[string]bool, len(exts))
+	for _, ext := range exts {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		set[ext] = true
+	}
+	return set
+}
+
+// DefaultBinarySampleSize is how many leading bytes are read to tell text
+// from binary files
+const DefaultBinarySampleSize = 512
+
 // IsTextFile checks if a file is a text file
 func IsTextFile(path string) (bool, error) {
+	return defaultDetector.IsTextFile(path)
+}
+
+// DetectFileType sniffs the MIME type of a file from its head and reports
+// whether it should be considered text
+func DetectFileType(path string) (string, bool, error) {
+	return defaultDetector.DetectFileType(path)
+}
+
+// IsTextFile checks if a file is a text file
+func (d *FileTypeDetector) IsTextFile(path string) (bool, error) {
+	_, isText, err := d.DetectFileType(path)
+	return isText, err
+}
+
+// DetectFileType sniffs the MIME type of a file from its head and reports
+// whether it should be considered text
+func (d *FileTypeDetector) DetectFileType(path string) (string, bool, error) {
 	f, err := os.Open(path)
 	if err != nil {
-		return false, err
+		return "", false, err
 	}
 	defer f.Close()
 
-	// Read first 512 bytes
-	buffer := make([]byte, 512)
-	n, err := f.Read(buffer)
-	if err != nil && err != io.EOF {
-		return false, err
+	buffer := make([]byte, d.sampleSize)
+	n, err := io.ReadFull(f, buffer)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", false, err
 	}
+	sample := buffer[:n]
 
 	// Check content type
-	contentType := http.DetectContentType(buffer[:n])
+	contentType := http.DetectContentType(sample)
+	mimeType, _, _ := strings.Cut(contentType, ";")
+
+	ext := strings.ToLower(filepath.Ext(path))
+	if d.treatAsText[ext] {
+		return mimeType, true, nil
+	}
+	if d.treatAsBinary[ext] {
+		return mimeType, false, nil
+	}
 
 	// Consider SVG files as text
-	if strings.HasSuffix(strings.ToLower(path), ".svg") {
-		return true, nil
+	if ext == ".svg" {
+		return mimeType, true, nil
+	}
+
+	if strings.Contains(contentType, "binary") {
+		return mimeType, false, nil
+	}
+
+	// Content sniffing only looks at the first 512 bytes, so a NUL anywhere
+	// in the sample also marks the file as binary. UTF-16 text is full of NULs.
+	// Sniffed non-text types such as image/png are kept for descriptions.
+	if !strings.Contains(contentType, "utf-16") && bytes.IndexByte(sample, 0) >= 0 {
+		if strings.HasPrefix(mimeType, "text/") {
+			mimeType = "application/octet-stream"
+		}
+		return mimeType, false, nil
+	}
+	return mimeType, true, nil
+}
+
+// ReadHead reads at most n bytes from the start of a file
+func ReadHead(path string, n int) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	buffer := make([]byte, n)
+	read, err := io.ReadFull(f, buffer)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+
+	return buffer[:read], nil
+}
+
+var generatedMarkerRegex = regexp.MustCompile(`(?i)(@generated|do not edit|auto-generated|autogenerated|generated by)`)
+
+// leadingCommentRegex matches lines that can open a file as comments
+var leadingCommentRegex = regexp.MustCompile(`^\s*(//|#|/?\*|<!--|--|;)`)
+
+// IsGenerated checks whether the comment lines opening a file head carry a
+// common generated-code marker. Markers past the first line of code, such
+// as a "do not edit" note in documentation, don't count.
+func IsGenerated(head []byte) bool {
+	if HasGeneratedHeader(head) {
+		return true
+	}
+
+	for _, line := range strings.Split(string(head), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if !leadingCommentRegex.MatchString(line) {
+			return false
+		}
+		if generatedMarkerRegex.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// generatedHeaderLines is how many leading lines are checked for a header marker
+const generatedHeaderLines = 5
+
+// GeneratedHeaderMarkers match lines that identify a file as generated when
+// they appear near its top. Append to this list to recognize other tools.
+var GeneratedHeaderMarkers = []*regexp.Regexp{
+	regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`),                                  // Go
+	regexp.MustCompile(`@generated\b`),                                                          // Facebook/Buck convention
+	regexp.MustCompile(`(?i)^\s*(//|#|/?\*|<!--|--)\s*(this file (is|was) )?auto-?generated\b`), // Generic comment headers
+	regexp.MustCompile(`(?i)^\s*(//|#|/?\*|<!--|--)\s*generated by\b`),
+}
+
+// HasGeneratedHeader checks whether the first lines of a file head carry a
+// generated-file marker
+func HasGeneratedHeader(head []byte) bool {
+	lines := strings.SplitN(string(head), "\n", generatedHeaderLines+1)
+	if len(lines) > generatedHeaderLines {
+		lines = lines[:generatedHeaderLines]
+	}
+
+	for _, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		for _, marker := range GeneratedHeaderMarkers {
+			if marker.MatchString(line) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsMinified checks whether a file looks minified, either by name or by
+// having very long lines in its head
+func IsMinified(path string, head []byte) bool {
+	const minifiedLineLength = 500
+
+	base := strings.ToLower(filepath.Base(path))
+	if strings.Contains(base, ".min.") {
+		return true
 	}
 
-	return !strings.Contains(contentType, "binary"), nil
+	lines := bytes.Count(head, []byte("\n")) + 1
+	return len(head)/lines > minifiedLineLength
 }
 
 // GetFileType returns the type of file based on its extension
@@ -72,7 +252,18 @@ func GetFileType(path string) string {
 
 // ShouldTreatAsBinary determines if a file should be treated as binary
 func ShouldTreatAsBinary(path string) bool {
+	return defaultDetector.ShouldTreatAsBinary(path)
+}
+
+// ShouldTreatAsBinary determines if a file should be treated as binary
+func (d *FileTypeDetector) ShouldTreatAsBinary(path string) bool {
 	ext := strings.ToLower(filepath.Ext(path))
+	if d.treatAsText[ext] {
+		return false
+	}
+	if d.treatAsBinary[ext] {
+		return true
+	}
 	_, isBinary := BinaryFileTypes[ext]
 	return isBinary
 }
@@ -81,3 +272,172 @@ func ShouldTreatAsBinary(path string) bool {
 func IsWhitespaceSensitive(ext string) bool {
 	return WhitespaceDependentExtensions[ext]
 }
+
+// DetectLanguageExt returns the extension of the language a file is written
+// in, judged by its name, shebang line or content, or "" if nothing gives it
+// away. Well-known names such as Makefile and shebangs always decide; YAML is
+// only sniffed from the content of extensionless and .txt files.
+func DetectLanguageExt(name, content string) string {
+	if ext, ok := languageFileNames[filepath.Base(name)]; ok {
+		return ext
+	}
+
+	firstLine, _, _ := strings.Cut(content, "\n")
+	firstLine = strings.TrimSpace(firstLine)
+	if ext := shebangExt(firstLine); ext != "" {
+		return ext
+	}
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case "", ".txt":
+		if strings.HasPrefix(firstLine, "%YAML") || firstLine == "---" {
+			return ".yaml"
+		}
+	}
+	return ""
+}
+
+// testAffixes are the suffixes test file names add to the name of the source
+// file they test, longest first so "Tests" is tried before "Test"
+var testAffixes = []string{"_test", ".test", ".spec", "_spec", "Tests", "Test"}
+
+// TestedSources returns the paths of the source files a test file tests by
+// naming convention, such as foo.go for foo_test.go or foo.py for
+// test_foo.py. The candidates sit next to the test and may not exist.
+func TestedSources(relPath string) []string {
+	dir, base := filepath.Split(relPath)
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+
+	var sources []string
+	for _, affix := range testAffixes {
+		if name, ok := strings.CutSuffix(stem, affix); ok && name != "" {
+			sources = append(sources, dir+name+ext)
+			break
+		}
+	}
+	if name, ok := strings.CutPrefix(stem, "test_"); ok && name != "" {
+		sources = append(sources, dir+name+ext)
+	}
+	return sources
+}
+
+// shebangExt returns the language extension of a "#!" interpreter line,
+// looking through /usr/bin/env and version suffixes like python3.12
+func shebangExt(line string) string {
+	rest, ok := strings.CutPrefix(line, "#!")
+	if !ok {
+		return ""
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return ""
+	}
+	interpreter := path.Base(fields[0])
+	if interpreter == "env" {
+		fields = slices.DeleteFunc(fields[1:], func(f string) bool { return strings.HasPrefix(f, "-") })
+		if len(fields) == 0 {
+			return ""
+		}
+		interpreter = path.Base(fields[0])
+	}
+
+	if ext, ok := shebangInterpreters[interpreter]; ok {
+		return ext
+	}
+	if base, _, ok := strings.Cut(interpreter, "."); ok {
+		return shebangInterpreters[base]
+	}
+	return ""
+}
+
+// CommonDirPrefix returns the longest directory prefix shared by all paths,
+// in slash form with a trailing slash, or an empty string if there is none
+func CommonDirPrefix(paths []string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+
+	prefix := strings.Split(path.Dir(filepath.ToSlash(paths[0])), "/")
+	for _, p := range paths[1:] {
+		dirs := strings.Split(path.Dir(filepath.ToSlash(p)), "/")
+		n := 0
+		for n < len(prefix) && n < len(dirs) && prefix[n] == dirs[n] {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+
+	if len(prefix) == 0 || (len(prefix) == 1 && prefix[0] == ".") {
+		return ""
+	}
+	return strings.Join(prefix, "/") + "/"
+}
+
+// TrailingComponents returns each path shortened to its last depth
+// components, in slash form. Paths whose shortened forms collide keep more
+// components until they are unique or whole, so only colliding paths grow.
+func TrailingComponents(paths []string, depth int) map[string]string {
+	parts := make([][]string, len(paths))
+	depths := make([]int, len(paths))
+	for i, p := range paths {
+		parts[i] = strings.Split(filepath.ToSlash(p), "/")
+		depths[i] = min(depth, len(parts[i]))
+	}
+
+	suffix := func(i int) string {
+		return strings.Join(parts[i][len(parts[i])-depths[i]:], "/")
+	}
+
+	for changed := true; changed; {
+		changed = false
+
+		groups := make(map[string][]int)
+		for i := range paths {
+			groups[suffix(i)] = append(groups[suffix(i)], i)
+		}
+		for _, group := range groups {
+			if len(group) < 2 {
+				continue
+			}
+			for _, i := range group {
+				if depths[i] < len(parts[i]) {
+					depths[i]++
+					changed = true
+				}
+			}
+		}
+	}
+
+	short := make(map[string]string, len(paths))
+	for i, p := range paths {
+		short[p] = suffix(i)
+	}
+	return short
+}
+
+// ReadPathList reads a list of paths separated by newlines, or by NUL bytes
+// when nul is set, skipping empty entries
+func ReadPathList(r io.Reader, nul bool) ([]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	sep := "\n"
+	if nul {
+		sep = "\x00"
+	}
+
+	var paths []string
+	for _, entry := range strings.Split(string(data), sep) {
+		if !nul {
+			entry = strings.TrimRight(entry, "\r")
+		}
+		if entry != "" {
+			paths = append(paths, entry)
+		}
+	}
+	return paths, nil
+}
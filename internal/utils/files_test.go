@@ -0,0 +1,230 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIsGenerated(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want bool
+	}{
+		{"go header", "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage pb\n", true},
+		{"after license", "// Copyright 2024 Example\n//\n// Code generated by mockery. DO NOT EDIT.\npackage mocks\n", true},
+		{"hash comment", "#!/bin/sh\n# Auto-generated from schema.yaml, do not edit\nset -e\n", true},
+		{"block comment", "/*\n * @generated by relay-compiler\n */\nexport const x = 1\n", true},
+		{"crlf", "// DO NOT EDIT: generated file\r\npackage x\r\n", true},
+		{"marker in code", "package cmd\n\n// Skip files marked \"do not edit\" or \"generated by\"\nvar x = 1\n", false},
+		{"marker in prose", "# Project\n\nFiles generated by tools are skipped.\n", false},
+		{"marker in string", "package utils\n\nvar re = `(do not edit|generated by)`\n", false},
+		{"empty", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsGenerated([]byte(tt.head)); got != tt.want {
+				t.Errorf("IsGenerated(%q) = %v, want %v", tt.head, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommonDirPrefix(t *testing.T) {
+	tests := []struct {
+		name  string
+		paths []string
+		want  string
+	}{
+		{"shared", []string{"src/app/a.go", "src/app/b/c.go"}, "src/app/"},
+		{"partial", []string{"src/app/a.go", "src/lib/b.go"}, "src/"},
+		{"none", []string{"a.go", "src/b.go"}, ""},
+		{"component not string prefix", []string{"src/app/a.go", "src/apple/b.go"}, "src/"},
+		{"empty", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CommonDirPrefix(tt.paths); got != tt.want {
+				t.Errorf("CommonDirPrefix(%q) = %q, want %q", tt.paths, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrailingComponents(t *testing.T) {
+	paths := []string{"cmd/server/main.go", "cmd/client/main.go", "internal/db/conn.go", "README.md"}
+	want := map[string]string{
+		"cmd/server/main.go":  "server/main.go",
+		"cmd/client/main.go":  "client/main.go",
+		"internal/db/conn.go": "conn.go",
+		"README.md":           "README.md",
+	}
+
+	if got := TrailingComponents(paths, 1); !reflect.DeepEqual(got, want) {
+		t.Errorf("TrailingComponents() = %v, want %v", got, want)
+	}
+}
+
+func TestDetectLanguageExt(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"Makefile", "all:\n\tgo build\n", ".mk"},
+		{"scripts/deploy", "#!/usr/bin/env bash\nset -e\n", ".sh"},
+		{"tool", "#!/usr/bin/env -S python3.12 -u\nprint()\n", ".py"},
+		{"run.txt", "#!/bin/sh\necho hi\n", ".sh"},
+		{"config", "---\nkey: value\n", ".yaml"},
+		{"notes.txt", "%YAML 1.2\n---\n", ".yaml"},
+		{"doc.md", "---\ntitle: x\n---\n", ""},
+		{"plain", "hello\n", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectLanguageExt(tt.name, tt.content); got != tt.want {
+				t.Errorf("DetectLanguageExt(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTestedSources(t *testing.T) {
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"pkg/parser_test.go", []string{"pkg/parser.go"}},
+		{"src/app.spec.ts", []string{"src/app.ts"}},
+		{"tests/test_models.py", []string{"tests/models.py"}},
+		{"Sources/ParserTests.swift", []string{"Sources/Parser.swift"}},
+		{"_test.go", nil},
+		{"main.go", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := TestedSources(tt.path); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TestedSources(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileTypeDetectorSampleSize(t *testing.T) {
+	h := NewTestHelper(t)
+	defer h.Cleanup()
+
+	// A NUL byte after 1000 bytes of text
+	path := h.CreateTempFile("late-nul.txt", strings.Repeat("a", 1000)+"\x00tail\n")
+
+	tests := []struct {
+		name       string
+		sampleSize int
+		wantText   bool
+	}{
+		{"default misses the NUL", 0, true},
+		{"smaller sample", 64, true},
+		{"sample covers the NUL", 2048, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isText, err := NewFileTypeDetector(nil, nil, tt.sampleSize).IsTextFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if isText != tt.wantText {
+				t.Errorf("IsTextFile() with a %d byte sample = %v, want %v", tt.sampleSize, isText, tt.wantText)
+			}
+		})
+	}
+
+	// Package-level detection keeps the default sample size
+	if isText, err := IsTextFile(path); err != nil || !isText {
+		t.Errorf("IsTextFile() = %v, %v, want text with the default sample", isText, err)
+	}
+}
+
+func TestDetectFileType(t *testing.T) {
+	h := NewTestHelper(t)
+	defer h.Cleanup()
+
+	tests := []struct {
+		name     string
+		content  string
+		wantMIME string
+		wantText bool
+	}{
+		{"logo.png", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR", "image/png", false},
+		{"archive.gz", "\x1f\x8b\x08\x00\x00\x00\x00\x00", "application/x-gzip", false},
+		{"notes.txt", "plain notes\n", "text/plain", true},
+		{"icon.svg", "<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>\n", "text/plain", true},
+		{"data.bin", "text\x00with a NUL\n", "application/octet-stream", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mimeType, isText, err := DetectFileType(h.CreateTempFile(tt.name, tt.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mimeType != tt.wantMIME || isText != tt.wantText {
+				t.Errorf("DetectFileType() = %q, %v, want %q, %v", mimeType, isText, tt.wantMIME, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestHasGeneratedHeader(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want bool
+	}{
+		{"go", "// Code generated by protoc-gen-go. DO NOT EDIT.\npackage api\n", true},
+		{"at generated", "/**\n * @generated SignedSource<<abc>>\n */\n", true},
+		{"auto-generated comment", "# This file was auto-generated\nx = 1\n", true},
+		{"generated by", "<!-- Generated by the docs tool -->\n", true},
+		{"crlf", "// Code generated by mockgen. DO NOT EDIT.\r\npackage mocks\r\n", true},
+		{"past the header lines", "package a\n\n\n\n\n// Code generated by x. DO NOT EDIT.\n", false},
+		{"prose", "// Handles generated code differently\npackage a\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HasGeneratedHeader([]byte(tt.head)); got != tt.want {
+				t.Errorf("HasGeneratedHeader(%q) = %v, want %v", tt.head, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadPathList(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		nul   bool
+		want  []string
+	}{
+		{"newlines", "a.go\r\n\nsub/b go\n", false, []string{"a.go", "sub/b go"}},
+		{"nul", "a.go\x00line\nbreak.go\x00\x00", true, []string{"a.go", "line\nbreak.go"}},
+		{"empty", "", false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadPathList(strings.NewReader(tt.input), tt.nul)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadPathList() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package utils
+
+// Concurrency levels picked from the input filesystem
+const (
+	DefaultConcurrency    = 10 // Filesystem couldn't be probed
+	NetworkConcurrency    = 4  // Remote mounts, where many parallel reads queue on the server
+	RotationalConcurrency = 2  // Spinning disks, where parallel reads cause seeks
+	minLocalConcurrency   = 8
+	maxLocalConcurrency   = 32
+)
+
+// FilesystemInfo describes the storage behind a path
+type FilesystemInfo struct {
+	Known      bool   // The probe succeeded
+	Type       string // Filesystem name for display, e.g. "nfs" or "ext4"
+	Network    bool   // NFS, SMB, FUSE or another remote mount
+	Rotational bool   // Backed by a spinning disk
+}
+
+// ConcurrencyFor maps filesystem signals to a number of files to read at
+// once: low for remote mounts and spinning disks, scaling with cpus for
+// local solid-state storage
+func ConcurrencyFor(fs FilesystemInfo, cpus int) int {
+	switch {
+	case !fs.Known:
+		return DefaultConcurrency
+	case fs.Network:
+		return NetworkConcurrency
+	case fs.Rotational:
+		return RotationalConcurrency
+	}
+
+	return min(max(cpus*2, minLocalConcurrency), maxLocalConcurrency)
+}
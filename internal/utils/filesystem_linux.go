@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// Filesystem magic numbers from statfs(2) for remote and FUSE mounts
+var networkFilesystems = map[uint32]string{
+	0x6969:     "nfs",
+	0x517B:     "smb",
+	0xFE534D42: "smb2",
+	0xFF534D42: "cifs",
+	0x65735546: "fuse",
+	0x01021997: "9p",
+	0x00C36400: "ceph",
+	0x013111A8: "ibrix",
+	0x0BD00BD0: "lustre",
+	0x47504653: "gpfs",
+}
+
+// Filesystem magic numbers for common local filesystems, for display
+var localFilesystems = map[uint32]string{
+	0xEF53:     "ext4",
+	0x9123683E: "btrfs",
+	0x58465342: "xfs",
+	0x2FC12FC1: "zfs",
+	0x01021994: "tmpfs",
+	0x794C7630: "overlayfs",
+	0xF2F52010: "f2fs",
+}
+
+// ProbeFilesystem reports the filesystem type of path from statfs and,
+// for block devices, whether the disk is rotational from sysfs
+func ProbeFilesystem(path string) FilesystemInfo {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(path, &fs); err != nil {
+		return FilesystemInfo{}
+	}
+
+	magic := uint32(fs.Type)
+	if name, ok := networkFilesystems[magic]; ok {
+		return FilesystemInfo{Known: true, Type: name, Network: true}
+	}
+
+	info := FilesystemInfo{Known: true, Type: localFilesystems[magic]}
+	if info.Type == "" {
+		info.Type = fmt.Sprintf("0x%x", magic)
+	}
+
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err == nil {
+		info.Rotational = isRotational(uint64(st.Dev))
+	}
+	return info
+}
+
+// isRotational reads the rotational flag for the block device dev, looking
+// at the parent disk when dev is a partition
+func isRotational(dev uint64) bool {
+	major := (dev>>8)&0xfff | (dev>>32)&^0xfff
+	minor := dev&0xff | (dev>>12)&^0xff
+	base := fmt.Sprintf("/sys/dev/block/%d:%d", major, minor)
+
+	for _, path := range []string{base + "/queue/rotational", base + "/../queue/rotational"} {
+		if data, err := os.ReadFile(path); err == nil {
+			return strings.TrimSpace(string(data)) == "1"
+		}
+	}
+	return false
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package utils
+
+// ProbeFilesystem reports nothing on platforms without a probe, so the
+// default concurrency is used
+func ProbeFilesystem(path string) FilesystemInfo {
+	return FilesystemInfo{}
+}
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestConcurrencyFor(t *testing.T) {
+	tests := []struct {
+		name string
+		fs   FilesystemInfo
+		cpus int
+		want int
+	}{
+		{"unknown", FilesystemInfo{}, 8, DefaultConcurrency},
+		{"network", FilesystemInfo{Known: true, Network: true, Rotational: true}, 8, NetworkConcurrency},
+		{"rotational", FilesystemInfo{Known: true, Rotational: true}, 8, RotationalConcurrency},
+		{"ssd few cpus", FilesystemInfo{Known: true}, 2, minLocalConcurrency},
+		{"ssd", FilesystemInfo{Known: true}, 6, 12},
+		{"ssd many cpus", FilesystemInfo{Known: true}, 64, maxLocalConcurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ConcurrencyFor(tt.fs, tt.cpus); got != tt.want {
+				t.Errorf("ConcurrencyFor() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProbeFilesystemMissingPath(t *testing.T) {
+	if got := ProbeFilesystem(filepath.Join(t.TempDir(), "missing")); got.Known {
+		t.Errorf("ProbeFilesystem() = %+v, want unknown for a missing path", got)
+	}
+}
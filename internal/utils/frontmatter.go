@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"strings"
+)
+
+const frontMatterDelimiter = "---"
+
+// ParseFrontMatter splits a leading "---" delimited YAML block from content.
+// Only top-level "key: value" scalars are read; nested structures and lists
+// are skipped. ok is false when content has no complete front matter block.
+func ParseFrontMatter(content string) (fields map[string]string, body string, ok bool) {
+	lines := strings.SplitAfter(content, "\n")
+	if len(lines) == 0 || strings.TrimRight(lines[0], "\r\n") != frontMatterDelimiter {
+		return nil, content, false
+	}
+
+	fields = make(map[string]string)
+	for i := 1; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\r\n")
+		if line == frontMatterDelimiter || line == "..." {
+			return fields, strings.Join(lines[i+1:], ""), true
+		}
+
+		// Indented lines belong to nested values
+		if line == "" || line[0] == ' ' || line[0] == '\t' || line[0] == '#' {
+			continue
+		}
+
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		fields[strings.TrimSpace(key)] = unquoteYAML(strings.TrimSpace(value))
+	}
+
+	return nil, content, false
+}
+
+// unquoteYAML strips matching single or double quotes from a scalar
+func unquoteYAML(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
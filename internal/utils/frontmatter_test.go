@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseFrontMatter(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		wantFields string
+		wantBody   string
+		wantOK     bool
+	}{
+		{
+			"yaml",
+			"---\ntitle: \"Getting Started\"\ntags:\n  - intro\nauthor: 'Ada'\n---\n# Body\n",
+			"map[author:Ada tags: title:Getting Started]", "# Body\n", true,
+		},
+		{"crlf and dots", "---\r\ntitle: Notes\r\n...\r\nbody\r\n", "map[title:Notes]", "body\r\n", true},
+		{"unterminated", "---\ntitle: Notes\nbody\n", "map[]", "---\ntitle: Notes\nbody\n", false},
+		{"none", "# Title\n---\n", "map[]", "# Title\n---\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, body, ok := ParseFrontMatter(tt.content)
+			if got := fmt.Sprint(fields); got != tt.wantFields || body != tt.wantBody || ok != tt.wantOK {
+				t.Errorf("ParseFrontMatter() = %s, %q, %v, want %s, %q, %v", got, body, ok, tt.wantFields, tt.wantBody, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"fmt"
+	"net/url"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// GitInfo identifies the code version of a repository checkout
+type GitInfo struct {
+	Branch string // Current branch, empty when HEAD is detached
+	Commit string // Abbreviated HEAD commit
+	Remote string // URL of the origin remote without credentials, empty if there is none
+}
+
+// GitMetadata returns the branch, HEAD commit and origin URL of the
+// repository containing dir
+func GitMetadata(dir string) (GitInfo, error) {
+	sha, err := GitShortSHA(dir)
+	if err != nil {
+		return GitInfo{}, err
+	}
+	info := GitInfo{Commit: sha}
+
+	if out, err := exec.Command("git", "-C", dir, "symbolic-ref", "--quiet", "--short", "HEAD").Output(); err == nil {
+		info.Branch = strings.TrimSpace(string(out))
+	}
+	if out, err := exec.Command("git", "-C", dir, "remote", "get-url", "origin").Output(); err == nil {
+		info.Remote = redactURLCredentials(strings.TrimSpace(string(out)))
+	}
+
+	return info, nil
+}
+
+// redactURLCredentials drops any user and password from a remote URL, so
+// tokens embedded in HTTPS remotes don't end up in the output. Remotes that
+// aren't URLs, such as git@host:repo, are returned unchanged.
+func redactURLCredentials(remote string) string {
+	u, err := url.Parse(remote)
+	if err != nil || u.User == nil || u.Scheme == "" {
+		return remote
+	}
+	u.User = nil
+	return u.String()
+}
+
+// GitShortSHA returns the abbreviated HEAD commit of the repository containing dir
+func GitShortSHA(dir string) (string, error) {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--short", "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve git HEAD: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// GitPrimaryAuthors returns up to limit authors of a file ordered by commit count.
+// Files not tracked by git yield an empty list.
+func GitPrimaryAuthors(dir, relPath string, limit int) ([]string, error) {
+	out, err := exec.Command("git", "-C", dir, "log", "--format=%an", "--", relPath).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read git log for %s: %w", relPath, err)
+	}
+
+	counts := make(map[string]int)
+	for _, name := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if name != "" {
+			counts[name]++
+		}
+	}
+
+	authors := make([]string, 0, len(counts))
+	for name := range counts {
+		authors = append(authors, name)
+	}
+	sort.Slice(authors, func(i, j int) bool {
+		if counts[authors[i]] != counts[authors[j]] {
+			return counts[authors[i]] > counts[authors[j]]
+		}
+		return authors[i] < authors[j]
+	})
+
+	if len(authors) > limit {
+		authors = authors[:limit]
+	}
+	return authors, nil
+}
+
+// GitTrackedFiles returns the set of files tracked by git under dir, keyed by
+// slash-separated paths relative to dir
+func GitTrackedFiles(dir string) (map[string]bool, error) {
+	out, err := exec.Command("git", "-C", dir, "ls-files", "-z").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tracked files (is %s inside a git repository?): %w", dir, err)
+	}
+
+	tracked := make(map[string]bool)
+	for _, path := range strings.Split(string(out), "\x00") {
+		if path != "" {
+			tracked[path] = true
+		}
+	}
+	return tracked, nil
+}
+
+// GitChangedFiles returns the files under dir changed in a commit range such
+// as "main..feature", keyed by slash-separated paths relative to dir. Files
+// deleted in the range are returned separately.
+func GitChangedFiles(dir, commitRange string) (map[string]bool, []string, error) {
+	out, err := exec.Command("git", "-C", dir, "diff", "--name-status", "--no-renames", "--relative", "-z", commitRange).Output()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to diff commit range %s: %w", commitRange, err)
+	}
+
+	changed := make(map[string]bool)
+	var deleted []string
+
+	fields := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		status, path := fields[i], fields[i+1]
+		if strings.HasPrefix(status, "D") {
+			deleted = append(deleted, path)
+		} else {
+			changed[path] = true
+		}
+	}
+
+	sort.Strings(deleted)
+	return changed, deleted, nil
+}
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"io"
+	"os"
+	"sort"
+)
+
+// DefaultHashAlgo is the hash used when none is configured
+const DefaultHashAlgo = "sha256"
+
+// Hasher computes hex-encoded digests with a registered algorithm
+type Hasher struct {
+	name    string
+	newHash func() hash.Hash
+}
+
+var hashers = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha1":   sha1.New,
+	"fnv64a": func() hash.Hash { return fnv.New64a() },
+}
+
+// RegisterHasher makes a hash algorithm available by name, replacing any
+// existing registration
+func RegisterHasher(name string, newHash func() hash.Hash) {
+	hashers[name] = newHash
+}
+
+// HasherNames returns the registered algorithm names in sorted order
+func HasherNames() []string {
+	names := make([]string, 0, len(hashers))
+	for name := range hashers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewHasher returns the hasher registered under name
+func NewHasher(name string) (Hasher, error) {
+	newHash, ok := hashers[name]
+	if !ok {
+		return Hasher{}, fmt.Errorf("unknown hash algorithm: %s", name)
+	}
+	return Hasher{name: name, newHash: newHash}, nil
+}
+
+// Name returns the algorithm name
+func (h Hasher) Name() string {
+	return h.name
+}
+
+// HashFile returns the hex-encoded digest of a file's content
+func (h Hasher) HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	d := h.newHash()
+	if _, err := io.Copy(d, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(d.Sum(nil)), nil
+}
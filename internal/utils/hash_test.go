@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"crypto/md5"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestHasherHashFile(t *testing.T) {
+	h := NewTestHelper(t)
+	defer h.Cleanup()
+	path := h.CreateTempFile("hello.txt", "hello\n")
+
+	tests := []struct {
+		algo string
+		want string
+	}{
+		{"sha256", "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"},
+		{"sha1", "f572d396fae9206628714fb2ce00f72e94f2258f"},
+		{"fnv64a", "a9bc80cca21f28b3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algo, func(t *testing.T) {
+			hasher, err := NewHasher(tt.algo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hasher.Name() != tt.algo {
+				t.Errorf("Name() = %q, want %q", hasher.Name(), tt.algo)
+			}
+			got, err := hasher.HashFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("HashFile() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewHasherUnknown(t *testing.T) {
+	if _, err := NewHasher("crc32"); err == nil || !strings.Contains(err.Error(), "crc32") {
+		t.Errorf("NewHasher(\"crc32\") error = %v, want unknown algorithm", err)
+	}
+}
+
+func TestRegisterHasher(t *testing.T) {
+	RegisterHasher("md5", md5.New)
+	t.Cleanup(func() { delete(hashers, "md5") })
+
+	if !slices.Contains(HasherNames(), "md5") {
+		t.Errorf("HasherNames() = %q, want md5 listed", HasherNames())
+	}
+
+	h := NewTestHelper(t)
+	defer h.Cleanup()
+	hasher, err := NewHasher("md5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := hasher.HashFile(h.CreateTempFile("hello.txt", "hello\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "b1946ac92492d2347c6235b4d2611184"; got != want {
+		t.Errorf("HashFile() = %s, want %s", got, want)
+	}
+}
+
+func BenchmarkHasher(b *testing.B) {
+	h := NewTestHelper(b)
+	defer h.Cleanup()
+	path := h.CreateTempFile("large.txt", strings.Repeat("0123456789abcdef", 64*1024))
+
+	for _, algo := range HasherNames() {
+		b.Run(algo, func(b *testing.B) {
+			hasher, err := NewHasher(algo)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.SetBytes(64 * 1024 * 16)
+			for range b.N {
+				if _, err := hasher.HashFile(path); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -1,29 +1,168 @@
 package utils
 
 import (
-	"github.com/sabhiram/go-gitignore"
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/sabhiram/go-gitignore"
 )
 
 // IgnoreMatcher handles file pattern matching for ignored files
 type IgnoreMatcher struct {
-	customIgnore  *ignore.GitIgnore
-	defaultIgnore *ignore.GitIgnore
+	ignore    *ignore.GitIgnore
+	literals  map[string]bool // Plain name patterns checked before the gitignore engine
+	negations []string        // "!" pattern bodies relative to the input directory
+	scoped    []scopedIgnore
 }
 
-// NewIgnoreMatcher creates a new ignore matcher with the given patterns
+// scopedIgnore applies patterns from an ancestor directory, where prefix is
+// the path from that directory down to the input directory
+type scopedIgnore struct {
+	prefix string
+	ignore *ignore.GitIgnore
+}
+
+// ScopedPatterns holds patterns read from an ignore file above the input directory
+type ScopedPatterns struct {
+	Source   string // Path of the ignore file
+	Prefix   string // Slash-separated path from the ignore file's directory to the input directory
+	Patterns []string
+}
+
+// NewIgnoreMatcher creates a new ignore matcher with the given patterns.
+// Default patterns come first so later custom patterns, including "!"
+// negations, override them as they would in a single .gitignore file.
 func NewIgnoreMatcher(patterns []string, useDefault bool) *IgnoreMatcher {
+	var lines []string
+	if useDefault {
+		lines = append(lines, DefaultIgnores...)
+	}
+	lines = append(lines, patterns...)
+
 	matcher := &IgnoreMatcher{}
+	if len(lines) > 0 {
+		matcher.ignore = ignore.CompileIgnoreLines(lines...)
+		matcher.literals = literalPatterns(lines)
+		matcher.negations = negationPatterns(lines, "")
+	}
 
-	if len(patterns) > 0 {
-		matcher.customIgnore = ignore.CompileIgnoreLines(patterns...)
+	return matcher
+}
+
+// literalPatterns returns the set of plain name patterns, such as
+// "node_modules", which match any path containing that name as a component.
+// The gitignore engine lets a negation re-include any file it matches, even
+// one inside an ignored directory ("dist" then "!*.md" keeps dist/README.md),
+// so a negation drops every earlier literal back to the engine.
+func literalPatterns(lines []string) map[string]bool {
+	literals := make(map[string]bool)
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "!") {
+			clear(literals)
+			continue
+		}
+		if line != "" && !strings.ContainsAny(line, "*?[]\\/#") {
+			literals[line] = true
+		}
 	}
+	return literals
+}
 
-	if useDefault {
-		matcher.defaultIgnore = ignore.CompileIgnoreLines(DefaultIgnores...)
+// negationPatterns returns the bodies of the "!" lines, as paths from the
+// input directory when prefix locates the patterns' own directory
+func negationPatterns(lines []string, prefix string) []string {
+	var negations []string
+	for _, line := range lines {
+		body, ok := strings.CutPrefix(strings.TrimSpace(line), "!")
+		if !ok || body == "" {
+			continue
+		}
+		body = strings.TrimSuffix(body, "/")
+		if prefix != "" && prefix != "." && strings.Contains(body, "/") {
+			// Anchored patterns from an ancestor are rebased onto the input directory
+			rel, ok := strings.CutPrefix(strings.TrimPrefix(body, "/"), prefix+"/")
+			switch {
+			case ok:
+				body = rel
+			case strings.ContainsAny(body, "*?["):
+				// A glob may match through the prefix, so assume it reaches everywhere
+				body = "**"
+			default:
+				continue
+			}
+		}
+		negations = append(negations, body)
 	}
+	return negations
+}
 
-	return matcher
+// SkipsDir reports whether a walk can skip the directory dir without
+// visiting its files: dir is ignored and no negation pattern can re-include
+// anything inside it. The gitignore engine lets "dist" then "!*.md" keep
+// dist/README.md, so such directories are descended into.
+func (im *IgnoreMatcher) SkipsDir(dir string) bool {
+	dir = filepath.ToSlash(dir)
+	if !im.ShouldIgnore(dir + "/") {
+		return false
+	}
+
+	dirParts := strings.Split(dir, "/")
+	for _, negation := range im.negations {
+		if negationReaches(negation, dirParts) {
+			return false
+		}
+	}
+	return true
+}
+
+// negationReaches reports whether a negation body could match a path below
+// the directory made of dirParts
+func negationReaches(negation string, dirParts []string) bool {
+	negation = strings.TrimPrefix(negation, "/")
+	if !strings.Contains(negation, "/") {
+		// Unanchored names match at any depth
+		return true
+	}
+
+	parts := strings.Split(negation, "/")
+	for i, dirPart := range dirParts {
+		if parts[i] == "**" {
+			return true
+		}
+		// The last part names the re-included path, which must be below dir
+		if i == len(parts)-1 {
+			return false
+		}
+		if matched, _ := path.Match(parts[i], dirPart); !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches reports whether path matches the patterns, for matchers used as include lists
+func (im *IgnoreMatcher) Matches(path string) bool {
+	return im.ShouldIgnore(path)
+}
+
+// AddScoped adds patterns from an ancestor directory to the matcher
+func (im *IgnoreMatcher) AddScoped(scoped ScopedPatterns) {
+	if len(scoped.Patterns) == 0 {
+		return
+	}
+
+	im.scoped = append(im.scoped, scopedIgnore{
+		prefix: scoped.Prefix,
+		ignore: ignore.CompileIgnoreLines(scoped.Patterns...),
+	})
+	im.negations = append(im.negations, negationPatterns(scoped.Patterns, scoped.Prefix)...)
 }
 
 // ShouldIgnore checks if a file should be ignored
@@ -31,13 +170,222 @@ func (im *IgnoreMatcher) ShouldIgnore(path string) bool {
 	// Normalize path separators
 	path = filepath.ToSlash(path)
 
-	if im.defaultIgnore != nil && im.defaultIgnore.MatchesPath(path) {
-		return true
+	if len(im.literals) > 0 {
+		for _, part := range strings.Split(path, "/") {
+			if im.literals[part] {
+				return true
+			}
+		}
 	}
 
-	if im.customIgnore != nil && im.customIgnore.MatchesPath(path) {
+	if im.ignore != nil && im.ignore.MatchesPath(path) {
 		return true
 	}
 
+	for _, s := range im.scoped {
+		if s.ignore.MatchesPath(s.prefix + "/" + path) {
+			return true
+		}
+	}
+
 	return false
 }
+
+// IgnoreRule is a single ignore pattern and the file it was read from
+type IgnoreRule struct {
+	Source  string
+	Pattern string
+	Hits    int // Paths matched while explaining
+
+	prefix string
+	negate bool
+	ignore *ignore.GitIgnore
+}
+
+// IgnoreExplainer finds the pattern that decides whether a path is ignored.
+// It evaluates patterns one at a time, so it is much slower than IgnoreMatcher
+// and only meant for diagnostics.
+type IgnoreExplainer struct {
+	rules []*IgnoreRule
+}
+
+// NewIgnoreExplainer creates an explainer for the given pattern sources, in
+// the order an IgnoreMatcher would apply them
+func NewIgnoreExplainer(sources []ScopedPatterns) *IgnoreExplainer {
+	e := &IgnoreExplainer{}
+	for _, source := range sources {
+		for _, pattern := range source.Patterns {
+			line := strings.TrimSpace(pattern)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			body, negate := strings.CutPrefix(line, "!")
+			e.rules = append(e.rules, &IgnoreRule{
+				Source:  source.Source,
+				Pattern: line,
+				prefix:  source.Prefix,
+				negate:  negate,
+				ignore:  ignore.CompileIgnoreLines(body),
+			})
+		}
+	}
+	return e
+}
+
+// Explain returns the last rule matching path, which should end in a slash
+// for directories. Nil is returned when no rule matches or the last match
+// is a negation that re-includes the path.
+func (e *IgnoreExplainer) Explain(path string) *IgnoreRule {
+	path = filepath.ToSlash(path)
+
+	var last *IgnoreRule
+	for _, rule := range e.rules {
+		candidate := path
+		if rule.prefix != "" && rule.prefix != "." {
+			candidate = rule.prefix + "/" + path
+		}
+		if rule.ignore.MatchesPath(candidate) {
+			rule.Hits++
+			last = rule
+		}
+	}
+
+	if last == nil || last.negate {
+		return nil
+	}
+	return last
+}
+
+// Unused returns the rules from source that matched no explained path
+func (e *IgnoreExplainer) Unused(source string) []*IgnoreRule {
+	var unused []*IgnoreRule
+	for _, rule := range e.rules {
+		if rule.Source == source && rule.Hits == 0 {
+			unused = append(unused, rule)
+		}
+	}
+	return unused
+}
+
+// FindAncestorIgnorePatterns walks up from dir collecting patterns from the named
+// ignore files in each ancestor. The walk stops at ceiling when given, otherwise
+// at the enclosing git repository root; outside a repository nothing is collected.
+func FindAncestorIgnorePatterns(dir, ceiling string, names []string) ([]ScopedPatterns, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if ceiling == "" {
+		if ceiling = findGitRoot(absDir); ceiling == "" {
+			return nil, nil
+		}
+	}
+	absCeiling, err := filepath.Abs(ceiling)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []ScopedPatterns
+	for current := absDir; current != absCeiling; {
+		parent := filepath.Dir(current)
+		if parent == current {
+			break
+		}
+		current = parent
+
+		prefix, err := filepath.Rel(current, absDir)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, name := range names {
+			patterns, err := ReadIgnoreFile(filepath.Join(current, name))
+			if err != nil {
+				return nil, err
+			}
+			if len(patterns) > 0 {
+				result = append(result, ScopedPatterns{
+					Source:   filepath.Join(current, name),
+					Prefix:   filepath.ToSlash(prefix),
+					Patterns: patterns,
+				})
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// findGitRoot returns the nearest directory at or above dir containing .git
+func findGitRoot(dir string) string {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// ValidatePattern checks that an ignore pattern can be compiled. The gitignore
+// engine turns patterns into regular expressions without escaping regexp
+// syntax and silently drops any that fail to compile.
+func ValidatePattern(pattern string) error {
+	line := strings.TrimSpace(pattern)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+	line = strings.TrimPrefix(line, "!")
+
+	if _, err := path.Match(line, ""); err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+
+	expr := strings.NewReplacer(`\*`, "x", "*", "", "?", "", ".", "x").Replace(line)
+	if _, err := regexp.Compile(expr); err != nil {
+		return fmt.Errorf("invalid pattern %q: unsupported regexp syntax", pattern)
+	}
+
+	return nil
+}
+
+// StdinPatternSource is the pattern file name that reads from standard input
+const StdinPatternSource = "-"
+
+// ReadIgnoreFile reads patterns from an ignore file, returning no patterns if it doesn't exist
+func ReadIgnoreFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadPatterns(f)
+}
+
+// ReadPatternSource reads patterns from the named file in dir, or from
+// standard input when name is "-"
+func ReadPatternSource(dir, name string) ([]string, error) {
+	if name == StdinPatternSource {
+		return ReadPatterns(os.Stdin)
+	}
+	return ReadIgnoreFile(filepath.Join(dir, name))
+}
+
+// ReadPatterns reads newline-separated patterns from r
+func ReadPatterns(r io.Reader) ([]string, error) {
+	var patterns []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		patterns = append(patterns, scanner.Text())
+	}
+
+	return patterns, scanner.Err()
+}
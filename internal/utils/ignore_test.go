@@ -0,0 +1,350 @@
+package utils
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	ignore "github.com/sabhiram/go-gitignore"
+)
+
+func TestVendoredIgnores(t *testing.T) {
+	matcher := NewIgnoreMatcher(VendoredIgnores, false)
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"third_party/zlib/zlib.h", true},
+		{"ios/Pods/Alamofire/Source/Request.swift", true},
+		{"web/bower_components/jquery/jquery.js", true},
+		{".venv/lib/python3.12/site-packages/requests/api.py", true},
+		{"packages/app/src/index.ts", false},
+		{"deps/handler.ex", false},
+		{"src/vendor_utils.go", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := matcher.ShouldIgnore(tt.path); got != tt.want {
+				t.Errorf("ShouldIgnore(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNoiseIgnores(t *testing.T) {
+	matcher := NewIgnoreMatcher(NoiseIgnores, false)
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"poetry.lock", true},
+		{"nix/flake.lock", true},
+		{"Pipfile.lock", true},
+		{"out/main.js", true},
+		{"coverage/lcov.info", true},
+		{"out/", true},
+		{"src/out/writer.go", false},
+		{"src/out/", false},
+		{"pkg/coverage/report.go", false},
+		{"layout.go", false},
+		{"web/app.min.js", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := matcher.ShouldIgnore(tt.path); got != tt.want {
+				t.Errorf("ShouldIgnore(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIgnoreExplainer(t *testing.T) {
+	explainer := NewIgnoreExplainer([]ScopedPatterns{
+		{Source: "defaults", Patterns: []string{"*.log", "build/", "# comment", ""}},
+		{Source: ".aidigestignore", Patterns: []string{"!keep.log", "docs/", "*.tmp"}},
+	})
+
+	tests := []struct {
+		path        string
+		wantSource  string
+		wantPattern string
+	}{
+		{"app.log", "defaults", "*.log"},
+		{"build/", "defaults", "build/"},
+		{"docs/", ".aidigestignore", "docs/"},
+		{"keep.log", "", ""},
+		{"src/main.go", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rule := explainer.Explain(tt.path)
+			if tt.wantPattern == "" {
+				if rule != nil {
+					t.Errorf("Explain(%q) = %s %q, want no rule", tt.path, rule.Source, rule.Pattern)
+				}
+				return
+			}
+			if rule == nil || rule.Source != tt.wantSource || rule.Pattern != tt.wantPattern {
+				t.Errorf("Explain(%q) = %+v, want %s %q", tt.path, rule, tt.wantSource, tt.wantPattern)
+			}
+		})
+	}
+
+	unused := explainer.Unused(".aidigestignore")
+	if len(unused) != 1 || unused[0].Pattern != "*.tmp" {
+		t.Errorf("Unused() = %+v, want only *.tmp", unused)
+	}
+}
+
+func TestValidatePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		wantErr bool
+	}{
+		{"*.log", false},
+		{"!build/", false},
+		{"docs/**/*.md", false},
+		{"# [comment", false},
+		{"", false},
+		{"file[0-9].txt", false},
+		{"file[.txt", true},
+		{"!bad[", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			if err := ValidatePattern(tt.pattern); (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePattern(%q) error = %v, wantErr %v", tt.pattern, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIgnoreMatcherLiterals(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		want     bool
+	}{
+		{"literal component", []string{"node_modules"}, "web/node_modules/react/index.js", true},
+		{"literal file name", []string{"Thumbs.db"}, "img/Thumbs.db", true},
+		{"literal not substring", []string{"node_modules"}, "src/node_modules_helper.js", false},
+		{"glob", []string{"*.log"}, "logs/app.log", true},
+		{"negation re-includes", []string{"*.log", "!keep.log"}, "keep.log", false},
+		{"negation disables literal", []string{"dist", "!dist/keep.js"}, "dist/keep.js", false},
+		{"literal kept after unrelated negation", []string{"dist", "!*.md"}, "dist/app.js", true},
+		{"negation re-includes inside literal", []string{"dist", "!*.md"}, "dist/README.md", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewIgnoreMatcher(tt.patterns, false).ShouldIgnore(tt.path); got != tt.want {
+				t.Errorf("ShouldIgnore(%q) with %q = %v, want %v", tt.path, tt.patterns, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIgnoreMatcherCustomOverridesDefaults(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		want     bool
+	}{
+		{"default ignored", nil, "package-lock.json", true},
+		{"custom negation re-includes", []string{"!package-lock.json"}, "package-lock.json", false},
+		{"custom negation leaves other defaults", []string{"!package-lock.json"}, "yarn.lock", true},
+		{"custom pattern adds to defaults", []string{"*.tmp"}, "cache/a.tmp", true},
+		{"default re-ignored after negation", []string{"!yarn.lock", "yarn.lock"}, "yarn.lock", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewIgnoreMatcher(tt.patterns, true).ShouldIgnore(tt.path); got != tt.want {
+				t.Errorf("ShouldIgnore(%q) with %q = %v, want %v", tt.path, tt.patterns, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIgnoreMatcherMatchesEngine(t *testing.T) {
+	patternSets := [][]string{
+		{"node_modules", "dist", "*.log"},
+		{"dist", "!*.md"},
+		{"dist", "!dist/keep.js"},
+		{"build", "!**/important.txt"},
+		{"vendor", "!vendor/"},
+		{"tmp", "!/tmp/keep", "cache"},
+		{"*.log", "!keep.log", "logs"},
+		{"docs", "!README.md", "docs"},
+		append(append([]string{}, DefaultIgnores...), "!package-lock.json", "!.env.example"),
+	}
+	paths := []string{
+		"dist/README.md",
+		"dist/app.js",
+		"dist/keep.js",
+		"src/dist/index.md",
+		"node_modules/react/index.js",
+		"web/node_modules/react/README.md",
+		"build/important.txt",
+		"build/out/important.txt",
+		"build/other.txt",
+		"vendor/lib.go",
+		"tmp/keep",
+		"tmp/other",
+		"cache/tmp/keep",
+		"logs/keep.log",
+		"logs/app.log",
+		"app.log",
+		"keep.log",
+		"docs/README.md",
+		"docs/guide.md",
+		"package-lock.json",
+		".env.example",
+		".env",
+		"src/main.go",
+	}
+
+	for _, patterns := range patternSets {
+		engine := ignore.CompileIgnoreLines(patterns...)
+		matcher := NewIgnoreMatcher(patterns, false)
+		for _, path := range paths {
+			if got, want := matcher.ShouldIgnore(path), engine.MatchesPath(path); got != want {
+				t.Errorf("ShouldIgnore(%q) with %q = %v, gitignore engine says %v", path, patterns, got, want)
+			}
+		}
+	}
+}
+
+func TestIgnoreMatcherSkipsDir(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		dir      string
+		want     bool
+	}{
+		{"ignored", []string{"node_modules"}, "web/node_modules", true},
+		{"not ignored", []string{"node_modules"}, "web", false},
+		{"unanchored negation", []string{"dist", "!*.md"}, "dist", false},
+		{"anchored negation inside", []string{"dist", "!/dist/keep.js"}, "dist", false},
+		{"anchored negation elsewhere", []string{"dist", "build", "!/dist/keep.js"}, "build", true},
+		{"negation glob inside", []string{"vendor", "!vendor/*/LICENSE"}, "vendor", false},
+		{"double star negation", []string{"out", "!**/keep/x.txt"}, "out", false},
+		{"trailing double star", []string{"build", "!build/**"}, "build/out", false},
+		{"negation re-includes the directory", []string{"build", "!build/cache"}, "build/cache", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewIgnoreMatcher(tt.patterns, false).SkipsDir(tt.dir); got != tt.want {
+				t.Errorf("SkipsDir(%q) with %q = %v, want %v", tt.dir, tt.patterns, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIgnoreMatcherSkipsDirAgreesWithEngine(t *testing.T) {
+	patternSets := [][]string{
+		{"dist", "!*.md"},
+		{"dist", "!dist/keep.js"},
+		{"build", "!**/important.txt"},
+		{"tmp", "!/tmp/keep", "cache"},
+		{"vendor", "!vendor/*/LICENSE"},
+		append(append([]string{}, DefaultIgnores...), EnvExampleIncludes...),
+	}
+	paths := []string{
+		"dist/README.md",
+		"dist/keep.js",
+		"build/out/important.txt",
+		"tmp/keep",
+		"cache/tmp/keep",
+		"vendor/lib/LICENSE",
+		"node_modules/pkg/.env.example",
+	}
+
+	// A skipped directory must not hide any file the engine would keep
+	for _, patterns := range patternSets {
+		engine := ignore.CompileIgnoreLines(patterns...)
+		matcher := NewIgnoreMatcher(patterns, false)
+		for _, p := range paths {
+			parts := strings.Split(p, "/")
+			for i := 1; i < len(parts); i++ {
+				dir := strings.Join(parts[:i], "/")
+				if matcher.SkipsDir(dir) && !engine.MatchesPath(p) {
+					t.Errorf("SkipsDir(%q) with %q hides %s, which the engine keeps", dir, patterns, p)
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkIgnoreMatcher(b *testing.B) {
+	paths := []string{
+		"src/components/Button.tsx",
+		"web/node_modules/react/cjs/react.development.js",
+		"internal/processor/processor.go",
+		"build/classes/Main.class",
+		"docs/guide/README.md",
+		".git/objects/ab/cdef0123",
+		"vendor/github.com/spf13/cobra/command.go",
+		"logs/2024/app.log",
+	}
+
+	b.Run("matcher", func(b *testing.B) {
+		matcher := NewIgnoreMatcher(nil, true)
+		for range b.N {
+			for _, path := range paths {
+				matcher.ShouldIgnore(path)
+			}
+		}
+	})
+
+	b.Run("engine", func(b *testing.B) {
+		engine := ignore.CompileIgnoreLines(DefaultIgnores...)
+		for range b.N {
+			for _, path := range paths {
+				engine.MatchesPath(path)
+			}
+		}
+	})
+}
+
+func TestFindAncestorIgnorePatterns(t *testing.T) {
+	h := NewTestHelper(t)
+	defer h.Cleanup()
+
+	root := h.CreateTempDir("root")
+	h.CreateTempFile("root/.gitignore", "*.log\n")
+	h.CreateTempFile("root/project/.aidigestignore", "tmp/\n")
+	// Above the ceiling, so never read
+	h.CreateTempFile(".gitignore", "*\n")
+	input := h.CreateTempDir("root/project/sub")
+
+	scoped, err := FindAncestorIgnorePatterns(input, root, []string{".gitignore", ".aidigestignore"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, s := range scoped {
+		got = append(got, fmt.Sprintf("%s %s %q", filepath.Base(filepath.Dir(s.Source)), s.Prefix, s.Patterns))
+	}
+	want := []string{`project sub ["tmp/"]`, `root project/sub ["*.log"]`}
+	if !slices.Equal(got, want) {
+		t.Errorf("FindAncestorIgnorePatterns() = %q, want %q", got, want)
+	}
+
+	// Outside a git repository and without a ceiling nothing is collected
+	if scoped, err := FindAncestorIgnorePatterns(input, "", []string{".gitignore"}); err != nil || scoped != nil {
+		t.Errorf("FindAncestorIgnorePatterns() without a ceiling = %v, %v, want nothing", scoped, err)
+	}
+}
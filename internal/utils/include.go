@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// includeDirective matches a line holding only an include comment, such as
+// "<!-- include: ./snippet.go -->"
+var includeDirective = regexp.MustCompile(`(?m)^[ \t]*<!--[ \t]*include:[ \t]*(\S+)[ \t]*-->[ \t]*$`)
+
+// IncludeResolver expands include directives by inlining the referenced
+// files, each in its own fence. Targets resolve relative to the including
+// file and must stay inside root.
+type IncludeResolver struct {
+	root string
+	read func(path string) ([]byte, error)
+}
+
+// NewIncludeResolver creates a resolver for files under root
+func NewIncludeResolver(root string) *IncludeResolver {
+	return &IncludeResolver{root: root, read: os.ReadFile}
+}
+
+// Expand replaces the include directives in content, read from path, with
+// the content of their targets, expanding nested directives too. Directives
+// whose target is missing, outside root or already being expanded are left
+// in place and reported as problems.
+func (r *IncludeResolver) Expand(content, path string) (string, []string) {
+	var problems []string
+	expanded := r.expand(content, path, []string{filepath.Clean(path)}, &problems)
+	return expanded, problems
+}
+
+func (r *IncludeResolver) expand(content, path string, stack []string, problems *[]string) string {
+	return includeDirective.ReplaceAllStringFunc(content, func(line string) string {
+		ref := includeDirective.FindStringSubmatch(line)[1]
+		target := filepath.Clean(filepath.Join(filepath.Dir(path), filepath.FromSlash(ref)))
+
+		if rel, err := filepath.Rel(r.root, target); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			*problems = append(*problems, fmt.Sprintf("%s: include %s is outside the input directory", path, ref))
+			return line
+		}
+		for _, open := range stack {
+			if open == target {
+				*problems = append(*problems, fmt.Sprintf("%s: include %s forms a cycle", path, ref))
+				return line
+			}
+		}
+
+		data, err := r.read(target)
+		if err != nil {
+			*problems = append(*problems, fmt.Sprintf("%s: include %s not found", path, ref))
+			return line
+		}
+
+		body := r.expand(strings.TrimSuffix(string(data), "\n"), target, append(stack, target), problems)
+		fence := FenceFor(body)
+		lang := strings.TrimPrefix(filepath.Ext(target), ".")
+		return fmt.Sprintf("%s%s\n%s\n%s", fence, lang, body, fence)
+	})
+}
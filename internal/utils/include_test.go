@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIncludeResolverExpand(t *testing.T) {
+	h := NewTestHelper(t)
+	defer h.Cleanup()
+	content := "Intro\n<!-- include: ../src/a.go -->\n  <!-- include: missing.txt -->\n<!-- include: ../../secret.txt -->\nnot <!-- include: a.go --> a directive\n"
+	readme := h.CreateTempFile("root/docs/README.md", content)
+	h.CreateTempFile("root/src/a.go", "package a\n// <!-- include: b.txt -->\n<!-- include: b.txt -->\n")
+	h.CreateTempFile("root/src/b.txt", "b body\n")
+	h.CreateTempFile("secret.txt", "secret\n")
+	root := filepath.Join(filepath.Dir(readme), "..")
+
+	got, problems := NewIncludeResolver(root).Expand(content, readme)
+
+	// The outer fence is lengthened to enclose the nested one
+	want := "Intro\n````go\npackage a\n// <!-- include: b.txt -->\n```txt\nb body\n```\n````\n" +
+		"  <!-- include: missing.txt -->\n<!-- include: ../../secret.txt -->\nnot <!-- include: a.go --> a directive\n"
+	if got != want {
+		t.Errorf("Expand() =\n%s\nwant\n%s", got, want)
+	}
+	if len(problems) != 2 || !strings.Contains(problems[0], "missing.txt not found") || !strings.Contains(problems[1], "outside the input directory") {
+		t.Errorf("problems = %q", problems)
+	}
+}
+
+func TestIncludeResolverCycle(t *testing.T) {
+	h := NewTestHelper(t)
+	defer h.Cleanup()
+	content := "A\n<!-- include: b.md -->\n"
+	a := h.CreateTempFile("root/a.md", content)
+	h.CreateTempFile("root/b.md", "B\n<!-- include: a.md -->\n")
+
+	got, problems := NewIncludeResolver(filepath.Dir(a)).Expand(content, a)
+
+	if want := "A\n```md\nB\n<!-- include: a.md -->\n```\n"; got != want {
+		t.Errorf("Expand() = %q, want %q", got, want)
+	}
+	if len(problems) != 1 || !strings.Contains(problems[0], "forms a cycle") {
+		t.Errorf("problems = %q, want a cycle", problems)
+	}
+}
@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// LanguageOverride sets the fence language of files matching Pattern. A
+// pattern like ".h" matches by extension; anything else is a gitignore-style
+// glob matched against the relative path, such as "src/matlab/**/*.m".
+type LanguageOverride struct {
+	Pattern  string `json:"pattern"`
+	Language string `json:"language"`
+}
+
+// ParseLanguageOverride parses a PATTERN=LANGUAGE flag value
+func ParseLanguageOverride(spec string) (LanguageOverride, error) {
+	pattern, language, ok := strings.Cut(spec, "=")
+	pattern, language = strings.TrimSpace(pattern), strings.TrimSpace(language)
+	if !ok || pattern == "" || language == "" {
+		return LanguageOverride{}, fmt.Errorf("invalid language override %q, expected PATTERN=LANGUAGE", spec)
+	}
+	return LanguageOverride{Pattern: pattern, Language: language}, nil
+}
+
+// isExtension reports whether the override matches by extension alone
+func (o LanguageOverride) isExtension() bool {
+	return strings.HasPrefix(o.Pattern, ".") && !strings.ContainsAny(o.Pattern, "/*?[")
+}
+
+// LanguageResolver picks fence languages, consulting overrides in order
+// before falling back to the file extension
+type LanguageResolver struct {
+	overrides []LanguageOverride
+	matchers  []*IgnoreMatcher // Compiled glob for each non-extension override
+}
+
+// NewLanguageResolver compiles the overrides; earlier ones take precedence
+func NewLanguageResolver(overrides []LanguageOverride) *LanguageResolver {
+	r := &LanguageResolver{overrides: overrides, matchers: make([]*IgnoreMatcher, len(overrides))}
+	for i, o := range overrides {
+		if !o.isExtension() {
+			r.matchers[i] = NewIgnoreMatcher([]string{o.Pattern}, false)
+		}
+	}
+	return r
+}
+
+// Language returns the fence language for a file
+func (r *LanguageResolver) Language(relPath string) string {
+	ext := filepath.Ext(relPath)
+	for i, o := range r.overrides {
+		if r.matchers[i] == nil {
+			if strings.EqualFold(o.Pattern, ext) {
+				return o.Language
+			}
+		} else if r.matchers[i].Matches(relPath) {
+			return o.Language
+		}
+	}
+	return strings.TrimPrefix(ext, ".")
+}
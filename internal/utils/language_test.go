@@ -0,0 +1,44 @@
+package utils
+
+import "testing"
+
+func TestParseLanguageOverride(t *testing.T) {
+	got, err := ParseLanguageOverride(" .h = cpp ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != (LanguageOverride{Pattern: ".h", Language: "cpp"}) {
+		t.Errorf("ParseLanguageOverride() = %+v", got)
+	}
+
+	for _, spec := range []string{".h", "=cpp", ".h="} {
+		if _, err := ParseLanguageOverride(spec); err == nil {
+			t.Errorf("ParseLanguageOverride(%q) should fail", spec)
+		}
+	}
+}
+
+func TestLanguageResolver(t *testing.T) {
+	r := NewLanguageResolver([]LanguageOverride{
+		{Pattern: "src/matlab/**/*.m", Language: "matlab"},
+		{Pattern: ".m", Language: "objectivec"},
+		{Pattern: ".H", Language: "cpp"},
+	})
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"src/matlab/sim/run.m", "matlab"},
+		{"ios/View.m", "objectivec"},
+		{"include/util.h", "cpp"},
+		{"main.go", "go"},
+		{"Makefile", ""},
+	}
+
+	for _, tt := range tests {
+		if got := r.Language(tt.path); got != tt.want {
+			t.Errorf("Language(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
@@ -7,7 +7,7 @@ import (
 	"time"
 )
 
-// Logger provides structured logging with emojis
+// Logger provides structured logging with emoji prefixes
 type Logger struct {
 	showTimestamp bool
 }
@@ -19,16 +19,16 @@ func NewLogger(showTimestamp bool) *Logger {
 	}
 }
 
-// Log prints a formatted log message with optional emoji
-func (l *Logger) Log(format string, emoji string, args ...interface{}) {
+// Log prints a formatted log message with an optional symbol prefix
+func (l *Logger) Log(format string, icon Symbol, args ...interface{}) {
 	var builder strings.Builder
 
 	if l.showTimestamp {
 		builder.WriteString(time.Now().Format("15:04:05 "))
 	}
 
-	if emoji != "" {
-		builder.WriteString(emoji)
+	if prefix := icon.String(); prefix != "" {
+		builder.WriteString(prefix)
 		builder.WriteString(" ")
 	}
 
@@ -40,21 +40,21 @@ func (l *Logger) Log(format string, emoji string, args ...interface{}) {
 // LogDebug prints a debug message
 func (l *Logger) LogDebug(format string, args ...interface{}) {
 	if os.Getenv("DEBUG") != "" {
-		l.Log(format, "🔍", args...)
+		l.Log(format, IconSearch, args...)
 	}
 }
 
 // LogError prints an error message
 func (l *Logger) LogError(format string, args ...interface{}) {
-	l.Log(format, "❌", args...)
+	l.Log(format, IconError, args...)
 }
 
 // LogWarning prints a warning message
 func (l *Logger) LogWarning(format string, args ...interface{}) {
-	l.Log(format, "⚠️", args...)
+	l.Log(format, IconWarning, args...)
 }
 
 // LogSuccess prints a success message
 func (l *Logger) LogSuccess(format string, args ...interface{}) {
-	l.Log(format, "✅ ", args...)
+	l.Log(format, IconSuccess, args...)
 }
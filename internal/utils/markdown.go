@@ -0,0 +1,219 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	markdownFenceRegex = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+	markdownBlockRegex = regexp.MustCompile(`^ {0,3}([#>|<]|[-*+][ \t]|\d{1,9}[.)]([ \t]|$)|\[[^\]]+\]:|(-[ \t]*){3,}$|(\*[ \t]*){3,}$|(_[ \t]*){3,}$|=+[ \t]*$)`)
+)
+
+// WrapMarkdown reflows prose paragraphs in markdown to lines of at most width
+// characters. Fenced and indented code, headings, lists and their indented
+// continuations, tables, quotes, HTML and front matter are left as they are,
+// as are words longer than width. Hard line breaks, written as two trailing
+// spaces or a backslash, are kept.
+func WrapMarkdown(content string, width int) string {
+	if width <= 0 {
+		return content
+	}
+
+	eol := "\n"
+	if strings.Contains(content, "\r\n") {
+		eol = "\r\n"
+	}
+	lines := strings.Split(content, "\n")
+
+	var out, paragraph []string
+	flush := func(hardBreak string) {
+		if len(paragraph) > 0 {
+			wrapped := wrapWords(strings.Fields(strings.Join(paragraph, " ")), width)
+			wrapped[len(wrapped)-1] += hardBreak
+			out = append(out, wrapped...)
+			paragraph = nil
+		}
+	}
+
+	var fence string
+	inFrontMatter := len(lines) > 0 && strings.TrimRight(lines[0], "\r") == frontMatterDelimiter
+	for i, raw := range lines {
+		line := strings.TrimSuffix(raw, "\r")
+
+		switch {
+		case inFrontMatter:
+			inFrontMatter = i == 0 || line != frontMatterDelimiter
+			out = append(out, line)
+			continue
+		case fence != "":
+			if m := markdownFenceRegex.FindStringSubmatch(line); m != nil && m[1][0] == fence[0] && len(m[1]) >= len(fence) &&
+				strings.TrimSpace(line[strings.Index(line, m[1])+len(m[1]):]) == "" {
+				fence = ""
+			}
+			out = append(out, line)
+			continue
+		}
+
+		if m := markdownFenceRegex.FindStringSubmatch(line); m != nil {
+			flush("")
+			fence = m[1]
+			out = append(out, line)
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		indented := len(paragraph) == 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t"))
+		if trimmed == "" || indented || markdownBlockRegex.MatchString(line) {
+			flush("")
+			out = append(out, line)
+			continue
+		}
+
+		switch {
+		case strings.HasSuffix(line, "  "):
+			paragraph = append(paragraph, trimmed)
+			flush("  ")
+		case strings.HasSuffix(trimmed, `\`):
+			paragraph = append(paragraph, strings.TrimSuffix(trimmed, `\`))
+			flush(`\`)
+		default:
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	flush("")
+
+	return strings.Join(out, eol)
+}
+
+// wrapWords greedily packs words into lines of at most width characters,
+// giving a word longer than width a line of its own. A word that would turn
+// a new line into a list item, heading or other block stays on the line
+// before, even if that makes the line too long.
+func wrapWords(words []string, width int) []string {
+	var lines []string
+	var current strings.Builder
+	for _, word := range words {
+		if current.Len() > 0 && current.Len()+1+len(word) > width && !markdownBlockRegex.MatchString(word+" ") {
+			lines = append(lines, current.String())
+			current.Reset()
+		}
+		if current.Len() > 0 {
+			current.WriteByte(' ')
+		}
+		current.WriteString(word)
+	}
+	return append(lines, current.String())
+}
+
+var (
+	markdownATXHeadingRegex = regexp.MustCompile(`^ {0,3}#{1,6}(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	markdownSetextRegex     = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	markdownBreakRegex      = regexp.MustCompile(`^ {0,3}((\*[ \t]*){3,}|(-[ \t]*){3,}|(_[ \t]*){3,})$`)
+	markdownLinkDefRegex    = regexp.MustCompile(`^ {0,3}\[[^\]]+\]:\s`)
+	markdownImageRegex      = regexp.MustCompile(`!\[([^\]]*)\](\([^)]*\)|\[[^\]]*\])`)
+	markdownLinkRegex       = regexp.MustCompile(`\[([^\]]+)\](\([^)]*\)|\[[^\]]*\])`)
+	markdownStrongRegex     = regexp.MustCompile(`(\*\*|__)(\S(?:.*?\S)?)(\*\*|__)`)
+	markdownStarRegex       = regexp.MustCompile(`\*(\S(?:.*?\S)?)\*`)
+	markdownUnderscoreRegex = regexp.MustCompile(`(^|\W)_(\S(?:.*?\S)?)_(\W|$)`)
+	markdownStrikeRegex     = regexp.MustCompile(`~~(\S(?:.*?\S)?)~~`)
+)
+
+// MarkdownToPlainText strips markdown syntax that only affects rendering:
+// headings become plain lines, links and images their text, and emphasis
+// and strikethrough markers are removed. Thematic breaks, setext underlines
+// and link reference definitions are dropped. Fenced and indented code,
+// inline code spans and front matter are left as they are.
+func MarkdownToPlainText(content string) string {
+	eol := "\n"
+	if strings.Contains(content, "\r\n") {
+		eol = "\r\n"
+	}
+	lines := strings.Split(content, "\n")
+
+	var out []string
+	var fence string
+	inParagraph := false
+	inFrontMatter := len(lines) > 0 && strings.TrimRight(lines[0], "\r") == frontMatterDelimiter
+	for i, raw := range lines {
+		line := strings.TrimSuffix(raw, "\r")
+
+		switch {
+		case inFrontMatter:
+			inFrontMatter = i == 0 || line != frontMatterDelimiter
+			out = append(out, line)
+			continue
+		case fence != "":
+			if m := markdownFenceRegex.FindStringSubmatch(line); m != nil && m[1][0] == fence[0] && len(m[1]) >= len(fence) &&
+				strings.TrimSpace(line[strings.Index(line, m[1])+len(m[1]):]) == "" {
+				fence = ""
+			}
+			out = append(out, line)
+			continue
+		}
+
+		if m := markdownFenceRegex.FindStringSubmatch(line); m != nil {
+			fence = m[1]
+			inParagraph = false
+			out = append(out, line)
+			continue
+		}
+
+		switch {
+		case strings.TrimSpace(line) == "":
+			inParagraph = false
+			out = append(out, line)
+		case !inParagraph && (strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t")):
+			out = append(out, line)
+		case inParagraph && markdownSetextRegex.MatchString(line), markdownBreakRegex.MatchString(line):
+			inParagraph = false
+		case markdownLinkDefRegex.MatchString(line):
+			inParagraph = false
+		case markdownATXHeadingRegex.MatchString(line):
+			inParagraph = false
+			out = append(out, plainInline(markdownATXHeadingRegex.FindStringSubmatch(line)[1]))
+		default:
+			inParagraph = true
+			out = append(out, plainInline(line))
+		}
+	}
+
+	return strings.Join(out, eol)
+}
+
+// plainInline removes link, image and emphasis syntax from a line of
+// markdown, leaving inline code spans untouched
+func plainInline(line string) string {
+	var b strings.Builder
+	for line != "" {
+		start := strings.IndexByte(line, '`')
+		if start < 0 {
+			b.WriteString(stripInlineMarkup(line))
+			break
+		}
+
+		ticks := len(line[start:]) - len(strings.TrimLeft(line[start:], "`"))
+		closing := strings.Index(line[start+ticks:], line[start:start+ticks])
+		if closing < 0 {
+			b.WriteString(stripInlineMarkup(line))
+			break
+		}
+
+		end := start + ticks + closing + ticks
+		b.WriteString(stripInlineMarkup(line[:start]))
+		b.WriteString(line[start:end])
+		line = line[end:]
+	}
+	return b.String()
+}
+
+// stripInlineMarkup removes link, image and emphasis syntax from text that
+// contains no code spans
+func stripInlineMarkup(text string) string {
+	text = markdownImageRegex.ReplaceAllString(text, "$1")
+	text = markdownLinkRegex.ReplaceAllString(text, "$1")
+	text = markdownStrongRegex.ReplaceAllString(text, "$2")
+	text = markdownStrikeRegex.ReplaceAllString(text, "$1")
+	text = markdownStarRegex.ReplaceAllString(text, "$1")
+	return markdownUnderscoreRegex.ReplaceAllString(text, "$1$2$3")
+}
@@ -0,0 +1,50 @@
+package utils
+
+import "testing"
+
+func TestWrapMarkdown(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		width int
+		want  string
+	}{
+		{"reflows prose", "one two three four five six", 10, "one two\nthree four\nfive six"},
+		{"short lines kept", "one two", 10, "one two"},
+		{"code kept", "```\none two three four five six\n```", 10, "```\none two three four five six\n```"},
+		{"heading kept", "# one two three four five six", 10, "# one two three four five six"},
+		{"long word kept", "supercalifragilistic word", 10, "supercalifragilistic\nword"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WrapMarkdown(tt.in, tt.width); got != tt.want {
+				t.Errorf("WrapMarkdown(%q, %d) = %q, want %q", tt.in, tt.width, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkdownToPlainText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"heading", "# Title\n\nText", "Title\n\nText"},
+		{"emphasis", "Some **bold**, *italic* and ~~old~~ text", "Some bold, italic and old text"},
+		{"links and images", "See [the docs](https://example.com) and ![logo](logo.png)", "See the docs and logo"},
+		{"inline code kept", "Run `go **test**` now", "Run `go **test**` now"},
+		{"fenced code kept", "```go\n# not a heading\n**x**\n```", "```go\n# not a heading\n**x**\n```"},
+		{"thematic break dropped", "a\n\n---\n\nb", "a\n\n\nb"},
+		{"link definition dropped", "[docs]: https://example.com\ntext", "text"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MarkdownToPlainText(tt.in); got != tt.want {
+				t.Errorf("MarkdownToPlainText(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := NewRateLimiter(10000)
+
+	// The initial bucket allows one second's worth without waiting
+	start := time.Now()
+	l.WaitN(10000)
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("first WaitN within the bucket took %v", elapsed)
+	}
+
+	// Concurrent callers share the rate: 4 × 500 bytes at 10000 B/s is about 200ms
+	start = time.Now()
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.WaitN(500)
+		}()
+	}
+	wg.Wait()
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond || elapsed > time.Second {
+		t.Errorf("concurrent WaitN took %v, want about 200ms", elapsed)
+	}
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Lines kept verbatim from each end of a summarized file
+const (
+	summaryHeadLines  = 20
+	summaryTailLines  = 10
+	summaryMaxOutline = 60
+)
+
+// outlineRegex matches unindented lines that introduce a declaration or a
+// section in common languages and markup
+var outlineRegex = regexp.MustCompile(`^(#{1,6} |(export\s+)?(default\s+)?(async\s+)?(func|function|def|class|type|interface|struct|enum|trait|impl|module|package|const|var|let|pub\s+fn|fn)\b)`)
+
+// Summarize replaces content with a deterministic extractive summary: a
+// marker, the first and last lines, and an outline of the declarations and
+// headings in between, each prefixed with its line number
+func Summarize(content string) string {
+	lines := strings.Split(content, "\n")
+	if len(lines) <= summaryHeadLines+summaryTailLines {
+		return content
+	}
+
+	middle := lines[summaryHeadLines : len(lines)-summaryTailLines]
+
+	var outline []string
+	for i, line := range middle {
+		if outlineRegex.MatchString(line) {
+			outline = append(outline, fmt.Sprintf("%d: %s", summaryHeadLines+i+1, strings.TrimSpace(line)))
+		}
+	}
+	omitted := 0
+	if len(outline) > summaryMaxOutline {
+		omitted = len(outline) - summaryMaxOutline
+		outline = outline[:summaryMaxOutline]
+	}
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "[summarized: %d lines, ~%d tokens; showing first %d and last %d lines]\n",
+		len(lines), EstimateTokenCount(content), summaryHeadLines, summaryTailLines)
+	buf.WriteString(strings.Join(lines[:summaryHeadLines], "\n"))
+	fmt.Fprintf(&buf, "\n[... %d lines omitted ...]\n", len(middle))
+	if len(outline) > 0 {
+		buf.WriteString("[outline]\n")
+		buf.WriteString(strings.Join(outline, "\n"))
+		if omitted > 0 {
+			fmt.Fprintf(&buf, "\n[... %d more outline entries ...]", omitted)
+		}
+		buf.WriteString("\n[end outline]\n")
+	}
+	buf.WriteString(strings.Join(lines[len(lines)-summaryTailLines:], "\n"))
+
+	return buf.String()
+}
@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	short := strings.Repeat("line\n", 10)
+	if got := Summarize(short); got != short {
+		t.Errorf("short content should be unchanged, got %q", got)
+	}
+
+	lines := make([]string, 50)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("l%d", i+1)
+	}
+	lines[24] = "func Run() {"
+	lines[29] = "    func nested() {"
+	got := Summarize(strings.Join(lines, "\n"))
+
+	for _, want := range []string{
+		"[summarized: 50 lines,",
+		"l1\n",
+		"l20\n[... 20 lines omitted ...]\n",
+		"[outline]\n25: func Run() {\n[end outline]\nl41\n",
+		"l50",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Summarize() missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "l21") || strings.Contains(got, "nested") {
+		t.Errorf("Summarize() kept omitted or indented lines:\n%s", got)
+	}
+	if Summarize(strings.Join(lines, "\n")) != got {
+		t.Error("Summarize() is not deterministic")
+	}
+}
@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"os"
+	"strings"
+)
+
+// Symbol is a decorative marker for console output with a plain ASCII
+// fallback for terminals that can't render emoji
+type Symbol struct {
+	Emoji string
+	ASCII string
+}
+
+// Console output decorations. Emoji drawn with a variation selector
+// (U+FE0F) render two cells wide but count as one, so they carry a
+// trailing space to keep the following text aligned.
+var (
+	IconSearch     = Symbol{"🔍", "[SCAN]"}
+	IconFound      = Symbol{"📚", "[FOUND]"}
+	IconFile       = Symbol{"📄", "[FILE]"}
+	IconError      = Symbol{"❌", "[ERROR]"}
+	IconWarning    = Symbol{"⚠️ ", "[WARN]"}
+	IconSuccess    = Symbol{"✅", "[OK]"}
+	IconSummary    = Symbol{"📊", "[SUMMARY]"}
+	IconFiles      = Symbol{"📁", "[FILES]"}
+	IconSize       = Symbol{"💾", "[SIZE]"}
+	IconRate       = Symbol{"🎯", "[RATE]"}
+	IconTokens     = Symbol{"🔤", "[TOKENS]"}
+	IconTip        = Symbol{"💡", "[TIP]"}
+	IconNote       = Symbol{"📝", "[NOTE]"}
+	IconList       = Symbol{"📋", "[LIST]"}
+	IconDone       = Symbol{"✨", "[DONE]"}
+	IconSizes      = Symbol{"📏", "[SIZES]"}
+	IconDetails    = Symbol{"🔍", "[DETAILS]"}
+	IconEstimate   = Symbol{"📐", "[ESTIMATE]"}
+	IconLanguages  = Symbol{"🗂️ ", "[LANGUAGES]"}
+	IconSavings    = Symbol{"✂️ ", "[SAVINGS]"}
+	IconDuplicates = Symbol{"👯", "[DUPLICATES]"}
+
+	Bullet    = Symbol{"•", "-"}
+	RangeDash = Symbol{"–", "-"}
+	PlusMinus = Symbol{"±", "+/-"}
+	ruleChar  = Symbol{"═", "="}
+)
+
+// Separators used inside the digest itself, which is always UTF-8
+const (
+	TitleSeparator = " \u2014 " // Em dash between a file header and its title
+	EncodingArrow  = " \u2192 " // Arrow in transcoding notes
+)
+
+// emojiEnabled controls whether symbols render as emoji or ASCII
+var emojiEnabled = true
+
+// SetEmoji enables or disables emoji in console output
+func SetEmoji(enabled bool) {
+	emojiEnabled = enabled
+}
+
+// String renders the symbol for the current output mode
+func (s Symbol) String() string {
+	if emojiEnabled {
+		return s.Emoji
+	}
+	return s.ASCII
+}
+
+// Rule returns a heading underline n characters wide
+func Rule(n int) string {
+	return strings.Repeat(ruleChar.String(), n)
+}
+
+// IsUTF8Terminal reports whether stdout is a terminal with a UTF-8 locale,
+// where emoji can be expected to render
+func IsUTF8Terminal() bool {
+	if !IsTerminal(os.Stdout) {
+		return false
+	}
+
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			value = strings.ToLower(value)
+			return strings.Contains(value, "utf-8") || strings.Contains(value, "utf8")
+		}
+	}
+	return false
+}
+
+// IsTerminal reports whether f is connected to a terminal. The null device
+// is a character device too, but is how scripts usually detach stdin, so it
+// doesn't count.
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	null, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(info, null)
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"os"
+	"testing"
+	"unicode"
+)
+
+func TestIsTerminal(t *testing.T) {
+	null, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer null.Close()
+
+	h := NewTestHelper(t)
+	defer h.Cleanup()
+	file, err := os.Open(h.CreateTempFile("input.txt", "y\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	for _, f := range []*os.File{null, file} {
+		if IsTerminal(f) {
+			t.Errorf("IsTerminal(%s) = true, want false", f.Name())
+		}
+	}
+}
+
+func TestSymbolModes(t *testing.T) {
+	t.Cleanup(func() { SetEmoji(true) })
+
+	SetEmoji(true)
+	if got := IconWarning.String(); got != "⚠️ " {
+		t.Errorf("IconWarning with emoji = %q", got)
+	}
+	if got := Rule(3); got != "═══" {
+		t.Errorf("Rule(3) with emoji = %q", got)
+	}
+
+	SetEmoji(false)
+	if got := IconWarning.String(); got != "[WARN]" {
+		t.Errorf("IconWarning without emoji = %q, want [WARN]", got)
+	}
+	if got := Rule(3); got != "===" {
+		t.Errorf("Rule(3) without emoji = %q, want ===", got)
+	}
+
+	for _, s := range []Symbol{IconSearch, IconFound, IconFile, IconError, IconWarning, IconSuccess, IconSummary,
+		IconFiles, IconSize, IconRate, IconTokens, IconTip, IconNote, IconList, IconDone, IconSizes, IconDetails,
+		IconEstimate, IconLanguages, IconSavings, IconDuplicates, Bullet, RangeDash, PlusMinus, ruleChar} {
+		for _, r := range s.ASCII {
+			if r > unicode.MaxASCII {
+				t.Errorf("fallback for %q is not ASCII: %q", s.Emoji, s.ASCII)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Output path placeholders
+const (
+	DatePlaceholder   = "{date}"
+	GitSHAPlaceholder = "{gitsha}"
+)
+
+// File header fields
+const (
+	HeaderPathField  = "{path}"
+	HeaderLangField  = "{lang}"
+	HeaderSizeField  = "{size}"
+	HeaderIndexField = "{index}"
+)
+
+// DefaultHeaderFormat reproduces the standard "# path" file header
+const DefaultHeaderFormat = "# " + HeaderPathField
+
+var headerFieldRegex = regexp.MustCompile(`\{[^{}]*\}`)
+
+// HeaderFields holds the values substituted into a header format
+type HeaderFields struct {
+	Path  string
+	Lang  string
+	Size  int64
+	Index int
+}
+
+// ValidateHeaderFormat checks that a header format is a single line using
+// only known fields
+func ValidateHeaderFormat(format string) error {
+	if strings.ContainsAny(format, "\r\n") {
+		return fmt.Errorf("header format must be a single line")
+	}
+	if strings.Count(format, "{") != strings.Count(format, "}") {
+		return fmt.Errorf("header format has unbalanced braces")
+	}
+
+	for _, field := range headerFieldRegex.FindAllString(format, -1) {
+		switch field {
+		case HeaderPathField, HeaderLangField, HeaderSizeField, HeaderIndexField:
+		default:
+			return fmt.Errorf("unknown header field: %s", field)
+		}
+	}
+
+	if !strings.Contains(format, HeaderPathField) {
+		return fmt.Errorf("header format must include %s", HeaderPathField)
+	}
+
+	return nil
+}
+
+// ExpandHeaderFormat renders a file header from a validated format
+func ExpandHeaderFormat(format string, fields HeaderFields) string {
+	return strings.NewReplacer(
+		HeaderPathField, fields.Path,
+		HeaderLangField, fields.Lang,
+		HeaderSizeField, FormatSize(fields.Size),
+		HeaderIndexField, strconv.Itoa(fields.Index),
+	).Replace(format)
+}
+
+// ExpandOutputTemplate replaces date and git placeholders in an output path.
+// The git SHA is resolved from repoDir only when the placeholder is present.
+func ExpandOutputTemplate(tmpl, dateLayout, repoDir string) (string, error) {
+	if strings.Contains(tmpl, DatePlaceholder) {
+		tmpl = strings.ReplaceAll(tmpl, DatePlaceholder, time.Now().Format(dateLayout))
+	}
+
+	if strings.Contains(tmpl, GitSHAPlaceholder) {
+		sha, err := GitShortSHA(repoDir)
+		if err != nil {
+			return "", err
+		}
+		tmpl = strings.ReplaceAll(tmpl, GitSHAPlaceholder, sha)
+	}
+
+	return tmpl, nil
+}
+
+// Extensions given to output paths that have none, by what is written
+const (
+	DefaultOutputExt = ".md"    // Markdown digest
+	JSONStreamExt    = ".jsonl" // JSON lines written by the result stream
+)
+
+// WithDefaultExt appends ext to an output path or split pattern without an
+// extension; an explicit extension is kept
+func WithDefaultExt(path, ext string) string {
+	if path == "" || filepath.Ext(path) != "" {
+		return path
+	}
+	return path + ext
+}
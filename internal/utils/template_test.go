@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithDefaultExt(t *testing.T) {
+	tests := []struct {
+		path string
+		ext  string
+		want string
+	}{
+		{"digest", DefaultOutputExt, "digest.md"},
+		{"out/digest", JSONStreamExt, "out/digest.jsonl"},
+		{"digest.txt", DefaultOutputExt, "digest.txt"},
+		{"results.jsonl", DefaultOutputExt, "results.jsonl"},
+		{"digest_part%d", DefaultOutputExt, "digest_part%d.md"},
+		{"", DefaultOutputExt, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := WithDefaultExt(tt.path, tt.ext); got != tt.want {
+				t.Errorf("WithDefaultExt(%q, %q) = %q, want %q", tt.path, tt.ext, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandOutputTemplate(t *testing.T) {
+	today := time.Now().Format("2006-01-02")
+
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{"codebase.md", "codebase.md"},
+		{"digest-{date}.md", "digest-" + today + ".md"},
+		{"{date}/{date}.md", today + "/" + today + ".md"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tmpl, func(t *testing.T) {
+			got, err := ExpandOutputTemplate(tt.tmpl, "2006-01-02", t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("ExpandOutputTemplate(%q) = %q, want %q", tt.tmpl, got, tt.want)
+			}
+		})
+	}
+
+	// {gitsha} needs a repository
+	if _, err := ExpandOutputTemplate("digest-{gitsha}.md", "2006-01-02", t.TempDir()); err == nil {
+		t.Error("ExpandOutputTemplate with {gitsha} outside a repository succeeded, want error")
+	}
+}
+
+func TestHeaderFormat(t *testing.T) {
+	tests := []struct {
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{DefaultHeaderFormat, "# src/main.go", false},
+		{"## {index}. {path} ({lang}, {size})", "## 3. src/main.go (go, 2.0 KB)", false},
+		{"# {lang}", "", true},
+		{"# {path} {owner}", "", true},
+		{"# {path", "", true},
+		{"# {path}\n", "", true},
+	}
+
+	fields := HeaderFields{Path: "src/main.go", Lang: "go", Size: 2048, Index: 3}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			err := ValidateHeaderFormat(tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateHeaderFormat(%q) error = %v, wantErr %v", tt.format, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := ExpandHeaderFormat(tt.format, fields); got != tt.want {
+				t.Errorf("ExpandHeaderFormat(%q) = %q, want %q", tt.format, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,18 +2,19 @@ package utils
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
 	"testing"
 )
 
 // TestHelper provides utility functions for testing
 type TestHelper struct {
-	t      *testing.T
+	t      testing.TB
 	tmpDir string
 }
 
 // NewTestHelper creates a new test helper
-func NewTestHelper(t *testing.T) *TestHelper {
+func NewTestHelper(t testing.TB) *TestHelper {
 	tmpDir, err := os.MkdirTemp("", "aidig-test-*")
 	if err != nil {
 		t.Fatal(err)
@@ -52,3 +53,18 @@ func (h *TestHelper) CreateTempDir(name string) string {
 	}
 	return path
 }
+
+// Git runs a git command in dir with a fixed identity and fails the test on error
+func (h *TestHelper) Git(dir string, args ...string) string {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=Test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=Test", "GIT_COMMITTER_EMAIL=test@example.com",
+		"GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1",
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		h.t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return string(out)
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 	"unicode"
@@ -32,12 +33,139 @@ func RemoveWhitespace(s string) string {
 	return strings.TrimSpace(buf.String())
 }
 
+// StripTrailingWhitespace trims spaces and tabs at the end of each line and
+// drops blank lines at the end of the text, leaving indentation intact. LF
+// and CRLF line endings are both kept as they are.
+func StripTrailingWhitespace(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		body, crlf := strings.CutSuffix(line, "\r")
+		lines[i] = strings.TrimRight(body, " \t")
+		if crlf {
+			lines[i] += "\r"
+		}
+	}
+
+	end := len(lines)
+	for end > 0 && (lines[end-1] == "" || lines[end-1] == "\r") {
+		end--
+	}
+	if end == 0 {
+		return ""
+	}
+	lines[end-1] = strings.TrimSuffix(lines[end-1], "\r")
+
+	return strings.Join(lines[:end], "\n")
+}
+
+// CapBlankLines collapses each run of blank lines to at most n lines,
+// leaving the content of non-blank lines untouched
+func CapBlankLines(s string, n int) string {
+	lines := strings.Split(s, "\n")
+	kept := lines[:0]
+	blank := 0
+
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			blank++
+			if blank > n {
+				continue
+			}
+		} else {
+			blank = 0
+		}
+		kept = append(kept, line)
+	}
+
+	return strings.Join(kept, "\n")
+}
+
+// Dedent removes the leading whitespace common to all non-blank lines,
+// preserving relative indentation. Blank lines are emptied.
+func Dedent(s string) string {
+	lines := strings.Split(s, "\n")
+
+	var common string
+	found := false
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if !found {
+			common = indent
+			found = true
+			continue
+		}
+
+		i := 0
+		for i < len(common) && i < len(indent) && common[i] == indent[i] {
+			i++
+		}
+		common = common[:i]
+	}
+
+	if common == "" {
+		return s
+	}
+
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			lines[i] = ""
+			continue
+		}
+		lines[i] = strings.TrimPrefix(line, common)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Line ending names accepted by NormalizeLineEndings
+const (
+	LineEndingLF   = "lf"
+	LineEndingCRLF = "crlf"
+)
+
+// NormalizeLineEndings converts all line endings in s to the given style.
+// CRLF input is normalized to LF first so content is never double-converted.
+func NormalizeLineEndings(s, eol string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	if eol == LineEndingCRLF {
+		s = strings.ReplaceAll(s, "\n", "\r\n")
+	}
+	return s
+}
+
+// FenceFor returns a backtick fence longer than any backtick run in content,
+// and at least three backticks long
+func FenceFor(content string) string {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+
+	if longest < 3 {
+		return "```"
+	}
+	return strings.Repeat("`", longest+1)
+}
+
 // EscapeTripleBackticks escapes triple backticks in text
 func EscapeTripleBackticks(s string) string {
 	return strings.ReplaceAll(s, "```", "\\`\\`\\`")
 }
 
-// NaturalLess implements natural string comparison
+// NaturalLess implements natural string comparison. It is a strict total
+// order, so sorts using it are deterministic: strings that tie part by part
+// fall back to plain comparison.
 func NaturalLess(s1, s2 string) bool {
 	s1Parts := numberRegex.Split(s1, -1)
 	s2Parts := numberRegex.Split(s2, -1)
@@ -66,7 +194,10 @@ func NaturalLess(s1, s2 string) bool {
 		}
 	}
 
-	return len(s1) < len(s2)
+	if len(s1Parts) != len(s2Parts) {
+		return len(s1Parts) < len(s2Parts)
+	}
+	return s1 < s2
 }
 
 // EstimateTokenCount provides a rough estimation of tokens in text
@@ -84,6 +215,61 @@ func EstimateTokenCount(text string) int {
 	return charCount / avgCharsPerToken
 }
 
+// TruncateToTokens cuts text to roughly maxTokens estimated tokens, preferring
+// a line boundary close to the cut. It returns the kept text and the
+// estimated tokens removed.
+func TruncateToTokens(text string, maxTokens int) (string, int) {
+	const (
+		avgCharsPerToken = 4
+		lineBackoff      = 5 // Back up to a newline only within the last 1/lineBackoff of the budget
+	)
+
+	maxChars := maxTokens * avgCharsPerToken
+	charCount := 0
+	cut := -1
+	nl, nlChars := -1, 0
+	for i, r := range text {
+		if r == '\n' {
+			nl, nlChars = i, charCount
+		}
+		if unicode.IsPrint(r) {
+			if charCount == maxChars {
+				cut = i
+				break
+			}
+			charCount++
+		}
+	}
+
+	if cut < 0 {
+		return text, 0
+	}
+
+	// Prefer the last line boundary before the cut, unless going back to it
+	// would give up too much of the budget, as with one very long line
+	if nl > 0 && maxChars-nlChars <= maxChars/lineBackoff {
+		cut = nl
+	}
+
+	return text[:cut], EstimateTokenCount(text[cut:])
+}
+
+// FormatSize formats a byte count in human-readable units
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
 func isWhitespace(r rune) bool {
 	switch r {
 	case ' ', '\t', '\n', '\v', '\f', '\r':
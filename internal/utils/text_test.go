@@ -0,0 +1,197 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStripTrailingWhitespace(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"lf", "func f() {  \n\treturn\t\n}\n\n\n", "func f() {\n\treturn\n}"},
+		{"crlf", "func f() {  \r\n\treturn\t\r\n}\r\n", "func f() {\r\n\treturn\r\n}"},
+		{"crlf trailing blank lines", "a \r\n  \r\n\t\r\n", "a"},
+		{"mixed endings", "a \r\nb  \nc", "a\r\nb\nc"},
+		{"indentation kept", "  a\n    b  ", "  a\n    b"},
+		{"only blank", " \n\t\r\n", ""},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StripTrailingWhitespace(tt.in); got != tt.want {
+				t.Errorf("StripTrailingWhitespace(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapBlankLines(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		n    int
+		want string
+	}{
+		{"caps runs", "a\n\n\n\nb\n\n\nc", 1, "a\n\nb\n\nc"},
+		{"keeps shorter runs", "a\n\nb", 2, "a\n\nb"},
+		{"whitespace-only lines are blank", "a\n  \n\t\n\nb", 1, "a\n  \nb"},
+		{"zero drops blank lines", "a\n\n\nb\n", 0, "a\nb"},
+		{"content untouched", "  a  \n\tb", 1, "  a  \n\tb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CapBlankLines(tt.in, tt.n); got != tt.want {
+				t.Errorf("CapBlankLines(%q, %d) = %q, want %q", tt.in, tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateToTokens(t *testing.T) {
+	long := strings.Repeat("x", 400)
+	lines := strings.Repeat("abcdefghi\n", 20) // 9 printable characters per line
+
+	tests := []struct {
+		name      string
+		text      string
+		maxTokens int
+		wantKept  string
+		wantSaved int
+	}{
+		{"fits", "short text", 10, "short text", 0},
+		{"cuts at nearby newline", lines, 10, strings.Repeat("abcdefghi\n", 4)[:39], 36},
+		{"long line after short first line", "header\n" + long, 25, "header\n" + long[:94], 76},
+		{"single long line", long, 10, long[:40], 90},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, saved := TruncateToTokens(tt.text, tt.maxTokens)
+			if kept != tt.wantKept {
+				t.Errorf("kept %q, want %q", kept, tt.wantKept)
+			}
+			if saved != tt.wantSaved {
+				t.Errorf("saved %d tokens, want %d", saved, tt.wantSaved)
+			}
+		})
+	}
+}
+
+func TestDedent(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"spaces", "    a\n      b\n    c", "a\n  b\nc"},
+		{"tabs", "\t\tif x {\n\t\t\ty()\n\t\t}", "if x {\n\ty()\n}"},
+		{"blank lines emptied", "  a\n   \n  b", "a\n\nb"},
+		{"mixed indentation kept", "\ta\n    b", "\ta\n    b"},
+		{"no indentation", "a\n  b", "a\n  b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Dedent(tt.in); got != tt.want {
+				t.Errorf("Dedent(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNaturalLess(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"file2.go", "file10.go", true},
+		{"file10.go", "file2.go", false},
+		{"part9", "part10", true},
+		{"a.go", "b.go", true},
+		{"v1.2.10", "v1.10.2", true},
+		{"file02.go", "file2.go", false},
+		{"file2.go", "file02.go", true},
+		{"same", "same", false},
+		{"x", "x1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" < "+tt.b, func(t *testing.T) {
+			if got := NaturalLess(tt.a, tt.b); got != tt.want {
+				t.Errorf("NaturalLess(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNaturalLessIsStrict(t *testing.T) {
+	names := []string{"a1", "a01", "a001", "a10", "a2", "b", "a", "1a", "01a", "a1b", "a1a"}
+	for _, a := range names {
+		if NaturalLess(a, a) {
+			t.Errorf("NaturalLess(%q, %q) = true, want false", a, a)
+		}
+		for _, b := range names {
+			if a != b && NaturalLess(a, b) == NaturalLess(b, a) {
+				t.Errorf("NaturalLess(%q, %q) and NaturalLess(%q, %q) agree, want exactly one true", a, b, b, a)
+			}
+		}
+	}
+}
+
+func TestFenceFor(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"no backticks", "plain", "```"},
+		{"inline code", "use `x` or ``y``", "```"},
+		{"triple", "```go\nx\n```", "````"},
+		{"quadruple", "````md\n```go\n```\n````", "`````"},
+		{"longest run wins", "``` and ``````` and ````", "````````"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FenceFor(tt.content); got != tt.want {
+				t.Errorf("FenceFor(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		size int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KB"},
+		{1536, "1.5 KB"},
+		{5 * 1024 * 1024, "5.0 MB"},
+		{3 << 30, "3.0 GB"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatSize(tt.size); got != tt.want {
+			t.Errorf("FormatSize(%d) = %q, want %q", tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeLineEndings(t *testing.T) {
+	const mixed = "a\r\nb\nc\r\n\r\nd"
+
+	if got, want := NormalizeLineEndings(mixed, LineEndingLF), "a\nb\nc\n\nd"; got != want {
+		t.Errorf("NormalizeLineEndings(lf) = %q, want %q", got, want)
+	}
+	if got, want := NormalizeLineEndings(mixed, LineEndingCRLF), "a\r\nb\r\nc\r\n\r\nd"; got != want {
+		t.Errorf("NormalizeLineEndings(crlf) = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var todoRegex = regexp.MustCompile(`\b(TODO|FIXME|HACK)\b[:\s]*(.*)`)
+
+// Todo is a TODO/FIXME/HACK marker found in a file
+type Todo struct {
+	Line   int
+	Marker string
+	Text   string
+}
+
+// ScanTodos reads r line by line and returns every marker it finds
+func ScanTodos(r io.Reader) ([]Todo, error) {
+	var todos []Todo
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		m := todoRegex.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		todos = append(todos, Todo{
+			Line:   line,
+			Marker: m[1],
+			Text:   strings.TrimSpace(m[2]),
+		})
+	}
+
+	return todos, scanner.Err()
+}
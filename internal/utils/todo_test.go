@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestScanTodos(t *testing.T) {
+	const source = `package main
+
+// TODO: handle errors
+func main() {
+	x := 1 // FIXME off by one
+	// HACK
+	// TODOS are not markers, nor is MYTODO
+}
+`
+
+	todos, err := ScanTodos(strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := fmt.Sprintf("%+v", todos)
+	want := "[{Line:3 Marker:TODO Text:handle errors} {Line:5 Marker:FIXME Text:off by one} {Line:6 Marker:HACK Text:}]"
+	if got != want {
+		t.Errorf("ScanTodos() = %s, want %s", got, want)
+	}
+}